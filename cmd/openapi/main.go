@@ -0,0 +1,33 @@
+// Команда openapi записывает спецификацию REST API в формате OpenAPI 3.1, из которой
+// генерируется клиент pkg/client:
+//
+//	go run ./cmd/openapi -o api/openapi/openapi.json
+package main
+
+import (
+	"flag"
+	"os"
+
+	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/openapi"
+)
+
+func main() {
+	out := flag.String("o", "api/openapi/openapi.json", "куда записать спецификацию")
+	flag.Parse()
+
+	doc, err := openapi.Load()
+	if err != nil {
+		logger.Log.Error("Ошибка загрузки спецификации OpenAPI", "error", err)
+		os.Exit(1)
+	}
+	b, err := openapi.V31(doc)
+	if err != nil {
+		logger.Log.Error("Ошибка перевода спецификации в OpenAPI 3.1", "error", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, append(b, '\n'), 0o644); err != nil {
+		logger.Log.Error("Ошибка записи спецификации", "error", err)
+		os.Exit(1)
+	}
+}
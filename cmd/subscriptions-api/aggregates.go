@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"subscriptions-service/internal/health"
+	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/repository"
+)
+
+// spendRefreshInterval - период фонового пересчета устаревших агрегатов расходов
+const spendRefreshInterval = time.Hour
+
+// runAggregatesCommand выполняет `aggregates rebuild|refresh|check` и возвращает код выхода
+func runAggregatesCommand(aggregates repository.SpendAggregates, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: subscriptions-api aggregates rebuild|refresh|check")
+		return 2
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "rebuild":
+		n, err := aggregates.RebuildSpend(ctx)
+		if err != nil {
+			logger.Log.Error("Ошибка пересборки агрегатов", "error", err)
+			return 1
+		}
+		logger.Log.Info("Агрегаты пересобраны", "pairs", n)
+	case "refresh":
+		n, err := aggregates.RefreshStaleSpend(ctx)
+		if err != nil {
+			logger.Log.Error("Ошибка пересчета агрегатов", "error", err)
+			return 1
+		}
+		logger.Log.Info("Устаревшие агрегаты пересчитаны", "pairs", n)
+	case "check":
+		mismatches, err := aggregates.CheckSpend(ctx)
+		if err != nil {
+			logger.Log.Error("Ошибка проверки агрегатов", "error", err)
+			return 1
+		}
+		if len(mismatches) > 0 {
+			enc := json.NewEncoder(os.Stdout)
+			for _, m := range mismatches {
+				_ = enc.Encode(m)
+			}
+			logger.Log.Error("Агрегаты расходятся с живым расчетом", "mismatches", len(mismatches))
+			return 1
+		}
+		logger.Log.Info("Агрегаты совпадают с живым расчетом")
+	default:
+		fmt.Fprintf(os.Stderr, "unknown aggregates command %q\n", args[0])
+		return 2
+	}
+	return 0
+}
+
+// refreshSpendAggregates периодически пересчитывает устаревшие агрегаты, пока не отменен ctx
+func refreshSpendAggregates(ctx context.Context, aggregates repository.SpendAggregates, worker *health.Worker) {
+	ticker := time.NewTicker(spendRefreshInterval)
+	defer ticker.Stop()
+
+	worker.Started()
+	defer worker.Stopped()
+
+	for {
+		n, err := aggregates.RefreshStaleSpend(ctx)
+		worker.Report(err)
+		if err != nil {
+			logger.Log.Error("Ошибка фонового пересчета агрегатов", "error", err)
+		} else if n > 0 {
+			logger.Log.Info("Фоновый пересчет агрегатов", "pairs", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
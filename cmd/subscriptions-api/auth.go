@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/config"
+	"subscriptions-service/internal/grpcserver"
+	"subscriptions-service/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jwksRefreshInterval - период обновления ключей, загруженных по auth.jwks_url
+const jwksRefreshInterval = 15 * time.Minute
+
+// apiKeyUsageInterval - как часто учет использования одного API-ключа пишется в БД
+const apiKeyUsageInterval = time.Minute
+
+// authenticators строит проверку учетных данных для REST и gRPC с общими ключами JWT и API-ключами
+func authenticators(cfg config.AuthConfig, keys auth.APIKeyStore) (gin.HandlerFunc, grpcserver.AuthFunc, error) {
+	if cfg.Disabled {
+		logger.Log.Warn("Аутентификация отключена: все запросы выполняются с правами администратора")
+		return auth.Disabled(), grpcserver.Disabled(), nil
+	}
+
+	vcfg := auth.VerifierConfig{
+		HS256Secret: cfg.HS256Secret,
+		UserClaim:   cfg.UserClaim,
+		RolesClaim:  cfg.RolesClaim,
+		AdminRole:   cfg.AdminRole,
+		TenantClaim: cfg.TenantClaim,
+		Issuer:      cfg.Issuer,
+		Audience:    cfg.Audience,
+	}
+
+	var err error
+	switch {
+	case cfg.JWKSFile != "":
+		vcfg.JWKS, err = auth.LoadJWKSFile(cfg.JWKSFile)
+	case cfg.JWKSURL != "":
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		vcfg.JWKS, err = auth.NewRemoteJWKS(ctx, cfg.JWKSURL, jwksRefreshInterval)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	verifier, err := auth.NewVerifier(vcfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return auth.Middleware(verifier, keys), grpcserver.Authenticate(verifier, keys), nil
+}
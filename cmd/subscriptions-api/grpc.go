@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+
+	"subscriptions-service/internal/logger"
+
+	"google.golang.org/grpc"
+)
+
+// stopGRPC дожидается завершения активных вызовов, в том числе потоков ListSubscriptions,
+// а по истечении ctx обрывает оставшиеся
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Log.Error("gRPC-вызовы не завершились за отведенное время, соединения закрыты")
+		srv.Stop()
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"time"
+
+	"subscriptions-service/internal/health"
+
+	"gorm.io/gorm"
+)
+
+// healthCheckTimeout ограничивает каждую проверку /readyz
+const healthCheckTimeout = 2 * time.Second
+
+// newHealthChecker проверяет, что схема находится на версии schemaVersion -
+// последней встроенной в сборку миграции
+func newHealthChecker(db *gorm.DB, schemaVersion uint) (*health.Checker, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	checker := health.NewChecker(healthCheckTimeout)
+	checker.Add("database", true, health.DBPing(sqlDB))
+	checker.Add("migrations", true, health.SchemaVersion(sqlDB, schemaVersion))
+	return checker, nil
+}
@@ -2,30 +2,69 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"math/rand"
+	"errors"
+	"flag"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/auth"
 	"subscriptions-service/internal/config"
 	"subscriptions-service/internal/database"
+	"subscriptions-service/internal/graph"
+	"subscriptions-service/internal/grpcserver"
 	"subscriptions-service/internal/handlers"
+	"subscriptions-service/internal/health"
 	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/metrics"
+	"subscriptions-service/internal/openapi"
+	"subscriptions-service/internal/replica"
 	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
 	"subscriptions-service/internal/trace"
 
 	docs "subscriptions-service/internal/swagger"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
 )
 
+// unversionedDeprecatedAt - с этого момента пути без префикса /v1 считаются устаревшими
+var unversionedDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// @title Subscriptions Service API
+// @version 1.0
+// @description Учет онлайн-подписок пользователей и расчет расходов на них
+// @BasePath /v1
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT в формате "Bearer <token>"
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @description API-ключ в формате "ApiKey <key>"
 func main() {
-	cfg := config.LoadConfig()
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		logger.Log.Error("Ошибка конфигурации", "error", err)
+		os.Exit(2)
+	}
+	logger.SetLevel(cfg.Log.Level)
+	logger.Log.Info("Загружена конфигурация", "config", cfg)
 
 	db, err := database.Connect(cfg)
 	if err != nil {
@@ -33,19 +72,101 @@ func main() {
 		return
 	}
 
+	migrator, err := newMigrator(db)
+	if err != nil {
+		logger.Log.Error("Ошибка чтения миграций", "error", err)
+		return
+	}
+	if len(args) > 0 && args[0] == "migrate" {
+		os.Exit(runMigrateCommand(migrator, args[1:]))
+	}
+	if cfg.Features.MigrateOnStart {
+		n, err := migrator.Up(context.Background())
+		if err != nil {
+			logger.Log.Error("Ошибка применения миграций", "error", err)
+			return
+		}
+		logger.Log.Info("Миграции применены", "applied", n, "version", migrator.Latest())
+	}
+
+	// с SQLite суммы считаются на лету, агрегаты расходов не ведутся
+	sqlite := database.IsSQLite(db)
+	aggregates := repository.NewSpendAggregates(db)
+	if len(args) > 0 && args[0] == "aggregates" {
+		if sqlite {
+			logger.Log.Error("Агрегаты расходов не используются с драйвером sqlite")
+			os.Exit(2)
+		}
+		os.Exit(runAggregatesCommand(aggregates, args[1:]))
+	}
+
+	err = replica.Register(db, cfg.DB.Replicas, replica.Pool{
+		MaxOpenConns:    cfg.DB.MaxOpenConns,
+		MaxIdleConns:    cfg.DB.MaxIdleConns,
+		ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DB.ConnMaxIdleTime,
+	})
+	if err != nil {
+		logger.Log.Error("Ошибка подключения к репликам", "error", err)
+		return
+	}
+
+	apiKeys := repository.NewAPIKeyRepository(db)
+	apiKeyUsage := auth.NewUsageBatcher(apiKeys, apiKeyUsageInterval)
+	authenticate, authenticateGRPC, err := authenticators(cfg.Auth, apiKeyUsage)
+	if err != nil {
+		logger.Log.Error("Ошибка настройки аутентификации", "error", err)
+		return
+	}
+	// без аутентификации у субъекта нет арендатора, и выбрать его можно только заголовком
+	trustTenantHeader := cfg.Auth.Disabled || cfg.Tenant.TrustHeader
+
+	if cfg.Features.Metrics {
+		if err := registerMetrics(db); err != nil {
+			logger.Log.Error("Ошибка регистрации метрик", "error", err)
+			return
+		}
+	}
+
+	checker, err := newHealthChecker(db, migrator.Latest())
+	if err != nil {
+		logger.Log.Error("Ошибка настройки проверок готовности", "error", err)
+		return
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go apiKeyUsage.Run(jobsCtx)
+
+	shutdownTracing, err := trace.Setup(context.Background(), trace.Config{
+		ServiceName: cfg.Tracing.ServiceName,
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		logger.Log.Error("Ошибка настройки трассировки", "error", err)
+		return
+	}
+
+	limitRate, err := rateLimitMiddleware(jobsCtx, cfg.RateLimit, db, checker)
+	if err != nil {
+		logger.Log.Error("Ошибка настройки лимита запросов", "error", err)
+		return
+	}
+
 	repo := repository.NewSubscriptionRepository(db)
+	if sqlite {
+		repo = repository.NewSQLiteSubscriptionRepository(db)
+	}
 	h := handlers.NewHandler(repo)
 
 	r := gin.Default()
 
-	r.Use(func(c *gin.Context) {
-		traceID := fmt.Sprintf("%d", rand.Int63())
-		c.Set("trace_id", traceID)
-
-		ctx := trace.WithTraceID(c.Request.Context(), traceID)
-		c.Request = c.Request.WithContext(ctx)
+	r.Use(trace.Middleware())
 
-		c.Writer.Header().Set("X-Trace-ID", traceID)
+	r.Use(func(c *gin.Context) {
+		traceID, _ := c.Get("trace_id")
 
 		start := time.Now()
 		c.Next()
@@ -61,25 +182,110 @@ func main() {
 		)
 	})
 
-	h.RegisterRoutes(r)
-
-	docs.SwaggerInfo.BasePath = "/"
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.Use(metrics.Middleware())
 
-	r.GET("/healthz", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	api := r.Group("/", database.RequestTimeout(cfg.DB.RequestTimeout), authenticate, tenant.Middleware(cfg.Tenant.Header, trustTenantHeader))
+	if limitRate != nil {
+		api.Use(limitRate)
+	}
+	if len(cfg.DB.Replicas) > 0 && cfg.DB.ReadYourWritesWindow > 0 {
+		api.Use(replica.ReadYourWrites(cfg.DB.ReadYourWritesWindow))
+	}
+	gql := graph.NewHandler(repo, graph.Limits{
+		MaxDepth:      cfg.GraphQL.MaxDepth,
+		MaxComplexity: cfg.GraphQL.MaxComplexity,
 	})
+	var analytics *handlers.AnalyticsHandler
+	if sqlite {
+		logger.Log.Warn("Аналитика недоступна с драйвером sqlite, маршруты /admin/analytics отключены")
+	} else {
+		analytics = handlers.NewAnalyticsHandler(repository.NewAnalyticsRepository(db))
+	}
+	apiKeysHandler := handlers.NewAPIKeyHandler(apiKeys)
+
+	spec, err := openapi.Load()
+	if err != nil {
+		logger.Log.Error("Ошибка загрузки спецификации OpenAPI", "error", err)
+		return
+	}
+	validate := openapi.Options{
+		Requests:  cfg.OpenAPI.ValidateRequests,
+		Responses: cfg.OpenAPI.ValidateResponses,
+	}
+
+	registerAPI := func(prefix string, mw ...gin.HandlerFunc) {
+		r := api.Group(prefix, append(mw, openapi.Middleware(spec, prefix, validate))...)
+		h.RegisterRoutes(r)
+		gql.RegisterRoutes(r)
+
+		admin := r.Group("/admin")
+		if analytics != nil {
+			analytics.RegisterRoutes(admin.Group("", auth.RequireScope(auth.ScopeAnalyticsRead)))
+		}
+		apiKeysHandler.RegisterRoutes(admin.Group("", auth.RequireAdmin()))
+	}
+	registerAPI(apiv1.Prefix)
+	// пути без версии остаются псевдонимами v1 до выхода следующей версии API
+	registerAPI("", handlers.Deprecated(unversionedDeprecatedAt, apiv1.Prefix))
+
+	if cfg.Features.Swagger {
+		docs.SwaggerInfo.BasePath = apiv1.Prefix
+		r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
+
+	if cfg.Features.Metrics {
+		r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	}
+
+	// /healthz оставлен для старых проверок и работает как /livez
+	r.GET("/healthz", health.LiveHandler())
+	r.GET("/livez", health.LiveHandler())
+	r.GET("/readyz", checker.ReadyHandler())
 
 	srv := &http.Server{
-		Addr:    cfg.ServerAddress,
-		Handler: r,
+		Addr:              cfg.Server.Address,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	if !sqlite {
+		aggregatesWorker := health.NewWorker("spend_aggregates_refresh")
+		checker.AddWorker(aggregatesWorker)
+		go refreshSpendAggregates(jobsCtx, aggregates, aggregatesWorker)
+	}
+
+	// gRPC API для внутренних сервисов работает с тем же репозиторием на отдельном порту
+	var grpcSrv *grpc.Server
+	var grpcHealth *grpchealth.Server
+	if cfg.Server.GRPCAddress != "" {
+		lis, err := net.Listen("tcp", cfg.Server.GRPCAddress)
+		if err != nil {
+			logger.Log.Error("Ошибка запуска gRPC-сервера", "error", err)
+			return
+		}
+		grpcSrv, grpcHealth = grpcserver.New(grpcserver.Config{
+			Authenticate:      authenticateGRPC,
+			TenantHeader:      cfg.Tenant.Header,
+			TrustTenantHeader: trustTenantHeader,
+			RequestTimeout:    cfg.DB.RequestTimeout,
+		}, repo)
+
+		go func() {
+			logger.Log.Info("gRPC-сервер запущен", "port", cfg.Server.GRPCAddress)
+			if err := grpcSrv.Serve(lis); err != nil {
+				logger.Log.Error("Ошибка gRPC-сервера", "error", err)
+			}
+		}()
 	}
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		logger.Log.Info("Сервис запущен", "port", cfg.ServerAddress)
+		logger.Log.Info("Сервис запущен", "port", cfg.Server.Address)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Log.Error("Ошибка запуска сервера", "error", err)
 		}
@@ -87,13 +293,29 @@ func main() {
 
 	<-quit
 	logger.Log.Info("Получен сигнал завершения работы, отключаем сервис...")
+	checker.SetShuttingDown()
+	if grpcHealth != nil {
+		grpcHealth.Shutdown()
+	}
+	time.Sleep(cfg.Server.DrainDelay)
+	stopJobs()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Log.Error("Ошибка при завершении сервера", "error", err)
 	}
+	if grpcSrv != nil {
+		stopGRPC(ctx, grpcSrv)
+	}
+	if err := apiKeyUsage.Flush(ctx); err != nil {
+		logger.Log.Error("Ошибка записи учета использования API-ключей", "error", err)
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Log.Error("Ошибка отправки трассировок", "error", err)
+	}
 
 	sqlDB, err := db.DB()
 	if err == nil {
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"subscriptions-service/internal/metrics"
+	"subscriptions-service/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+func registerMetrics(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err := metrics.RegisterDB(sqlDB); err != nil {
+		return err
+	}
+	return metrics.RegisterActivity(repository.NewStatsRepository(db))
+}
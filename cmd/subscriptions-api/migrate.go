@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"subscriptions-service/internal/database"
+	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/migrate"
+	"subscriptions-service/migrations"
+
+	"gorm.io/gorm"
+)
+
+func newMigrator(db *gorm.DB) (*migrate.Runner, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if database.IsSQLite(db) {
+		return migrate.NewSQLite(sqlDB, migrations.SQLite)
+	}
+	return migrate.New(sqlDB, migrations.FS)
+}
+
+const migrateUsage = "usage: subscriptions-api migrate up|down [N]|status|goto V|force V"
+
+// runMigrateCommand выполняет `migrate up|down|status|goto|force` и возвращает код выхода
+func runMigrateCommand(runner *migrate.Runner, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		n, err := runner.Up(ctx)
+		if err != nil {
+			logger.Log.Error("Ошибка применения миграций", "error", err)
+			return 1
+		}
+		logger.Log.Info("Миграции применены", "applied", n, "version", runner.Latest())
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+		}
+		n, err := runner.Down(ctx, steps)
+		if err != nil {
+			logger.Log.Error("Ошибка отката миграций", "error", err)
+			return 1
+		}
+		logger.Log.Info("Миграции откачены", "reverted", n)
+	case "status":
+		status, err := runner.Status(ctx)
+		if err != nil {
+			logger.Log.Error("Ошибка чтения версии схемы", "error", err)
+			return 1
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(status)
+		if status.Dirty {
+			return 1
+		}
+	case "goto", "force":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		if args[0] == "force" {
+			if err := runner.Force(ctx, uint(version)); err != nil {
+				logger.Log.Error("Ошибка установки версии схемы", "error", err)
+				return 1
+			}
+			logger.Log.Info("Версия схемы установлена", "version", version)
+			return 0
+		}
+		n, err := runner.Goto(ctx, uint(version))
+		if err != nil {
+			logger.Log.Error("Ошибка перехода к версии схемы", "error", err)
+			return 1
+		}
+		logger.Log.Info("Схема переведена на версию", "version", version, "migrations", n)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n", args[0])
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/config"
+	"subscriptions-service/internal/health"
+	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// routeCosts - стоимость тяжелых маршрутов в токенах, остальные стоят 1.
+// Стоимость одинакова под /v1 и по устаревшим путям без версии.
+var routeCosts = withVersionPrefix(apiv1.Prefix, ratelimit.Costs{
+	"GET /subscriptions/sum":                      10,
+	"GET /subscriptions/sum/monthly":              10,
+	"GET /users/:user_id/subscriptions/conflicts": 5,
+	"GET /admin/analytics/popular-services":       20,
+	"GET /admin/analytics/average-spend":          20,
+	"GET /admin/analytics/churn":                  20,
+	"GET /admin/analytics/price-distribution":     20,
+})
+
+func withVersionPrefix(prefix string, costs ratelimit.Costs) ratelimit.Costs {
+	out := make(ratelimit.Costs, 2*len(costs))
+	for route, cost := range costs {
+		method, path, _ := strings.Cut(route, " ")
+		out[route] = cost
+		out[method+" "+prefix+path] = cost
+	}
+	return out
+}
+
+// rateLimitCleanupInterval - период удаления неиспользуемых ведер из Postgres
+const rateLimitCleanupInterval = 10 * time.Minute
+
+// rateLimitMiddleware возвращает nil, если ограничение выключено
+func rateLimitMiddleware(ctx context.Context, cfg config.RateLimitConfig, db *gorm.DB, checker *health.Checker) (gin.HandlerFunc, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	limits := ratelimit.Config{Capacity: cfg.Capacity, RefillPerSecond: cfg.RefillPerSecond}
+
+	var store ratelimit.Store
+	switch cfg.Store {
+	case "memory":
+		store = ratelimit.NewMemoryStore(limits)
+	case "postgres":
+		pg := ratelimit.NewPostgresStore(db, limits)
+		worker := health.NewWorker("rate_limit_cleanup")
+		checker.AddWorker(worker)
+		go cleanupRateLimits(ctx, pg, worker)
+		store = pg
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", cfg.Store)
+	}
+	return ratelimit.Middleware(store, limits, routeCosts), nil
+}
+
+func cleanupRateLimits(ctx context.Context, store *ratelimit.PostgresStore, worker *health.Worker) {
+	ticker := time.NewTicker(rateLimitCleanupInterval)
+	defer ticker.Stop()
+
+	worker.Started()
+	defer worker.Stopped()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, err := store.DeleteIdle(ctx)
+		worker.Report(err)
+		if err != nil {
+			logger.Log.Error("Ошибка очистки ведер лимита запросов", "error", err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Package apierror - ошибки REST API со стабильными кодами. Клиенты различают ошибки
+// по полю code ответа application/problem+json (RFC 7807), а не по тексту.
+package apierror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Code - стабильный код ошибки. Коды входят в контракт API: существующие не меняются,
+// новые добавляются по мере появления новых ошибок.
+type Code string
+
+const (
+	CodeValidationFailed     Code = "validation_failed"
+	CodeUnauthenticated      Code = "unauthenticated"
+	CodeInvalidToken         Code = "invalid_token"
+	CodeInvalidAPIKey        Code = "invalid_api_key"
+	CodeForbidden            Code = "forbidden"
+	CodeInsufficientScope    Code = "insufficient_scope"
+	CodeInvalidTenant        Code = "invalid_tenant"
+	CodeTenantMismatch       Code = "tenant_mismatch"
+	CodeNotFound             Code = "not_found"
+	CodeSubscriptionNotFound Code = "subscription_not_found"
+	CodeDiscountNotFound     Code = "discount_not_found"
+	CodeAPIKeyNotFound       Code = "api_key_not_found"
+	CodeRateLimitExceeded    Code = "rate_limit_exceeded"
+	CodeRequestCanceled      Code = "request_canceled"
+	CodeTimeout              Code = "timeout"
+	CodeInternal             Code = "internal_error"
+)
+
+// StatusClientClosedRequest - клиент отключился до ответа (нестандартный код nginx)
+const StatusClientClosedRequest = 499
+
+// FieldError - ошибка в конкретном параметре или поле запроса. Для полей тела
+// Pointer - JSON Pointer (RFC 6901) на поле, например /end_date.
+type FieldError struct {
+	Field   string `json:"field"`
+	Pointer string `json:"pointer,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error - ошибка с HTTP-статусом и кодом для клиента. Detail и Fields уходят клиенту,
+// Err - исходная ошибка, которая только пишется в лог.
+type Error struct {
+	Status int
+	Code   Code
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// Validation - ошибка 400 validation_failed с перечнем неверных полей
+func Validation(detail string, fields ...FieldError) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeValidationFailed, Detail: detail, Fields: fields}
+}
+
+// InvalidField - ошибка validation_failed в одном поле
+func InvalidField(field, message string) *Error {
+	return Validation("invalid "+field, FieldError{Field: field, Message: message})
+}
+
+// NotFound уточняет код ошибки "запись не найдена" из репозитория: вместо общего
+// not_found клиент получает code и detail. Остальные ошибки возвращаются как есть.
+func NotFound(err error, code Code, detail string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Error{Status: http.StatusNotFound, Code: code, Detail: detail, Err: err}
+	}
+	return err
+}
+
+// From переводит любую ошибку в *Error. Ошибки репозитория сопоставляются здесь,
+// чтобы обработчики не разбирали их сами; неизвестные ошибки становятся internal_error
+// без текста исходной ошибки.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return &Error{Status: http.StatusNotFound, Code: CodeNotFound, Detail: "resource not found", Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Status: http.StatusGatewayTimeout, Code: CodeTimeout, Detail: "request timed out", Err: err}
+	case errors.As(err, &pgErr) && pgErr.Code == "57014":
+		// query_canceled: сработал statement_timeout
+		return &Error{Status: http.StatusGatewayTimeout, Code: CodeTimeout, Detail: "request timed out", Err: err}
+	case errors.Is(err, context.Canceled):
+		return &Error{Status: StatusClientClosedRequest, Code: CodeRequestCanceled, Detail: "request canceled", Err: err}
+	default:
+		return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Detail: "internal server error", Err: err}
+	}
+}
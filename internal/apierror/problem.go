@@ -0,0 +1,75 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/trace"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem - тело ответа с ошибкой по RFC 7807. Тип about:blank: смысл ошибки
+// передает код в расширении code, title совпадает с текстом HTTP-статуса.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     Code         `json:"code"`
+	TraceID  string       `json:"trace_id,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Respond отвечает клиенту ошибкой err в формате problem+json.
+// Ошибки сервера пишутся в лог вместе с исходной ошибкой.
+func Respond(c *gin.Context, err error) {
+	e := From(err)
+	traceID := trace.TraceIDFromContext(c.Request.Context())
+
+	if e.Status >= http.StatusInternalServerError {
+		logger.Log.Error("Ошибка обработки запроса", "trace_id", traceID,
+			"method", c.Request.Method, "path", c.FullPath(), "code", e.Code, "error", err)
+	}
+
+	title := http.StatusText(e.Status)
+	if e.Status == StatusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+	body, _ := json.Marshal(Problem{
+		Type:     "about:blank",
+		Title:    title,
+		Status:   e.Status,
+		Detail:   e.Detail,
+		Instance: c.Request.URL.Path,
+		Code:     e.Code,
+		TraceID:  traceID,
+		Errors:   e.Fields,
+	})
+	c.Data(e.Status, ContentType, body)
+}
+
+// Abort отвечает ошибкой и прерывает цепочку обработчиков; для middleware,
+// которые отклоняют запрос до обработчика
+func Abort(c *gin.Context, err error) {
+	Respond(c, err)
+	c.Abort()
+}
+
+// Middleware отвечает клиенту последней ошибкой, которую обработчик добавил через
+// c.Error, если сам обработчик ответ не записал. Так ошибки репозиториев переводятся
+// в ответы в одном месте.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		Respond(c, c.Errors.Last().Err)
+	}
+}
@@ -0,0 +1,93 @@
+package apiv1
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/validation"
+
+	"github.com/google/uuid"
+)
+
+// APIKey - ключ доступа в ответах API, без хэша
+type APIKey struct {
+	ID             uuid.UUID   `json:"id" format:"uuid"`
+	TenantID       string      `json:"tenant_id" example:"default"`
+	Name           string      `json:"name" example:"billing-sync"`
+	Prefix         string      `json:"prefix"`
+	Scopes         []string    `json:"scopes" example:"subscriptions:read"`
+	AllowedUserIDs []uuid.UUID `json:"allowed_user_ids"`
+	ExpiresAt      *time.Time  `json:"expires_at,omitempty" format:"date-time" extensions:"x-nullable"`
+	RevokedAt      *time.Time  `json:"revoked_at" format:"date-time" extensions:"x-nullable"`
+	LastUsedAt     *time.Time  `json:"last_used_at" format:"date-time" extensions:"x-nullable"`
+	UsageCount     int64       `json:"usage_count"`
+	CreatedAt      time.Time   `json:"created_at" format:"date-time"`
+}
+
+func NewAPIKey(k models.APIKey) APIKey {
+	return APIKey{
+		ID:             k.ID,
+		TenantID:       k.TenantID,
+		Name:           k.Name,
+		Prefix:         k.Prefix,
+		Scopes:         k.Scopes,
+		AllowedUserIDs: k.AllowedUserIDs,
+		ExpiresAt:      k.ExpiresAt,
+		RevokedAt:      k.RevokedAt,
+		LastUsedAt:     k.LastUsedAt,
+		UsageCount:     k.UsageCount,
+		CreatedAt:      k.CreatedAt,
+	}
+}
+
+func NewAPIKeys(keys []models.APIKey) []APIKey {
+	return mapAll(keys, NewAPIKey)
+}
+
+// CreateAPIKeyRequest - тело POST /admin/api-keys
+type CreateAPIKeyRequest struct {
+	Name           string      `json:"name" validate:"required" example:"billing-sync"`
+	Scopes         []string    `json:"scopes" validate:"required,min=1" example:"subscriptions:read"`
+	ExpiresAt      *time.Time  `json:"expires_at,omitempty" format:"date-time" extensions:"x-nullable"`
+	AllowedUserIDs []uuid.UUID `json:"allowed_user_ids,omitempty" extensions:"x-nullable"`
+}
+
+func (r *CreateAPIKeyRequest) Validate() []apierror.FieldError {
+	var errs []apierror.FieldError
+	for i, scope := range r.Scopes {
+		if !auth.ValidScope(scope) {
+			errs = append(errs, validation.Field(fmt.Sprintf("scopes[%d]", i),
+				"must be one of "+strings.Join(auth.Scopes, ", ")))
+		}
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		errs = append(errs, validation.Field("expires_at", "must be in the future"))
+	}
+	return errs
+}
+
+// Model вызывается после проверки. Сам ключ не сохраняется, только его префикс и хэш.
+func (r *CreateAPIKeyRequest) Model(prefix, hash string) models.APIKey {
+	allowed := models.UUIDList{}
+	if r.AllowedUserIDs != nil {
+		allowed = r.AllowedUserIDs
+	}
+	return models.APIKey{
+		Name:           r.Name,
+		Prefix:         prefix,
+		KeyHash:        hash,
+		Scopes:         r.Scopes,
+		AllowedUserIDs: allowed,
+		ExpiresAt:      r.ExpiresAt,
+	}
+}
+
+// CreateAPIKeyResponse содержит сам ключ. Он показывается только при создании.
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,56 @@
+// Package apiv1 описывает тела запросов и ответов REST API версии v1 и их
+// преобразование в модели. Модели БД меняются независимо от API: новое поле
+// в models попадает в ответы, только когда его добавят сюда.
+package apiv1
+
+import (
+	"time"
+
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/validation"
+)
+
+// Prefix - префикс маршрутов версии
+const Prefix = "/v1"
+
+func formatMonth(m models.MonthYearDate) string {
+	return time.Time(m).Format(validation.MonthLayout)
+}
+
+func formatMonthPtr(m *models.MonthYearDate) *string {
+	if m == nil {
+		return nil
+	}
+	s := formatMonth(*m)
+	return &s
+}
+
+// parseMonth вызывается после проверки тегом month, поэтому ошибка не возникает
+func parseMonth(s string) models.MonthYearDate {
+	t, _ := time.Parse(validation.MonthLayout, s)
+	return models.MonthYearDate(t)
+}
+
+func parseMonthPtr(s *string) *models.MonthYearDate {
+	if s == nil {
+		return nil
+	}
+	m := parseMonth(*s)
+	return &m
+}
+
+// monthBefore сообщает, что месяц a раньше b; неверные месяцы отклоняются тегом month
+func monthBefore(a, b string) bool {
+	ta, errA := time.Parse(validation.MonthLayout, a)
+	tb, errB := time.Parse(validation.MonthLayout, b)
+	return errA == nil && errB == nil && ta.Before(tb)
+}
+
+// mapAll преобразует список моделей. Пустой список отдается как [], а не null.
+func mapAll[M, D any](in []M, f func(M) D) []D {
+	out := make([]D, len(in))
+	for i, m := range in {
+		out[i] = f(m)
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package apiv1
+
+import (
+	"subscriptions-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Conflict - найденная проблема в данных пользователя вместе с предлагаемым исправлением
+type Conflict struct {
+	Kind            string      `json:"kind" enums:"overlap,similar_name,invalid_period" example:"overlap"`
+	SubscriptionIDs []uuid.UUID `json:"subscription_ids"`
+	Description     string      `json:"description"`
+	Fix             ConflictFix `json:"fix"`
+}
+
+// ConflictFix - исправление конфликта: записи из Update нужно сохранить
+// в указанном состоянии, тела из Create - отправить в POST /subscriptions,
+// записи из Delete - удалить
+type ConflictFix struct {
+	Action string                      `json:"action" enums:"merge,trim,split,remove,rename,swap_dates" example:"merge"`
+	Update []Subscription              `json:"update,omitempty"`
+	Create []CreateSubscriptionRequest `json:"create,omitempty"`
+	Delete []uuid.UUID                 `json:"delete,omitempty"`
+}
+
+func NewConflict(c models.Conflict) Conflict {
+	return Conflict{
+		Kind:            string(c.Kind),
+		SubscriptionIDs: c.SubscriptionIDs,
+		Description:     c.Description,
+		Fix: ConflictFix{
+			Action: string(c.Fix.Action),
+			Update: NewSubscriptions(c.Fix.Update),
+			Create: mapAll(c.Fix.Create, NewCreateSubscriptionRequest),
+			Delete: c.Fix.Delete,
+		},
+	}
+}
+
+func NewConflicts(conflicts []models.Conflict) []Conflict {
+	return mapAll(conflicts, NewConflict)
+}
@@ -0,0 +1,65 @@
+package apiv1
+
+import (
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/validation"
+
+	"github.com/google/uuid"
+)
+
+// Discount - скидка подписки в ответах API
+type Discount struct {
+	ID             uuid.UUID `json:"id" format:"uuid" example:"0b9a5f0e-8a52-4a3c-9a0e-2f1a4a4f7d10"`
+	SubscriptionID uuid.UUID `json:"subscription_id" format:"uuid" example:"3fa85f64-5717-4562-b3fc-2c963f66afa6"`
+	Type           string    `json:"type" enums:"percent,fixed" example:"percent"`
+	Value          int       `json:"value" example:"50"`
+	StartDate      string    `json:"start_date" format:"month" example:"07-2025"`
+	EndDate        *string   `json:"end_date" format:"month" example:"09-2025" extensions:"x-nullable"`
+}
+
+func NewDiscount(d models.Discount) Discount {
+	return Discount{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		Type:           string(d.Type),
+		Value:          d.Value,
+		StartDate:      formatMonth(d.StartDate),
+		EndDate:        formatMonthPtr(d.EndDate),
+	}
+}
+
+func NewDiscounts(discounts []models.Discount) []Discount {
+	return mapAll(discounts, NewDiscount)
+}
+
+// CreateDiscountRequest - тело POST /subscriptions/{id}/discounts.
+// Для percent Value - процент от цены, для fixed - сумма в рублях.
+type CreateDiscountRequest struct {
+	Type      string  `json:"type" validate:"required,oneof=percent fixed" enums:"percent,fixed" example:"percent"`
+	Value     *int    `json:"value" validate:"required,gt=0" example:"50"`
+	StartDate string  `json:"start_date" validate:"required,month" format:"month" example:"07-2025"`
+	EndDate   *string `json:"end_date,omitempty" validate:"omitempty,month" format:"month" example:"09-2025" extensions:"x-nullable"`
+}
+
+func (r *CreateDiscountRequest) Validate() []apierror.FieldError {
+	var errs []apierror.FieldError
+	if models.DiscountType(r.Type) == models.DiscountPercent && r.Value != nil && *r.Value > 100 {
+		errs = append(errs, validation.Field("value", "percent discount value must be between 1 and 100"))
+	}
+	if r.EndDate != nil && monthBefore(*r.EndDate, r.StartDate) {
+		errs = append(errs, validation.Field("end_date", "must not be before start_date"))
+	}
+	return errs
+}
+
+// Model вызывается после проверки и привязывает скидку к подписке из пути
+func (r *CreateDiscountRequest) Model(subscriptionID uuid.UUID) models.Discount {
+	return models.Discount{
+		SubscriptionID: subscriptionID,
+		Type:           models.DiscountType(r.Type),
+		Value:          *r.Value,
+		StartDate:      parseMonth(r.StartDate),
+		EndDate:        parseMonthPtr(r.EndDate),
+	}
+}
@@ -0,0 +1,122 @@
+package apiv1
+
+import (
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/validation"
+
+	"github.com/google/uuid"
+)
+
+// Subscription - подписка в ответах API
+type Subscription struct {
+	ID          uuid.UUID `json:"id" format:"uuid" example:"3fa85f64-5717-4562-b3fc-2c963f66afa6"`
+	ServiceName string    `json:"service_name" example:"Yandex Plus"`
+	Price       int       `json:"price" example:"400"`
+	UserID      uuid.UUID `json:"user_id" format:"uuid" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	StartDate   string    `json:"start_date" format:"month" example:"07-2025"`
+	EndDate     *string   `json:"end_date" format:"month" example:"12-2025" extensions:"x-nullable"`
+}
+
+func NewSubscription(s models.Subscription) Subscription {
+	return Subscription{
+		ID:          s.ID,
+		ServiceName: s.ServiceName,
+		Price:       s.Price,
+		UserID:      s.UserID,
+		StartDate:   formatMonth(s.StartDate),
+		EndDate:     formatMonthPtr(s.EndDate),
+	}
+}
+
+func NewSubscriptions(subs []models.Subscription) []Subscription {
+	return mapAll(subs, NewSubscription)
+}
+
+// CreateSubscriptionRequest - тело POST /subscriptions
+type CreateSubscriptionRequest struct {
+	ServiceName string `json:"service_name" validate:"required,max=255" example:"Yandex Plus"`
+	Price       *int   `json:"price" validate:"required,gt=0" example:"400"`
+	// UserID по умолчанию - пользователь из токена, обязателен только для администратора
+	UserID    string  `json:"user_id,omitempty" validate:"omitempty,uuid" format:"uuid" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	StartDate string  `json:"start_date" validate:"required,month" format:"month" example:"07-2025"`
+	EndDate   *string `json:"end_date,omitempty" validate:"omitempty,month" format:"month" example:"12-2025" extensions:"x-nullable"`
+}
+
+// NewCreateSubscriptionRequest - тело запроса, создающего копию подписки s
+func NewCreateSubscriptionRequest(s models.Subscription) CreateSubscriptionRequest {
+	price := s.Price
+	return CreateSubscriptionRequest{
+		ServiceName: s.ServiceName,
+		Price:       &price,
+		UserID:      s.UserID.String(),
+		StartDate:   formatMonth(s.StartDate),
+		EndDate:     formatMonthPtr(s.EndDate),
+	}
+}
+
+func (r *CreateSubscriptionRequest) Validate() []apierror.FieldError {
+	var errs []apierror.FieldError
+	if r.UserID == "" {
+		errs = append(errs, validation.Field("user_id", "is required"))
+	} else if id, err := uuid.Parse(r.UserID); err == nil && id == uuid.Nil {
+		errs = append(errs, validation.Field("user_id", "must not be the nil UUID"))
+	}
+	if r.EndDate != nil && monthBefore(*r.EndDate, r.StartDate) {
+		errs = append(errs, validation.Field("end_date", "must not be before start_date"))
+	}
+	return errs
+}
+
+// Model вызывается после проверки, поэтому поля уже разобраны без ошибок
+func (r *CreateSubscriptionRequest) Model() models.Subscription {
+	return models.Subscription{
+		ServiceName: r.ServiceName,
+		Price:       *r.Price,
+		UserID:      uuid.MustParse(r.UserID),
+		StartDate:   parseMonth(r.StartDate),
+		EndDate:     parseMonthPtr(r.EndDate),
+	}
+}
+
+// UpdateSubscriptionRequest - тело PUT /subscriptions/{id}. Отсутствующие поля не меняются.
+type UpdateSubscriptionRequest struct {
+	ServiceName *string `json:"service_name,omitempty" validate:"omitempty,min=1,max=255" example:"Yandex Plus" extensions:"x-nullable"`
+	Price       *int    `json:"price,omitempty" validate:"omitempty,gt=0" example:"400" extensions:"x-nullable"`
+	UserID      *string `json:"user_id,omitempty" validate:"omitempty,uuid" format:"uuid" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba" extensions:"x-nullable"`
+	StartDate   *string `json:"start_date,omitempty" validate:"omitempty,month" format:"month" example:"07-2025" extensions:"x-nullable"`
+	EndDate     *string `json:"end_date,omitempty" validate:"omitempty,month" format:"month" example:"12-2025" extensions:"x-nullable"`
+}
+
+func (r *UpdateSubscriptionRequest) Validate() []apierror.FieldError {
+	var errs []apierror.FieldError
+	if r.UserID != nil {
+		if id, err := uuid.Parse(*r.UserID); err == nil && id == uuid.Nil {
+			errs = append(errs, validation.Field("user_id", "must not be the nil UUID"))
+		}
+	}
+	if r.StartDate != nil && r.EndDate != nil && monthBefore(*r.EndDate, *r.StartDate) {
+		errs = append(errs, validation.Field("end_date", "must not be before start_date"))
+	}
+	return errs
+}
+
+// Model возвращает изменения с нулевыми значениями в отсутствующих полях,
+// которые репозиторий оставляет без изменений
+func (r *UpdateSubscriptionRequest) Model() models.Subscription {
+	var sub models.Subscription
+	if r.ServiceName != nil {
+		sub.ServiceName = *r.ServiceName
+	}
+	if r.Price != nil {
+		sub.Price = *r.Price
+	}
+	if r.UserID != nil {
+		sub.UserID = uuid.MustParse(*r.UserID)
+	}
+	if r.StartDate != nil {
+		sub.StartDate = parseMonth(*r.StartDate)
+	}
+	sub.EndDate = parseMonthPtr(r.EndDate)
+	return sub
+}
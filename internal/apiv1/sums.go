@@ -0,0 +1,81 @@
+package apiv1
+
+import (
+	"subscriptions-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type SumResponse struct {
+	Sum       int                `json:"sum" example:"2400"`
+	Overlap   string             `json:"overlap" enums:"max,sum,latest" example:"max"`
+	Collapsed []CollapsedOverlap `json:"collapsed"`
+}
+
+type MonthlySumResponse struct {
+	Gross     int                `json:"gross" example:"2800"`
+	Discount  int                `json:"discount" example:"400"`
+	Sum       int                `json:"sum" example:"2400"`
+	Months    []MonthlySpend     `json:"months"`
+	Overlap   string             `json:"overlap" enums:"max,sum,latest" example:"max"`
+	Collapsed []CollapsedOverlap `json:"collapsed"`
+}
+
+// MonthlySpend - расходы пользователя за один месяц до и после скидок
+type MonthlySpend struct {
+	Month    string `json:"month" format:"month" example:"07-2025"`
+	Gross    int    `json:"gross" example:"700"`
+	Discount int    `json:"discount" example:"100"`
+	Net      int    `json:"net" example:"600"`
+}
+
+func NewMonthlySpend(m models.MonthlySpend) MonthlySpend {
+	return MonthlySpend{
+		Month:    formatMonth(m.Month),
+		Gross:    m.Gross,
+		Discount: m.Discount,
+		Net:      m.Net,
+	}
+}
+
+// CollapsedOverlap - запись, исключенная из расчета суммы, так как в те же месяцы
+// по тому же сервису учтена другая запись
+type CollapsedOverlap struct {
+	ServiceName string    `json:"service_name" example:"Spotify"`
+	KeptID      uuid.UUID `json:"kept_id" format:"uuid"`
+	CollapsedID uuid.UUID `json:"collapsed_id" format:"uuid"`
+	StartMonth  string    `json:"start_month" format:"month" example:"03-2025"`
+	EndMonth    string    `json:"end_month" format:"month" example:"07-2025"`
+	Months      int       `json:"months" example:"5"`
+}
+
+func NewCollapsedOverlap(o models.CollapsedOverlap) CollapsedOverlap {
+	return CollapsedOverlap{
+		ServiceName: o.ServiceName,
+		KeptID:      o.KeptID,
+		CollapsedID: o.CollapsedID,
+		StartMonth:  formatMonth(o.StartMonth),
+		EndMonth:    formatMonth(o.EndMonth),
+		Months:      o.Months,
+	}
+}
+
+func NewCollapsedOverlaps(collapsed []models.CollapsedOverlap) []CollapsedOverlap {
+	return mapAll(collapsed, NewCollapsedOverlap)
+}
+
+// NewMonthlySumResponse складывает помесячные расходы в итоговые суммы
+func NewMonthlySumResponse(months []models.MonthlySpend, overlap string, collapsed []models.CollapsedOverlap) MonthlySumResponse {
+	resp := MonthlySumResponse{
+		Months:    make([]MonthlySpend, 0, len(months)),
+		Overlap:   overlap,
+		Collapsed: NewCollapsedOverlaps(collapsed),
+	}
+	for _, m := range months {
+		resp.Gross += m.Gross
+		resp.Discount += m.Discount
+		resp.Sum += m.Net
+		resp.Months = append(resp.Months, NewMonthlySpend(m))
+	}
+	return resp
+}
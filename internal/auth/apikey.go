@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"subscriptions-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeSubscriptionsRead  = "subscriptions:read"
+	ScopeSubscriptionsWrite = "subscriptions:write"
+	ScopeAnalyticsRead      = "analytics:read"
+)
+
+var Scopes = []string{ScopeSubscriptionsRead, ScopeSubscriptionsWrite, ScopeAnalyticsRead}
+
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// apiKeyPrefix отличает ключи сервиса от прочих секретов, например при поиске утечек
+const apiKeyPrefix = "sk_"
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	ErrAPIKeyExpired = errors.New("api key expired")
+	ErrAPIKeyRevoked = errors.New("api key revoked")
+)
+
+// APIKeyStore - хранилище ключей, нужное middleware
+type APIKeyStore interface {
+	// FindAPIKeyByPrefix возвращает ключ по открытой части или gorm.ErrRecordNotFound
+	FindAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	// RecordAPIKeyUsage добавляет count запросов к счетчику ключа и выставляет время последнего
+	RecordAPIKeyUsage(ctx context.Context, id uuid.UUID, at time.Time, count int) error
+}
+
+// GenerateAPIKey создает новый ключ вида sk_<prefix>.<secret>.
+// Клиенту отдается key, в БД сохраняются только prefix и hash.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	var public [6]byte
+	var secret [32]byte
+	if _, err = rand.Read(public[:]); err != nil {
+		return "", "", "", err
+	}
+	if _, err = rand.Read(secret[:]); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(public[:])
+	key = apiKeyPrefix + prefix + "." + base64.RawURLEncoding.EncodeToString(secret[:])
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey - SHA-256 ключа. Ключ случайный и длинный, поэтому медленный KDF не нужен.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func parseAPIKey(key string) (prefix string, ok bool) {
+	rest, found := strings.CutPrefix(key, apiKeyPrefix)
+	if !found {
+		return "", false
+	}
+	prefix, secret, found := strings.Cut(rest, ".")
+	if !found || prefix == "" || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+// VerifyAPIKey находит ключ в хранилище, сверяет хэш, срок действия и отзыв
+func VerifyAPIKey(ctx context.Context, store APIKeyStore, key string, now time.Time) (*models.APIKey, error) {
+	prefix, ok := parseAPIKey(key)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	stored, err := store.FindAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(stored.KeyHash), []byte(HashAPIKey(key))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	if stored.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+	if stored.ExpiresAt != nil && !now.Before(*stored.ExpiresAt) {
+		return nil, ErrAPIKeyExpired
+	}
+	return stored, nil
+}
+
+func apiKeyPrincipal(key *models.APIKey) Principal {
+	return Principal{
+		TenantID:       key.TenantID,
+		APIKeyID:       key.ID,
+		Scopes:         key.Scopes,
+		AllowedUserIDs: key.AllowedUserIDs,
+	}
+}
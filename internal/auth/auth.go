@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/trace"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Principal - аутентифицированный клиент запроса: пользователь с JWT
+// или сервисный клиент с API-ключом (APIKeyID не пустой)
+type Principal struct {
+	UserID uuid.UUID
+	Admin  bool
+	// TenantID - арендатор из токена или API-ключа, пустой - не задан
+	TenantID string
+
+	APIKeyID uuid.UUID
+	Scopes   []string
+	// AllowedUserIDs ограничивает пользователей, доступных по API-ключу. Пустой - без ограничений.
+	AllowedUserIDs []uuid.UUID
+}
+
+func (p Principal) IsAPIKey() bool {
+	return p.APIKeyID != uuid.Nil
+}
+
+// CanAccess сообщает, может ли клиент работать с данными пользователя userID
+func (p Principal) CanAccess(userID uuid.UUID) bool {
+	if p.IsAPIKey() {
+		return len(p.AllowedUserIDs) == 0 || slices.Contains(p.AllowedUserIDs, userID)
+	}
+	return p.Admin || p.UserID == userID
+}
+
+// HasScope сообщает, разрешено ли клиенту действие scope. У API-ключа проверяется
+// список scopes, пользователю доступны подписки, администратору - еще и аналитика.
+func (p Principal) HasScope(scope string) bool {
+	if p.IsAPIKey() {
+		return slices.Contains(p.Scopes, scope)
+	}
+	return scope != ScopeAnalyticsRead || p.Admin
+}
+
+type ctxKey string
+
+const principalKey ctxKey = "principal"
+
+var ErrNoPrincipal = errors.New("unauthenticated")
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey).(Principal)
+	return p, ok
+}
+
+func setPrincipal(c *gin.Context, p Principal) {
+	c.Set(string(principalKey), p)
+	c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), p))
+}
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidToken       = errors.New("invalid token")
+)
+
+// Authenticate проверяет значение заголовка Authorization: Bearer <JWT> или ApiKey <ключ>.
+// Ошибка - ErrMissingCredentials, ErrInvalidToken или ErrInvalidAPIKey с причиной в цепочке.
+// Другие ошибки - сбой хранилища ключей, а не отказ клиенту.
+// keys может быть nil, тогда API-ключи не принимаются.
+func Authenticate(ctx context.Context, v *Verifier, keys APIKeyStore, header string) (Principal, error) {
+	if key, found := strings.CutPrefix(header, "ApiKey "); found && keys != nil {
+		return authenticateAPIKey(ctx, keys, key)
+	}
+
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found || token == "" {
+		return Principal{}, ErrMissingCredentials
+	}
+
+	p, err := v.Verify(ctx, token)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return p, nil
+}
+
+func authenticateAPIKey(ctx context.Context, keys APIKeyStore, key string) (Principal, error) {
+	traceID := trace.TraceIDFromContext(ctx)
+	now := time.Now()
+
+	stored, err := VerifyAPIKey(ctx, keys, key, now)
+	switch {
+	case errors.Is(err, ErrInvalidAPIKey):
+		return Principal{}, err
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, ErrAPIKeyExpired), errors.Is(err, ErrAPIKeyRevoked):
+		return Principal{}, fmt.Errorf("%w: %w", ErrInvalidAPIKey, err)
+	case err != nil:
+		return Principal{}, fmt.Errorf("verify api key: %w", err)
+	}
+
+	// ошибка учета использования не должна ломать запрос клиента
+	if err := keys.RecordAPIKeyUsage(ctx, stored.ID, now, 1); err != nil {
+		logger.Log.Error("Ошибка учета использования API-ключа", "trace_id", traceID, "api_key_id", stored.ID, "error", err)
+	}
+	logger.Log.Debug("Запрос по API-ключу", "trace_id", traceID, "api_key_id", stored.ID, "name", stored.Name)
+
+	return apiKeyPrincipal(stored), nil
+}
+
+// Middleware проверяет заголовок Authorization через Authenticate
+// и кладет Principal в контекст запроса
+func Middleware(v *Verifier, keys APIKeyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, err := Authenticate(c.Request.Context(), v, keys, c.GetHeader("Authorization"))
+		if err != nil {
+			traceID, _ := c.Get("trace_id")
+			switch {
+			case errors.Is(err, ErrMissingCredentials):
+				c.Header("WWW-Authenticate", `Bearer`)
+				apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "missing bearer token"))
+			case errors.Is(err, ErrInvalidAPIKey):
+				logger.Log.Warn("Невалидный API-ключ", "trace_id", traceID, "error", err)
+				apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidAPIKey, "invalid api key"))
+			case errors.Is(err, ErrInvalidToken):
+				logger.Log.Warn("Невалидный JWT", "trace_id", traceID, "error", err)
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+				apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "invalid token"))
+			default:
+				// хранилище ключей недоступно: 500 с записью в лог, как ошибки репозитория
+				apierror.Abort(c, err)
+			}
+			return
+		}
+
+		setPrincipal(c, p)
+		c.Next()
+	}
+}
+
+// Disabled пропускает все запросы с правами администратора.
+// Используется, когда аутентификация выключена конфигурацией, и в тестах.
+func Disabled() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setPrincipal(c, Principal{Admin: true})
+		c.Next()
+	}
+}
+
+// RequireAdmin пропускает только клиентов с ролью администратора
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := PrincipalFromContext(c.Request.Context())
+		if !ok {
+			apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, ErrNoPrincipal.Error()))
+			return
+		}
+		if !p.Admin {
+			apierror.Abort(c, apierror.New(http.StatusForbidden, apierror.CodeForbidden, "admin role required"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope пропускает только клиентов, которым разрешено действие scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := PrincipalFromContext(c.Request.Context())
+		if !ok {
+			apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, ErrNoPrincipal.Error()))
+			return
+		}
+		if !p.HasScope(scope) {
+			apierror.Abort(c, apierror.New(http.StatusForbidden, apierror.CodeInsufficientScope, "scope "+scope+" required"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// ClientKey идентифицирует клиента запроса: API-ключ, пользователь из токена
+// или IP для остальных. Должен вызываться после аутентификации.
+func ClientKey(c *gin.Context) string {
+	if p, ok := PrincipalFromContext(c.Request.Context()); ok {
+		switch {
+		case p.IsAPIKey():
+			return "apikey:" + p.APIKeyID.String()
+		case p.UserID != uuid.Nil:
+			return "user:" + p.UserID.String()
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"subscriptions-service/internal/logger"
+)
+
+// KeySet - набор открытых ключей RS256 по kid
+type KeySet interface {
+	Key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: invalid n: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: invalid e: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks has no RS256 keys")
+	}
+	return keys, nil
+}
+
+// staticKeySet - ключи, загруженные один раз (например, из файла)
+type staticKeySet map[string]*rsa.PublicKey
+
+func (s staticKeySet) Key(_ context.Context, kid string) (*rsa.PublicKey, error) {
+	return lookupKey(s, kid)
+}
+
+func lookupKey(keys map[string]*rsa.PublicKey, kid string) (*rsa.PublicKey, error) {
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	// токен без kid допустим, если ключ в наборе один
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func LoadJWKSFile(path string) (KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+	return staticKeySet(keys), nil
+}
+
+// remoteKeySet загружает ключи по URL и обновляет их раз в refresh или при встрече
+// неизвестного kid. Попытки загрузки делаются не чаще раза в minRefetch.
+type remoteKeySet struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+const minRefetch = 30 * time.Second
+
+func NewRemoteJWKS(ctx context.Context, url string, refresh time.Duration) (KeySet, error) {
+	s := &remoteKeySet{
+		url:         url,
+		refresh:     refresh,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		attemptedAt: time.Now(),
+	}
+	if err := s.fetch(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *remoteKeySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
+
+func (s *remoteKeySet) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, known := s.keys[kid]
+	stale := time.Since(s.fetchedAt) > s.refresh || (!known && kid != "")
+	if stale && time.Since(s.attemptedAt) > minRefetch {
+		s.attemptedAt = time.Now()
+		if err := s.fetch(ctx); err != nil {
+			// продолжаем работать на старых ключах, если JWKS временно недоступен
+			logger.Log.Warn("Не удалось обновить JWKS", "url", s.url, "error", err)
+		}
+	}
+	return lookupKey(s.keys, kid)
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type VerifierConfig struct {
+	// HS256Secret - общий секрет для токенов HS256
+	HS256Secret string
+	// JWKS - ключи для токенов RS256
+	JWKS KeySet
+	// UserClaim - claim с UUID пользователя
+	UserClaim string
+	// RolesClaim - claim со списком ролей (массив строк или строка через пробел)
+	RolesClaim string
+	// TenantClaim - claim с арендатором, необязательный
+	TenantClaim string
+	AdminRole   string
+	Issuer      string
+	Audience    string
+}
+
+type Verifier struct {
+	cfg     VerifierConfig
+	methods []string
+}
+
+func NewVerifier(cfg VerifierConfig) (*Verifier, error) {
+	var methods []string
+	if cfg.HS256Secret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKS != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("no jwt keys configured")
+	}
+	if cfg.UserClaim == "" {
+		cfg.UserClaim = "sub"
+	}
+	return &Verifier{cfg: cfg, methods: methods}, nil
+}
+
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (Principal, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(v.methods),
+		jwt.WithExpirationRequired(),
+	}
+	if v.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.cfg.Issuer))
+	}
+	if v.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.cfg.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.Alg() {
+		case jwt.SigningMethodHS256.Alg():
+			return []byte(v.cfg.HS256Secret), nil
+		case jwt.SigningMethodRS256.Alg():
+			kid, _ := t.Header["kid"].(string)
+			return v.cfg.JWKS.Key(ctx, kid)
+		default:
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+	}, opts...)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	p := Principal{Admin: hasRole(claims[v.cfg.RolesClaim], v.cfg.AdminRole)}
+
+	rawUserID, _ := claims[v.cfg.UserClaim].(string)
+	userID, err := uuid.Parse(rawUserID)
+	if err != nil && !p.Admin {
+		return Principal{}, fmt.Errorf("claim %q is not a valid user id", v.cfg.UserClaim)
+	}
+	p.UserID = userID
+
+	if v.cfg.TenantClaim != "" {
+		p.TenantID, _ = claims[v.cfg.TenantClaim].(string)
+	}
+
+	return p, nil
+}
+
+func hasRole(claim interface{}, role string) bool {
+	if role == "" {
+		return false
+	}
+	switch roles := claim.(type) {
+	case string:
+		for _, r := range strings.FieldsFunc(roles, func(ch rune) bool { return ch == ' ' || ch == ',' }) {
+			if r == role {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, r := range roles {
+			if s, ok := r.(string); ok && s == role {
+				return true
+			}
+		}
+	}
+	return false
+}
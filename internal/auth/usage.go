@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"subscriptions-service/internal/logger"
+
+	"github.com/google/uuid"
+)
+
+// UsageBatcher - APIKeyStore, который копит учет использования ключей в памяти и пишет
+// его в store не чаще раза в interval на ключ. Без него каждый запрос по ключу обновлял
+// бы одну и ту же строку api_keys, и частые клиенты упирались бы в блокировку строки.
+// Первый запрос по ключу записывается сразу, остальные - следующей записью или Flush.
+type UsageBatcher struct {
+	APIKeyStore
+	interval time.Duration
+
+	mu    sync.Mutex
+	usage map[uuid.UUID]*keyUsage
+}
+
+type keyUsage struct {
+	count     int
+	lastUsed  time.Time
+	flushedAt time.Time
+}
+
+func NewUsageBatcher(store APIKeyStore, interval time.Duration) *UsageBatcher {
+	return &UsageBatcher{APIKeyStore: store, interval: interval, usage: map[uuid.UUID]*keyUsage{}}
+}
+
+func (b *UsageBatcher) RecordAPIKeyUsage(ctx context.Context, id uuid.UUID, at time.Time, count int) error {
+	b.mu.Lock()
+	u, ok := b.usage[id]
+	if !ok {
+		u = &keyUsage{}
+		b.usage[id] = u
+	}
+	u.count += count
+	if at.After(u.lastUsed) {
+		u.lastUsed = at
+	}
+	if at.Sub(u.flushedAt) < b.interval {
+		b.mu.Unlock()
+		return nil
+	}
+	pending, lastUsed := u.count, u.lastUsed
+	u.count, u.flushedAt = 0, at
+	b.mu.Unlock()
+
+	return b.write(ctx, id, lastUsed, pending)
+}
+
+// Flush записывает весь накопленный учет, например перед остановкой сервиса
+func (b *UsageBatcher) Flush(ctx context.Context) error {
+	now := time.Now()
+	type pending struct {
+		id       uuid.UUID
+		count    int
+		lastUsed time.Time
+	}
+	var batch []pending
+
+	b.mu.Lock()
+	for id, u := range b.usage {
+		if u.count == 0 {
+			// ключ давно не использовался, запись больше не нужна
+			if now.Sub(u.flushedAt) >= b.interval {
+				delete(b.usage, id)
+			}
+			continue
+		}
+		batch = append(batch, pending{id: id, count: u.count, lastUsed: u.lastUsed})
+		u.count, u.flushedAt = 0, now
+	}
+	b.mu.Unlock()
+
+	var errs []error
+	for _, p := range batch {
+		errs = append(errs, b.write(ctx, p.id, p.lastUsed, p.count))
+	}
+	return errors.Join(errs...)
+}
+
+// Run раз в interval сбрасывает учет ключей, по которым после записи не было запросов.
+// Завершается вместе с ctx; оставшийся учет записывает Flush при остановке.
+func (b *UsageBatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := b.Flush(ctx); err != nil {
+			logger.Log.Error("Ошибка записи учета использования API-ключей", "error", err)
+		}
+	}
+}
+
+// write пишет учет в store, а при ошибке возвращает его в очередь, чтобы не потерять запросы
+func (b *UsageBatcher) write(ctx context.Context, id uuid.UUID, lastUsed time.Time, count int) error {
+	err := b.APIKeyStore.RecordAPIKeyUsage(ctx, id, lastUsed, count)
+	if err != nil {
+		b.mu.Lock()
+		if u, ok := b.usage[id]; ok {
+			u.count += count
+		} else {
+			b.usage[id] = &keyUsage{count: count, lastUsed: lastUsed}
+		}
+		b.mu.Unlock()
+	}
+	return err
+}
@@ -1,39 +1,297 @@
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
-	"subscriptions-service/internal/logger"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+// Config собирается слоями: значения по умолчанию, YAML-файл, переменные окружения
+// и флаги командной строки; каждый следующий слой перекрывает предыдущий.
+// Тег env задает переменную окружения поля, флаг называется по пути в YAML (-db.host).
 type Config struct {
-	DBHost        string
-	DBPort        string
-	DBUser        string
-	DBPassword    string
-	DBName        string
-	ServerAddress string
-	LogLevel      string
+	Server    ServerConfig    `yaml:"server"`
+	DB        DBConfig        `yaml:"db"`
+	Log       LogConfig       `yaml:"log"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Tenant    TenantConfig    `yaml:"tenant"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	GraphQL   GraphQLConfig   `yaml:"graphql"`
+	OpenAPI   OpenAPIConfig   `yaml:"openapi"`
+	Features  FeaturesConfig  `yaml:"features"`
+}
+
+type ServerConfig struct {
+	Address           string        `yaml:"address" env:"SERVER_ADDRESS"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// ShutdownTimeout - сколько ждать завершения активных запросов при остановке
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	// DrainDelay - сколько /readyz отвечает 503 перед остановкой сервера,
+	// чтобы балансировщик успел убрать реплику
+	DrainDelay time.Duration `yaml:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY"`
+	// GRPCAddress - адрес gRPC API на отдельном порту, пустой - gRPC-сервер не запускается
+	GRPCAddress string `yaml:"grpc_address" env:"GRPC_ADDRESS"`
+}
+
+type DBConfig struct {
+	// Driver - postgres или sqlite. SQLite хранит все в одном файле Path и подходит
+	// для запуска одним бинарником без Postgres; реплики, аналитика и хранение
+	// лимитов запросов в БД с ним недоступны.
+	Driver string `yaml:"driver" env:"DB_DRIVER"`
+	Path   string `yaml:"path" env:"DB_PATH"`
+
+	Host     string `yaml:"host" env:"DB_HOST"`
+	Port     int    `yaml:"port" env:"DB_PORT"`
+	User     string `yaml:"user" env:"DB_USER"`
+	Password string `yaml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name     string `yaml:"name" env:"DB_NAME"`
+
+	// SSLMode - режим TLS libpq: disable, allow, prefer, require, verify-ca или verify-full.
+	// SSLRootCert - CA для проверки сервера, SSLCert и SSLKey - клиентский сертификат.
+	SSLMode     string `yaml:"sslmode" env:"DB_SSLMODE"`
+	SSLRootCert string `yaml:"sslrootcert" env:"DB_SSLROOTCERT"`
+	SSLCert     string `yaml:"sslcert" env:"DB_SSLCERT"`
+	SSLKey      string `yaml:"sslkey" env:"DB_SSLKEY"`
+
+	// пул соединений, 0 в MaxOpenConns - без ограничения
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+
+	// ConnectTimeout ограничивает установку одного соединения, StatementTimeout -
+	// выполнение одного SQL-запроса на стороне Postgres, RequestTimeout - всю работу
+	// с БД в рамках HTTP-запроса. 0 - без ограничения.
+	ConnectTimeout   time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+	StatementTimeout time.Duration `yaml:"statement_timeout" env:"DB_STATEMENT_TIMEOUT"`
+	RequestTimeout   time.Duration `yaml:"request_timeout" env:"DB_REQUEST_TIMEOUT"`
+	// StartupTimeout - сколько повторять подключение при запуске, пока Postgres недоступен
+	StartupTimeout time.Duration `yaml:"startup_timeout" env:"DB_STARTUP_TIMEOUT"`
+
+	// Replicas - строки подключения к репликам для чтения списков и сумм,
+	// в переменной окружения через запятую. ReadYourWritesWindow - сколько клиент
+	// после изменения читает с основной базы, 0 - не закреплять.
+	Replicas             []string      `yaml:"replicas" env:"DB_REPLICAS" secret:"true"`
+	ReadYourWritesWindow time.Duration `yaml:"read_your_writes_window" env:"DB_READ_YOUR_WRITES_WINDOW"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+}
+
+type AuthConfig struct {
+	// Disabled отключает проверку JWT: все запросы выполняются с правами администратора
+	Disabled    bool   `yaml:"disabled" env:"AUTH_DISABLED"`
+	HS256Secret string `yaml:"hs256_secret" env:"JWT_HS256_SECRET" secret:"true"`
+	JWKSFile    string `yaml:"jwks_file" env:"JWT_JWKS_FILE"`
+	JWKSURL     string `yaml:"jwks_url" env:"JWT_JWKS_URL"`
+	UserClaim   string `yaml:"user_claim" env:"JWT_USER_CLAIM"`
+	RolesClaim  string `yaml:"roles_claim" env:"JWT_ROLES_CLAIM"`
+	AdminRole   string `yaml:"admin_role" env:"JWT_ADMIN_ROLE"`
+	// TenantClaim - claim с арендатором
+	TenantClaim string `yaml:"tenant_claim" env:"JWT_TENANT_CLAIM"`
+	Issuer      string `yaml:"issuer" env:"JWT_ISSUER"`
+	Audience    string `yaml:"audience" env:"JWT_AUDIENCE"`
+}
+
+type TenantConfig struct {
+	// Header - заголовок с арендатором для токенов без claim арендатора
+	Header string `yaml:"header" env:"TENANT_HEADER"`
+	// TrustHeader - заголовок выставляет доверенный шлюз, поэтому он принимается
+	// от токенов и ключей без арендатора. При отключенной аутентификации заголовку
+	// доверяют всегда.
+	TrustHeader bool `yaml:"trust_header" env:"TENANT_TRUST_HEADER"`
+}
+
+// RateLimitConfig - token bucket на клиента: Capacity токенов, RefillPerSecond токенов в секунду
+type RateLimitConfig struct {
+	Enabled         bool    `yaml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	Capacity        int     `yaml:"capacity" env:"RATE_LIMIT_CAPACITY"`
+	RefillPerSecond float64 `yaml:"refill_per_second" env:"RATE_LIMIT_REFILL_PER_SECOND"`
+	// Store - memory или postgres
+	Store string `yaml:"store" env:"RATE_LIMIT_STORE"`
+}
+
+type TracingConfig struct {
+	// Exporter - none, otlp, stdout или file (в File)
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER"`
+	File        string  `yaml:"file" env:"TRACING_FILE"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	ServiceName string  `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+}
+
+// GraphQLConfig ограничивает запросы к /graphql до их выполнения: MaxDepth - вложенность
+// полей, MaxComplexity - оценку числа возвращаемых полей с учетом размеров списков
+type GraphQLConfig struct {
+	MaxDepth      int `yaml:"max_depth" env:"GRAPHQL_MAX_DEPTH"`
+	MaxComplexity int `yaml:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY"`
 }
 
-func LoadConfig() Config {
-	cfg := Config{
-		DBHost:        getEnv("DB_HOST"),
-		DBPort:        getEnv("DB_PORT"),
-		DBUser:        getEnv("DB_USER"),
-		DBPassword:    getEnv("DB_PASSWORD"),
-		DBName:        getEnv("DB_NAME"),
-		ServerAddress: getEnv("SERVER_ADDRESS"),
-		LogLevel:      getEnv("LOG_LEVEL"),
+// OpenAPIConfig включает проверку REST-запросов и ответов по swagger.json.
+// Проверка ответов буферизует их целиком и предназначена для тестовых стендов.
+type OpenAPIConfig struct {
+	ValidateRequests  bool `yaml:"validate_requests" env:"OPENAPI_VALIDATE_REQUESTS"`
+	ValidateResponses bool `yaml:"validate_responses" env:"OPENAPI_VALIDATE_RESPONSES"`
+}
+
+type FeaturesConfig struct {
+	// MigrateOnStart применяет встроенные миграции при запуске сервиса
+	MigrateOnStart bool `yaml:"migrate_on_start" env:"MIGRATE_ON_START"`
+	Metrics        bool `yaml:"metrics" env:"METRICS_ENABLED"`
+	Swagger        bool `yaml:"swagger" env:"SWAGGER_ENABLED"`
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Address:           ":8080",
+			GRPCAddress:       ":9090",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       time.Minute,
+			ShutdownTimeout:   10 * time.Second,
+			DrainDelay:        5 * time.Second,
+		},
+		DB: DBConfig{
+			Driver:           "postgres",
+			Path:             "subscriptions.db",
+			Port:             5432,
+			SSLMode:          "disable",
+			MaxOpenConns:     20,
+			MaxIdleConns:     10,
+			ConnMaxLifetime:  30 * time.Minute,
+			ConnMaxIdleTime:  5 * time.Minute,
+			ConnectTimeout:   5 * time.Second,
+			StatementTimeout: 30 * time.Second,
+			RequestTimeout:   10 * time.Second,
+			StartupTimeout:   time.Minute,
+
+			ReadYourWritesWindow: 5 * time.Second,
+		},
+		Log: LogConfig{Level: "info"},
+		Auth: AuthConfig{
+			UserClaim:   "sub",
+			RolesClaim:  "roles",
+			AdminRole:   "admin",
+			TenantClaim: "tenant_id",
+		},
+		Tenant: TenantConfig{Header: "X-Tenant-ID"},
+		RateLimit: RateLimitConfig{
+			Enabled:         true,
+			Capacity:        60,
+			RefillPerSecond: 1,
+			Store:           "memory",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			File:        "traces.jsonl",
+			SampleRatio: 1,
+			ServiceName: "subscriptions-service",
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:      6,
+			MaxComplexity: 1000,
+		},
+		OpenAPI: OpenAPIConfig{ValidateRequests: true},
+		Features: FeaturesConfig{
+			Metrics: true,
+			Swagger: true,
+		},
 	}
+}
 
-	logger.Log.Info("Загружена конфигурация", "config", cfg)
-	return cfg
+// Load собирает конфигурацию из всех слоев и проверяет ее. Путь к YAML-файлу
+// задается флагом -config или переменной CONFIG_FILE. Возвращает аргументы,
+// оставшиеся после флагов (подкоманду), и все найденные ошибки разом.
+func Load(args []string) (Config, []string, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("subscriptions-api", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "путь к YAML-файлу конфигурации (env CONFIG_FILE)")
+	flagValues := make(map[string]string)
+	for _, f := range fields(&cfg) {
+		path := f.path
+		usage := fmt.Sprintf("env %s", f.env)
+		record := func(s string) error {
+			flagValues[path] = s
+			return nil
+		}
+		if f.isBool() {
+			fs.BoolFunc(path, usage, record)
+		} else {
+			fs.Func(path, usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+
+	var errs []error
+	if *configFile != "" {
+		if err := loadYAML(&cfg, *configFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	fieldList := fields(&cfg)
+	for _, f := range fieldList {
+		// пустая переменная считается незаданной, как пустые значения в .env.example
+		if raw := os.Getenv(f.env); raw != "" {
+			if err := f.set(raw); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", f.env, err))
+			}
+		}
+	}
+	for _, f := range fieldList {
+		if raw, ok := flagValues[f.path]; ok {
+			if err := f.set(raw); err != nil {
+				errs = append(errs, fmt.Errorf("flag -%s: %w", f.path, err))
+			}
+		}
+	}
+
+	errs = append(errs, cfg.Validate()...)
+	if len(errs) > 0 {
+		return cfg, nil, errors.Join(errs...)
+	}
+	return cfg, fs.Args(), nil
+}
+
+func loadYAML(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
 }
 
-func getEnv(key string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// LogValue скрывает секреты, чтобы конфигурацию можно было писать в лог целиком
+func (c Config) LogValue() slog.Value {
+	fieldList := fields(&c)
+	attrs := make([]slog.Attr, 0, len(fieldList))
+	for _, f := range fieldList {
+		if f.secret && !f.value.IsZero() {
+			attrs = append(attrs, slog.String(f.path, "[REDACTED]"))
+			continue
+		}
+		attrs = append(attrs, slog.Any(f.path, f.value.Interface()))
 	}
-	logger.Log.Error("Не установлена переменная окружения", "var", key)
-	panic("Do not set enviroment variable")
+	return slog.GroupValue(attrs...)
 }
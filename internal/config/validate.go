@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Validate возвращает все найденные ошибки конфигурации, а не только первую
+func (c Config) Validate() []error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	oneOf := func(path, value string, allowed ...string) {
+		if !slices.Contains(allowed, value) {
+			fail("%s must be one of %v, got %q", path, allowed, value)
+		}
+	}
+	nonNegative := func(path string, d time.Duration) {
+		if d < 0 {
+			fail("%s must not be negative", path)
+		}
+	}
+
+	if c.Server.Address == "" {
+		fail("server.address is required")
+	}
+	nonNegative("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	nonNegative("server.read_timeout", c.Server.ReadTimeout)
+	nonNegative("server.write_timeout", c.Server.WriteTimeout)
+	nonNegative("server.idle_timeout", c.Server.IdleTimeout)
+	nonNegative("server.drain_delay", c.Server.DrainDelay)
+	if c.Server.ShutdownTimeout <= 0 {
+		fail("server.shutdown_timeout must be positive")
+	}
+	if c.Server.GRPCAddress != "" && c.Server.GRPCAddress == c.Server.Address {
+		fail("server.grpc_address must differ from server.address")
+	}
+
+	oneOf("db.driver", c.DB.Driver, "postgres", "sqlite")
+	switch c.DB.Driver {
+	case "postgres":
+		if c.DB.Host == "" {
+			fail("db.host is required")
+		}
+		if c.DB.Port < 1 || c.DB.Port > 65535 {
+			fail("db.port must be between 1 and 65535, got %d", c.DB.Port)
+		}
+		if c.DB.User == "" {
+			fail("db.user is required")
+		}
+		if c.DB.Name == "" {
+			fail("db.name is required")
+		}
+	case "sqlite":
+		if c.DB.Path == "" {
+			fail("db.path is required for the sqlite driver")
+		}
+		if len(c.DB.Replicas) > 0 {
+			fail("db.replicas are not supported by the sqlite driver")
+		}
+		if c.RateLimit.Store == "postgres" {
+			fail("rate_limit.store postgres is not supported by the sqlite driver")
+		}
+	}
+	if c.DB.MaxOpenConns < 0 {
+		fail("db.max_open_conns must not be negative")
+	}
+	if c.DB.MaxIdleConns < 0 {
+		fail("db.max_idle_conns must not be negative")
+	}
+	if c.DB.MaxOpenConns > 0 && c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		fail("db.max_idle_conns (%d) must not exceed db.max_open_conns (%d)", c.DB.MaxIdleConns, c.DB.MaxOpenConns)
+	}
+	nonNegative("db.conn_max_lifetime", c.DB.ConnMaxLifetime)
+	nonNegative("db.conn_max_idle_time", c.DB.ConnMaxIdleTime)
+	nonNegative("db.connect_timeout", c.DB.ConnectTimeout)
+	nonNegative("db.statement_timeout", c.DB.StatementTimeout)
+	nonNegative("db.request_timeout", c.DB.RequestTimeout)
+	nonNegative("db.startup_timeout", c.DB.StartupTimeout)
+	nonNegative("db.read_your_writes_window", c.DB.ReadYourWritesWindow)
+	for i, dsn := range c.DB.Replicas {
+		if dsn == "" {
+			fail("db.replicas[%d] must not be empty", i)
+		}
+	}
+	oneOf("db.sslmode", c.DB.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	if (c.DB.SSLCert == "") != (c.DB.SSLKey == "") {
+		fail("db.sslcert and db.sslkey must be set together")
+	}
+	if c.DB.SSLMode == "disable" && (c.DB.SSLRootCert != "" || c.DB.SSLCert != "") {
+		fail("db.sslrootcert and db.sslcert require db.sslmode other than disable")
+	}
+
+	oneOf("log.level", c.Log.Level, "debug", "info", "warn", "error")
+
+	if !c.Auth.Disabled && c.Auth.HS256Secret == "" && c.Auth.JWKSFile == "" && c.Auth.JWKSURL == "" {
+		fail("one of auth.hs256_secret, auth.jwks_file or auth.jwks_url is required unless auth.disabled is set")
+	}
+	if c.Auth.JWKSFile != "" && c.Auth.JWKSURL != "" {
+		fail("auth.jwks_file and auth.jwks_url are mutually exclusive")
+	}
+	if c.Auth.UserClaim == "" {
+		fail("auth.user_claim is required")
+	}
+
+	if c.Tenant.Header == "" {
+		fail("tenant.header is required")
+	}
+
+	if c.RateLimit.Capacity <= 0 {
+		fail("rate_limit.capacity must be positive")
+	}
+	if c.RateLimit.RefillPerSecond <= 0 {
+		fail("rate_limit.refill_per_second must be positive")
+	}
+	oneOf("rate_limit.store", c.RateLimit.Store, "memory", "postgres")
+
+	oneOf("tracing.exporter", c.Tracing.Exporter, "none", "otlp", "stdout", "file")
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio must be between 0 and 1")
+	}
+	if c.Tracing.Exporter == "file" && c.Tracing.File == "" {
+		fail("tracing.file is required for the file exporter")
+	}
+
+	if c.GraphQL.MaxDepth <= 0 {
+		fail("graphql.max_depth must be positive")
+	}
+	if c.GraphQL.MaxComplexity <= 0 {
+		fail("graphql.max_complexity must be positive")
+	}
+
+	return errs
+}
@@ -4,16 +4,23 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
 	"subscriptions-service/internal/config"
 	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/metrics"
 	"subscriptions-service/internal/trace"
 	"time"
 
+	"github.com/glebarez/sqlite"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	gormlogger "gorm.io/gorm/logger"
 )
 
+// gormLoggerWithTrace пишет метрики и отладочный лог SQL-запросов. Спаны запросов
+// создает tracePlugin: в логгер приходит текст с уже подставленными параметрами.
 type gormLoggerWithTrace struct {
 	inner gormlogger.Interface
 }
@@ -37,22 +44,37 @@ func (g *gormLoggerWithTrace) Error(ctx context.Context, msg string, data ...int
 func (g *gormLoggerWithTrace) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
 	sql, rows := fc()
 	traceID := trace.TraceIDFromContext(ctx)
+	duration := time.Since(begin)
+	operation := queryOperation(sql)
+
+	metrics.ObserveQuery(operation, duration, queryFailed(err))
 
 	logger.Log.Debug("SQL executed",
 		"trace_id", traceID,
 		"sql", sql,
 		"rows", rows,
-		"duration_ms", time.Since(begin).Milliseconds(),
+		"duration_ms", duration.Milliseconds(),
 		"error", err,
 	)
 }
 
-func Connect(cfg config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort)
+// queryOperation - тип SQL-запроса по первому слову для метрик и имен спанов
+func queryOperation(sql string) string {
+	word, _, _ := strings.Cut(strings.TrimSpace(sql), " ")
+	switch op := strings.ToLower(word); op {
+	case "select", "insert", "update", "delete", "with":
+		return op
+	default:
+		return "other"
+	}
+}
 
+// Connect открывает пул соединений к базе, выбранной cfg.DB.Driver. Пока Postgres
+// недоступен, подключение повторяется с экспоненциальной задержкой до истечения
+// cfg.DB.StartupTimeout. Файл SQLite открывается сразу, без повторов.
+func Connect(cfg config.Config) (*gorm.DB, error) {
 	var gormLevel gormlogger.LogLevel
-	switch cfg.LogLevel {
+	switch cfg.Log.Level {
 	case "debug":
 		gormLevel = gormlogger.Info
 	case "warn":
@@ -72,13 +94,96 @@ func Connect(cfg config.Config) (*gorm.DB, error) {
 		},
 	)}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: newLogger,
-	})
+	var db *gorm.DB
+	var err error
+	tracing := &tracePlugin{system: semconv.DBSystemPostgreSQL}
+	if cfg.DB.Driver == "sqlite" {
+		tracing.system = semconv.DBSystemSqlite
+		db, err = gorm.Open(sqlite.Open(SQLiteDSN(cfg.DB)), &gorm.Config{Logger: newLogger})
+	} else {
+		db, err = openWithRetry(DSN(cfg.DB), &gorm.Config{Logger: newLogger}, cfg.DB.StartupTimeout)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if err := db.Use(tracing); err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.DB.ConnMaxIdleTime)
 
 	logger.Log.Info("Подключение к базе данных успешно")
 	return db, nil
 }
+
+const (
+	retryInitialDelay = 500 * time.Millisecond
+	retryMaxDelay     = 10 * time.Second
+)
+
+func openWithRetry(dsn string, gormCfg *gorm.Config, timeout time.Duration) (*gorm.DB, error) {
+	deadline := time.Now().Add(timeout)
+	delay := retryInitialDelay
+	for attempt := 1; ; attempt++ {
+		db, err := gorm.Open(postgres.Open(dsn), gormCfg)
+		if err == nil {
+			return db, nil
+		}
+
+		wait := min(delay, time.Until(deadline))
+		if wait <= 0 {
+			return nil, fmt.Errorf("connect to database after %d attempts: %w", attempt, err)
+		}
+		logger.Log.Warn("БД недоступна, повторяем подключение", "attempt", attempt, "retry_in", wait.String(), "error", err)
+		time.Sleep(wait)
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// DSN собирает строку подключения в формате key=value. statement_timeout
+// передается как параметр сессии и действует на все запросы соединения.
+func DSN(cfg config.DBConfig) string {
+	params := []struct{ key, value string }{
+		{"host", cfg.Host},
+		{"port", strconv.Itoa(cfg.Port)},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"dbname", cfg.Name},
+		{"sslmode", cfg.SSLMode},
+		{"sslrootcert", cfg.SSLRootCert},
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+	}
+	if cfg.ConnectTimeout > 0 {
+		// libpq принимает только целые секунды
+		seconds := int((cfg.ConnectTimeout + time.Second - 1) / time.Second)
+		params = append(params, struct{ key, value string }{"connect_timeout", strconv.Itoa(seconds)})
+	}
+	if cfg.StatementTimeout > 0 {
+		params = append(params, struct{ key, value string }{"statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)})
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		if p.value == "" {
+			continue
+		}
+		parts = append(parts, p.key+"="+quoteDSNValue(p.value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// quoteDSNValue экранирует значение, если в нем есть пробелы, кавычки или обратная косая черта
+func quoteDSNValue(v string) string {
+	if !strings.ContainsAny(v, " '\\") {
+		return v
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"net/url"
+
+	"subscriptions-service/internal/config"
+
+	"gorm.io/gorm"
+)
+
+// sqliteBusyTimeoutMs - сколько соединение ждет блокировку записи, прежде чем вернуть SQLITE_BUSY
+const sqliteBusyTimeoutMs = "5000"
+
+// SQLiteDSN - путь к файлу базы с прагмами соединения: внешние ключи включены,
+// WAL позволяет читать параллельно с записью, а запись ждет блокировку вместо ошибки
+func SQLiteDSN(cfg config.DBConfig) string {
+	q := url.Values{}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Add("_pragma", "busy_timeout("+sqliteBusyTimeoutMs+")")
+	return cfg.Path + "?" + q.Encode()
+}
+
+// IsSQLite сообщает, открыт ли db драйвером SQLite
+func IsSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
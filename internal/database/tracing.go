@@ -0,0 +1,83 @@
+package database
+
+import (
+	"errors"
+	"subscriptions-service/internal/trace"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// traceBeginKey - время начала запроса в настройках экземпляра gorm
+const traceBeginKey = "subscriptions:trace_begin"
+
+// tracePlugin создает спаны SQL-запросов в колбэках gorm. В отличие от логгера, колбэки
+// видят Statement.SQL до подстановки параметров, поэтому в коллектор не уходят значения
+// запросов: хэши API-ключей, идентификаторы пользователей и арендаторов.
+type tracePlugin struct {
+	// system - атрибут db.system для спанов запросов
+	system attribute.KeyValue
+}
+
+func (p *tracePlugin) Name() string {
+	return "subscriptions:trace"
+}
+
+func (p *tracePlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("trace:before_create", p.before),
+		cb.Create().After("gorm:create").Register("trace:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("trace:before_query", p.before),
+		cb.Query().After("gorm:query").Register("trace:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("trace:before_update", p.before),
+		cb.Update().After("gorm:update").Register("trace:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("trace:before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register("trace:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("trace:before_row", p.before),
+		cb.Row().After("gorm:row").Register("trace:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("trace:before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register("trace:after_raw", p.after),
+	)
+}
+
+func (p *tracePlugin) before(db *gorm.DB) {
+	db.InstanceSet(traceBeginKey, time.Now())
+}
+
+func (p *tracePlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(traceBeginKey)
+	if !ok || db.DryRun || db.Statement.SQL.Len() == 0 {
+		return
+	}
+	begin := value.(time.Time)
+	sql := db.Statement.SQL.String()
+	operation := queryOperation(sql)
+
+	attrs := []attribute.KeyValue{p.system, semconv.DBOperationName(operation), semconv.DBQueryText(sql)}
+	// для Row и Rows строки еще не прочитаны, и gorm сообщает -1
+	if db.RowsAffected >= 0 {
+		attrs = append(attrs, attribute.Int64("db.rows_affected", db.RowsAffected))
+	}
+
+	// спан создается задним числом: колбэк вызывается после выполнения запроса
+	_, span := trace.Tracer().Start(db.Statement.Context, "SQL "+operation,
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithTimestamp(begin),
+		oteltrace.WithAttributes(attrs...),
+	)
+	if queryFailed(db.Error) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+	span.End()
+}
+
+// queryFailed сообщает, считается ли ошибка запроса сбоем: отсутствие записи им не является
+func queryFailed(err error) bool {
+	return err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+}
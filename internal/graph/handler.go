@@ -0,0 +1,56 @@
+// Package graph - GraphQL API поверх SubscriptionRepository. Схема в schema.graphqls,
+// код исполнения генерируется gqlgen (make graphql).
+package graph
+
+import (
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/repository"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/gin-gonic/gin"
+)
+
+// Limits ограничивают запрос до выполнения резолверов
+type Limits struct {
+	// MaxDepth - наибольшая вложенность полей запроса
+	MaxDepth int
+	// MaxComplexity - наибольшая оценка сложности запроса, см. complexity
+	MaxComplexity int
+}
+
+type Handler struct {
+	repo   repository.SubscriptionRepository
+	server *handler.Server
+}
+
+func NewHandler(repo repository.SubscriptionRepository, limits Limits) *Handler {
+	schema := NewExecutableSchema(Config{
+		Resolvers:  &Resolver{repo: repo},
+		Complexity: complexity(),
+	})
+
+	srv := handler.New(schema)
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.Use(extension.Introspection{})
+	srv.Use(DepthLimit{Limit: limits.MaxDepth})
+	srv.Use(extension.FixedComplexityLimit(limits.MaxComplexity))
+
+	return &Handler{repo: repo, server: srv}
+}
+
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	read := auth.RequireScope(auth.ScopeSubscriptionsRead)
+
+	r.POST("/graphql", read, h.Serve)
+	r.GET("/graphql", read, h.Serve)
+}
+
+// Serve выполняет запрос GraphQL. Загрузчики создаются на каждый запрос, чтобы
+// кэш не переживал запрос и не смешивал данные разных клиентов и арендаторов.
+func (h *Handler) Serve(c *gin.Context) {
+	ctx := withLoaders(c.Request.Context(), newLoaders(h.repo))
+	h.server.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
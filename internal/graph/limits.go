@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"subscriptions-service/internal/graph/model"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const errDepthLimit = "DEPTH_LIMIT_EXCEEDED"
+
+// DepthLimit отклоняет операции с вложенностью полей больше Limit до выполнения
+// резолверов. Поля интроспекции (__schema, __type) не учитываются: запрос схемы
+// клиентами глубже любого запроса к данным.
+type DepthLimit struct {
+	Limit int
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = DepthLimit{}
+
+func (d DepthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (d DepthLimit) Validate(graphql.ExecutableSchema) error {
+	if d.Limit < 1 {
+		return errors.New("depth limit must be positive")
+	}
+	return nil
+}
+
+func (d DepthLimit) MutateOperationContext(_ context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if depth := selectionDepth(rc.Operation.SelectionSet); depth > d.Limit {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.Limit)
+		errcode.Set(err, errDepthLimit)
+		return err
+	}
+	return nil
+}
+
+// selectionDepth - наибольшая вложенность полей; фрагменты глубину не добавляют.
+// Циклы фрагментов отклоняет валидация запроса до расширений.
+func selectionDepth(set ast.SelectionSet) int {
+	depth := 0
+	for _, sel := range set {
+		var d int
+		switch s := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			d = 1 + selectionDepth(s.SelectionSet)
+		case *ast.InlineFragment:
+			d = selectionDepth(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				d = selectionDepth(s.Definition.SelectionSet)
+			}
+		}
+		depth = max(depth, d)
+	}
+	return depth
+}
+
+// complexity оценивает стоимость полей-списков по числу элементов, которые они
+// могут вернуть; остальные поля стоят 1 плюс стоимость вложенных
+func complexity() ComplexityRoot {
+	var c ComplexityRoot
+	c.Query.Users = func(childComplexity int, ids []uuid.UUID) int {
+		return 1 + len(ids)*childComplexity
+	}
+	c.User.Subscriptions = func(childComplexity int, _ *model.Period, _ *string, limit *int, _ *int) int {
+		return 1 + pageLimit(limit)*childComplexity
+	}
+	return c
+}
@@ -0,0 +1,145 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// loaderWait - сколько загрузчик собирает ключи перед запросом к репозиторию.
+// Резолверы элементов списка выполняются параллельно и успевают попасть в один пакет.
+const loaderWait = 2 * time.Millisecond
+
+// filter - аргументы поля User, общие для всех пользователей пакета. Поля сравнимы,
+// чтобы filter был частью ключа загрузчика: месяцы хранятся строками, "" - не задан.
+type filter struct {
+	serviceName string
+	hasService  bool
+	start, end  string
+	overlap     repository.OverlapPolicy
+}
+
+func newFilter(serviceName *string, start, end *time.Time, overlap repository.OverlapPolicy) filter {
+	f := filter{overlap: overlap}
+	if serviceName != nil {
+		f.serviceName, f.hasService = *serviceName, true
+	}
+	if start != nil {
+		f.start = start.Format(filterMonth)
+	}
+	if end != nil {
+		f.end = end.Format(filterMonth)
+	}
+	return f
+}
+
+const filterMonth = "2006-01"
+
+// args возвращает фильтр в виде аргументов репозитория
+func (f filter) args() (serviceName *string, start, end *time.Time) {
+	if f.hasService {
+		name := f.serviceName
+		serviceName = &name
+	}
+	if f.start != "" {
+		t, _ := time.Parse(filterMonth, f.start)
+		start = &t
+	}
+	if f.end != "" {
+		t, _ := time.Parse(filterMonth, f.end)
+		end = &t
+	}
+	return serviceName, start, end
+}
+
+type userKey struct {
+	userID uuid.UUID
+	filter filter
+}
+
+// Loaders собирают обращения резолверов к данным пользователей в пакеты: подписки
+// и расходы всех пользователей запроса с одинаковым фильтром читаются одним вызовом
+// репозитория, а повторные обращения берутся из кэша загрузчика. Создаются на каждый запрос.
+type Loaders struct {
+	subscriptions *dataloader.Loader[userKey, []models.Subscription]
+	spend         *dataloader.Loader[userKey, []models.ServiceMonthSpend]
+}
+
+func newLoaders(repo repository.SubscriptionRepository) *Loaders {
+	return &Loaders{
+		subscriptions: dataloader.NewBatchedLoader(
+			batchByFilter(func(ctx context.Context, userIDs []uuid.UUID, f filter) ([]models.Subscription, error) {
+				serviceName, start, end := f.args()
+				return repo.ListByUsers(ctx, userIDs, serviceName, start, end)
+			}, func(s models.Subscription) uuid.UUID { return s.UserID }),
+			dataloader.WithWait[userKey, []models.Subscription](loaderWait),
+		),
+		spend: dataloader.NewBatchedLoader(
+			batchByFilter(func(ctx context.Context, userIDs []uuid.UUID, f filter) ([]models.ServiceMonthSpend, error) {
+				serviceName, start, end := f.args()
+				return repo.ServiceMonthlySpend(ctx, userIDs, serviceName, start, end, f.overlap)
+			}, func(s models.ServiceMonthSpend) uuid.UUID { return s.UserID }),
+			dataloader.WithWait[userKey, []models.ServiceMonthSpend](loaderWait),
+		),
+	}
+}
+
+// batchByFilter строит пакетную функцию загрузчика: ключи группируются по фильтру,
+// для каждой группы репозиторий вызывается один раз, а строки раздаются по пользователям
+func batchByFilter[T any](
+	load func(ctx context.Context, userIDs []uuid.UUID, f filter) ([]T, error),
+	userOf func(T) uuid.UUID,
+) dataloader.BatchFunc[userKey, []T] {
+	return func(ctx context.Context, keys []userKey) []*dataloader.Result[[]T] {
+		results := make([]*dataloader.Result[[]T], len(keys))
+
+		groups := make(map[filter][]int)
+		var order []filter
+		for i, k := range keys {
+			if _, ok := groups[k.filter]; !ok {
+				order = append(order, k.filter)
+			}
+			groups[k.filter] = append(groups[k.filter], i)
+		}
+
+		for _, f := range order {
+			indexes := groups[f]
+			userIDs := make([]uuid.UUID, 0, len(indexes))
+			for _, i := range indexes {
+				userIDs = append(userIDs, keys[i].userID)
+			}
+
+			rows, err := load(ctx, userIDs, f)
+			if err != nil {
+				for _, i := range indexes {
+					results[i] = &dataloader.Result[[]T]{Error: err}
+				}
+				continue
+			}
+
+			byUser := make(map[uuid.UUID][]T)
+			for _, row := range rows {
+				byUser[userOf(row)] = append(byUser[userOf(row)], row)
+			}
+			for _, i := range indexes {
+				results[i] = &dataloader.Result[[]T]{Data: byUser[keys[i].userID]}
+			}
+		}
+		return results
+	}
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *Loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFromContext(ctx context.Context) *Loaders {
+	return ctx.Value(loadersKey{}).(*Loaders)
+}
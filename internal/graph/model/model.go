@@ -0,0 +1,9 @@
+package model
+
+import "github.com/google/uuid"
+
+// User - пользователь в графе. Отдельной таблицы пользователей нет, поэтому
+// объект несет только id, а подписки и расходы загружаются резолверами.
+type User struct {
+	ID uuid.UUID
+}
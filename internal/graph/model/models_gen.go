@@ -0,0 +1,73 @@
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
+
+package model
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"subscriptions-service/internal/models"
+)
+
+// Период в месяцах, границы включительно. Пустое начало - без ограничения, пустой конец - текущий месяц для сумм.
+type Period struct {
+	Start *models.MonthYearDate `json:"start,omitempty"`
+	End   *models.MonthYearDate `json:"end,omitempty"`
+}
+
+type Query struct {
+}
+
+type ServiceSpend struct {
+	ServiceName string `json:"serviceName"`
+	Gross       int    `json:"gross"`
+	Discount    int    `json:"discount"`
+	Net         int    `json:"net"`
+}
+
+// Политика учета пересекающихся записей одного сервиса, как параметр overlap в REST
+type OverlapPolicy string
+
+const (
+	// учитывается запись с максимальной ценой
+	OverlapPolicyMax OverlapPolicy = "MAX"
+	// учитываются все записи
+	OverlapPolicySum OverlapPolicy = "SUM"
+	// учитывается запись, начавшаяся позже остальных
+	OverlapPolicyLatest OverlapPolicy = "LATEST"
+)
+
+var AllOverlapPolicy = []OverlapPolicy{
+	OverlapPolicyMax,
+	OverlapPolicySum,
+	OverlapPolicyLatest,
+}
+
+func (e OverlapPolicy) IsValid() bool {
+	switch e {
+	case OverlapPolicyMax, OverlapPolicySum, OverlapPolicyLatest:
+		return true
+	}
+	return false
+}
+
+func (e OverlapPolicy) String() string {
+	return string(e)
+}
+
+func (e *OverlapPolicy) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OverlapPolicy(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OverlapPolicy", str)
+	}
+	return nil
+}
+
+func (e OverlapPolicy) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
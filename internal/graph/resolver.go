@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/graph/model"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Resolver - корневой резолвер GraphQL. Данные пользователей читаются через Loaders
+// из контекста запроса, отдельные подписки - напрямую из репозитория.
+type Resolver struct {
+	repo repository.SubscriptionRepository
+}
+
+// Размер страницы подписок, как limit в GET /subscriptions: значение вне диапазона
+// заменяется значением по умолчанию
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+)
+
+// pageLimit возвращает фактический размер страницы; его же учитывает сложность запроса
+func pageLimit(limit *int) int {
+	if limit == nil || *limit < 1 || *limit > maxPageLimit {
+		return defaultPageLimit
+	}
+	return *limit
+}
+
+// page вырезает страницу из подписок пользователя, загруженных целиком
+func page(subs []models.Subscription, limit, offset *int) []models.Subscription {
+	from := 0
+	if offset != nil && *offset > 0 {
+		from = *offset
+	}
+	if from >= len(subs) {
+		return []models.Subscription{}
+	}
+	return subs[from:min(from+pageLimit(limit), len(subs))]
+}
+
+func periodBounds(p *model.Period) (start, end *time.Time) {
+	if p == nil {
+		return nil, nil
+	}
+	if p.Start != nil {
+		t := time.Time(*p.Start)
+		start = &t
+	}
+	if p.End != nil {
+		t := time.Time(*p.End)
+		end = &t
+	}
+	return start, end
+}
+
+// loadSpend возвращает расходы пользователя по месяцам и сервисам через загрузчик
+func loadSpend(ctx context.Context, userID uuid.UUID, period *model.Period, serviceName *string, overlap *model.OverlapPolicy) ([]models.ServiceMonthSpend, error) {
+	policy := repository.OverlapMax
+	if overlap != nil {
+		p, err := repository.ParseOverlapPolicy(strings.ToLower(string(*overlap)))
+		if err != nil {
+			return nil, err
+		}
+		policy = p
+	}
+	start, end := periodBounds(period)
+
+	return loadersFromContext(ctx).spend.Load(ctx, userKey{
+		userID: userID,
+		filter: newFilter(serviceName, start, end, policy),
+	})()
+}
+
+// authorizeUser проверяет, что клиент запроса может читать данные пользователя userID.
+// Код ошибки передается в extensions.code, как принято в GraphQL.
+func authorizeUser(ctx context.Context, userID uuid.UUID) error {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return &gqlerror.Error{
+			Message:    auth.ErrNoPrincipal.Error(),
+			Extensions: map[string]any{"code": "UNAUTHENTICATED"},
+		}
+	}
+	if !p.CanAccess(userID) {
+		return &gqlerror.Error{
+			Message:    "access denied",
+			Extensions: map[string]any{"code": "FORBIDDEN"},
+		}
+	}
+	return nil
+}
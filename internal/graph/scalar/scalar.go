@@ -0,0 +1,52 @@
+// Package scalar - скаляры GraphQL, привязанные к типам сервиса
+package scalar
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"subscriptions-service/internal/models"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+)
+
+// MarshalUUID отдает ID как строку UUID
+func MarshalUUID(id uuid.UUID) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(id.String()))
+	})
+}
+
+func UnmarshalUUID(v any) (uuid.UUID, error) {
+	s, ok := v.(string)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("ID must be a string")
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid ID %q", s)
+	}
+	return id, nil
+}
+
+// MarshalMonth отдает месяц в формате MM-YYYY, как MonthYearDate в JSON
+func MarshalMonth(m models.MonthYearDate) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(time.Time(m).Format("01-2006")))
+	})
+}
+
+func UnmarshalMonth(v any) (models.MonthYearDate, error) {
+	s, ok := v.(string)
+	if !ok {
+		return models.MonthYearDate{}, fmt.Errorf("Month must be a string in MM-YYYY format")
+	}
+	t, err := time.Parse("01-2006", s)
+	if err != nil {
+		return models.MonthYearDate{}, fmt.Errorf("invalid month %q, expected MM-YYYY", s)
+	}
+	return models.MonthYearDate(t), nil
+}
@@ -0,0 +1,149 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.55
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"subscriptions-service/internal/graph/model"
+	"subscriptions-service/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// User is the resolver for the user field.
+func (r *queryResolver) User(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	if err := authorizeUser(ctx, id); err != nil {
+		return nil, err
+	}
+	return &model.User{ID: id}, nil
+}
+
+// Users is the resolver for the users field.
+func (r *queryResolver) Users(ctx context.Context, ids []uuid.UUID) ([]model.User, error) {
+	users := make([]model.User, 0, len(ids))
+	for _, id := range ids {
+		if err := authorizeUser(ctx, id); err != nil {
+			return nil, err
+		}
+		users = append(users, model.User{ID: id})
+	}
+	return users, nil
+}
+
+// Subscription is the resolver for the subscription field.
+func (r *queryResolver) Subscription(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	sub, err := r.repo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// чужая подписка неотличима от несуществующей, чтобы по ответу нельзя было перебирать id
+	if err := authorizeUser(ctx, sub.UserID); err != nil {
+		return nil, nil
+	}
+	return sub, nil
+}
+
+// User is the resolver for the user field.
+func (r *subscriptionResolver) User(ctx context.Context, obj *models.Subscription) (*model.User, error) {
+	return &model.User{ID: obj.UserID}, nil
+}
+
+// Subscriptions is the resolver for the subscriptions field.
+func (r *userResolver) Subscriptions(ctx context.Context, obj *model.User, period *model.Period, serviceName *string, limit *int, offset *int) ([]models.Subscription, error) {
+	start, end := periodBounds(period)
+
+	subs, err := loadersFromContext(ctx).subscriptions.Load(ctx, userKey{
+		userID: obj.ID,
+		filter: newFilter(serviceName, start, end, ""),
+	})()
+	if err != nil {
+		return nil, err
+	}
+	return page(subs, limit, offset), nil
+}
+
+// MonthlySpend is the resolver for the monthlySpend field.
+func (r *userResolver) MonthlySpend(ctx context.Context, obj *model.User, period *model.Period, serviceName *string, overlap *model.OverlapPolicy) ([]models.MonthlySpend, error) {
+	spend, err := loadSpend(ctx, obj.ID, period, serviceName, overlap)
+	if err != nil {
+		return nil, err
+	}
+
+	// строки упорядочены по месяцу, сервисы одного месяца суммируются
+	months := []models.MonthlySpend{}
+	for _, s := range spend {
+		if len(months) == 0 || !time.Time(months[len(months)-1].Month).Equal(time.Time(s.Month)) {
+			months = append(months, models.MonthlySpend{Month: s.Month})
+		}
+		m := &months[len(months)-1]
+		m.Gross += s.Gross
+		m.Discount += s.Discount
+		m.Net += s.Net
+	}
+	return months, nil
+}
+
+// SpendByService is the resolver for the spendByService field.
+func (r *userResolver) SpendByService(ctx context.Context, obj *model.User, period *model.Period, overlap *model.OverlapPolicy) ([]model.ServiceSpend, error) {
+	spend, err := loadSpend(ctx, obj.ID, period, nil, overlap)
+	if err != nil {
+		return nil, err
+	}
+
+	byService := make(map[string]*model.ServiceSpend)
+	for _, s := range spend {
+		total, ok := byService[s.ServiceName]
+		if !ok {
+			total = &model.ServiceSpend{ServiceName: s.ServiceName}
+			byService[s.ServiceName] = total
+		}
+		total.Gross += s.Gross
+		total.Discount += s.Discount
+		total.Net += s.Net
+	}
+
+	services := make([]model.ServiceSpend, 0, len(byService))
+	for _, total := range byService {
+		services = append(services, *total)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].ServiceName < services[j].ServiceName
+	})
+	return services, nil
+}
+
+// TotalSpend is the resolver for the totalSpend field.
+func (r *userResolver) TotalSpend(ctx context.Context, obj *model.User, period *model.Period, serviceName *string, overlap *model.OverlapPolicy) (int, error) {
+	spend, err := loadSpend(ctx, obj.ID, period, serviceName, overlap)
+	if err != nil {
+		return 0, err
+	}
+
+	sum := 0
+	for _, s := range spend {
+		sum += s.Net
+	}
+	return sum, nil
+}
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
+// User returns UserResolver implementation.
+func (r *Resolver) User() UserResolver { return &userResolver{r} }
+
+type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
@@ -0,0 +1,76 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	subscriptionsv1 "subscriptions-service/api/subscriptions/v1"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/repository"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// AuthFunc проверяет значение метаданных authorization и возвращает клиента вызова
+type AuthFunc func(ctx context.Context, authorization string) (auth.Principal, error)
+
+// Authenticate принимает те же JWT и API-ключи, что REST (auth.Middleware)
+func Authenticate(v *auth.Verifier, keys auth.APIKeyStore) AuthFunc {
+	return func(ctx context.Context, authorization string) (auth.Principal, error) {
+		return auth.Authenticate(ctx, v, keys, authorization)
+	}
+}
+
+// Disabled выполняет все вызовы с правами администратора, как auth.Disabled
+func Disabled() AuthFunc {
+	return func(context.Context, string) (auth.Principal, error) {
+		return auth.Principal{Admin: true}, nil
+	}
+}
+
+type Config struct {
+	Authenticate AuthFunc
+	// TenantHeader - заголовок арендатора REST, в метаданных gRPC - в нижнем регистре
+	TenantHeader string
+	// TrustTenantHeader разрешает субъекту без арендатора выбрать его метаданными,
+	// как trustHeader в tenant.Middleware
+	TrustTenantHeader bool
+	// RequestTimeout ограничивает вызов, как database.RequestTimeout запрос REST. 0 - без ограничения.
+	RequestTimeout time.Duration
+}
+
+// New создает gRPC-сервер с SubscriptionService поверх repo, сервисами health и reflection.
+// Цепочка перехватчиков повторяет middleware REST: трассировка, лог, таймаут,
+// аутентификация и арендатор. Возвращает также health.Server, чтобы при остановке
+// перевести сервис в NOT_SERVING.
+func New(cfg Config, repo repository.SubscriptionRepository) (*grpc.Server, *health.Server) {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			traceUnary(),
+			logUnary(),
+			timeoutUnary(cfg.RequestTimeout),
+			authUnary(cfg.Authenticate),
+			tenantUnary(cfg.TenantHeader, cfg.TrustTenantHeader),
+		),
+		grpc.ChainStreamInterceptor(
+			traceStream(),
+			logStream(),
+			timeoutStream(cfg.RequestTimeout),
+			authStream(cfg.Authenticate),
+			tenantStream(cfg.TenantHeader, cfg.TrustTenantHeader),
+		),
+	)
+
+	subscriptionsv1.RegisterSubscriptionServiceServer(srv, NewSubscriptionServer(repo))
+
+	healthSrv := health.NewServer()
+	healthSrv.SetServingStatus(subscriptionsv1.SubscriptionService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthSrv)
+
+	reflection.Register(srv)
+
+	return srv, healthSrv
+}
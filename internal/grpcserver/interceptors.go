@@ -0,0 +1,303 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	subscriptionsv1 "subscriptions-service/api/subscriptions/v1"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/tenant"
+	"subscriptions-service/internal/trace"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// servicePrefix - методы SubscriptionService. Health и reflection доступны без
+// аутентификации, как /livez и /readyz в REST.
+var servicePrefix = "/" + subscriptionsv1.SubscriptionService_ServiceDesc.ServiceName + "/"
+
+// methodScopes - scope, нужный для вызова метода, как auth.RequireScope на маршрутах REST
+var methodScopes = map[string]string{
+	subscriptionsv1.SubscriptionService_CreateSubscription_FullMethodName: auth.ScopeSubscriptionsWrite,
+	subscriptionsv1.SubscriptionService_GetSubscription_FullMethodName:    auth.ScopeSubscriptionsRead,
+	subscriptionsv1.SubscriptionService_UpdateSubscription_FullMethodName: auth.ScopeSubscriptionsWrite,
+	subscriptionsv1.SubscriptionService_DeleteSubscription_FullMethodName: auth.ScopeSubscriptionsWrite,
+	subscriptionsv1.SubscriptionService_ListSubscriptions_FullMethodName:  auth.ScopeSubscriptionsRead,
+	subscriptionsv1.SubscriptionService_SumSubscriptions_FullMethodName:   auth.ScopeSubscriptionsRead,
+}
+
+// serverStream подменяет контекст потока, который перехватчики дополняют по цепочке
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// metadataCarrier - propagation.TextMapCarrier поверх метаданных gRPC
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// startSpan продолжает трассировку из метаданных traceparent или начинает новую, как
+// trace.Middleware. Trace id возвращается клиенту в заголовках x-trace-id и traceparent.
+func startSpan(ctx context.Context, method string, setHeader func(metadata.MD) error) (context.Context, oteltrace.Span) {
+	propagator := otel.GetTextMapPropagator()
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = propagator.Extract(ctx, metadataCarrier(md.Copy()))
+
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	attrs := []attribute.KeyValue{
+		semconv.RPCSystemGRPC,
+		semconv.RPCService(service),
+		semconv.RPCMethod(name),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, semconv.ClientAddress(p.Addr.String()))
+	}
+	ctx, span := trace.Tracer().Start(ctx, strings.TrimPrefix(method, "/"),
+		oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+		oteltrace.WithAttributes(attrs...),
+	)
+
+	traceID := span.SpanContext().TraceID().String()
+	ctx = trace.WithTraceID(ctx, traceID)
+
+	header := metadata.Pairs("x-trace-id", traceID)
+	propagator.Inject(ctx, metadataCarrier(header))
+	if err := setHeader(header); err != nil {
+		logger.Log.Debug("Не удалось передать заголовки трассировки", "trace_id", traceID, "error", err)
+	}
+	return ctx, span
+}
+
+func endSpan(span oteltrace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	// ошибки сервера, как 5xx в REST; ошибки клиента статус спана не меняют
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal,
+		codes.Unavailable, codes.DataLoss:
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+	span.End()
+}
+
+func traceUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := startSpan(ctx, info.FullMethod, func(md metadata.MD) error {
+			return grpc.SetHeader(ctx, md)
+		})
+		resp, err := handler(ctx, req)
+		endSpan(span, err)
+		return resp, err
+	}
+}
+
+func traceStream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startSpan(ss.Context(), info.FullMethod, ss.SetHeader)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		endSpan(span, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	var clientAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		clientAddr = p.Addr.String()
+	}
+	logger.Log.Info("gRPC request",
+		"trace_id", trace.TraceIDFromContext(ctx),
+		"method", method,
+		"code", status.Code(err).String(),
+		"latency_ms", time.Since(start).Milliseconds(),
+		"client_ip", clientAddr,
+	)
+}
+
+func logUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func logStream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+func timeoutUnary(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+func timeoutStream(timeout time.Duration) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if timeout <= 0 {
+			return handler(srv, ss)
+		}
+		ctx, cancel := context.WithTimeout(ss.Context(), timeout)
+		defer cancel()
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate проверяет метаданные authorization и scope метода и кладет Principal в контекст
+func authenticate(ctx context.Context, authFn AuthFunc, method string) (context.Context, error) {
+	if !strings.HasPrefix(method, servicePrefix) {
+		return ctx, nil
+	}
+
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("authorization"); len(v) > 0 {
+			authorization = v[0]
+		}
+	}
+
+	p, err := authFn(ctx, authorization)
+	if err != nil {
+		traceID := trace.TraceIDFromContext(ctx)
+		switch {
+		case errors.Is(err, auth.ErrMissingCredentials):
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		case errors.Is(err, auth.ErrInvalidAPIKey):
+			logger.Log.Warn("Невалидный API-ключ", "trace_id", traceID, "error", err)
+			return nil, status.Error(codes.Unauthenticated, "invalid api key")
+		case errors.Is(err, auth.ErrInvalidToken):
+			logger.Log.Warn("Невалидный JWT", "trace_id", traceID, "error", err)
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		default:
+			return nil, repositoryError(ctx, err)
+		}
+	}
+
+	if scope, ok := methodScopes[method]; ok && !p.HasScope(scope) {
+		return nil, status.Error(codes.PermissionDenied, "scope "+scope+" required")
+	}
+	return auth.WithPrincipal(ctx, p), nil
+}
+
+func authUnary(authFn AuthFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, authFn, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func authStream(authFn AuthFunc) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), authFn, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// resolveTenant определяет арендатора вызова по правилам tenant.Middleware:
+// арендатор из токена имеет приоритет, метаданные с другим значением отклоняются,
+// субъекту без арендатора метаданные доверяются только при trustHeader
+func resolveTenant(ctx context.Context, header string, trustHeader bool, method string) (context.Context, error) {
+	if !strings.HasPrefix(method, servicePrefix) {
+		return ctx, nil
+	}
+
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(header); len(v) > 0 {
+			id = v[0]
+		}
+	}
+	if id != "" && !tenant.Valid(id) {
+		return nil, status.Error(codes.InvalidArgument, "invalid tenant")
+	}
+
+	p, ok := auth.PrincipalFromContext(ctx)
+	switch {
+	case ok && p.TenantID != "":
+		if !tenant.Valid(p.TenantID) {
+			return nil, status.Error(codes.PermissionDenied, "invalid tenant")
+		}
+		if id != "" && id != p.TenantID {
+			logger.Log.Warn("Арендатор в заголовке не совпадает с токеном", "trace_id", trace.TraceIDFromContext(ctx),
+				"header", id, "token", p.TenantID)
+			return nil, status.Error(codes.PermissionDenied, "tenant mismatch")
+		}
+		id = p.TenantID
+	case !trustHeader:
+		return nil, status.Error(codes.PermissionDenied, "principal has no tenant")
+	case id == "":
+		id = tenant.Default
+	}
+	return tenant.WithID(ctx, id), nil
+}
+
+func tenantUnary(header string, trustHeader bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := resolveTenant(ctx, header, trustHeader, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func tenantStream(header string, trustHeader bool) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := resolveTenant(ss.Context(), header, trustHeader, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
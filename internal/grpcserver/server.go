@@ -0,0 +1,350 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	subscriptionsv1 "subscriptions-service/api/subscriptions/v1"
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/metrics"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/trace"
+	"subscriptions-service/internal/validation"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// listPageSize - сколько подписок ListSubscriptions читает из репозитория за раз
+const listPageSize = 100
+
+// SubscriptionServer реализует SubscriptionService с той же логикой, что handlers.Handler
+type SubscriptionServer struct {
+	subscriptionsv1.UnimplementedSubscriptionServiceServer
+	repo repository.SubscriptionRepository
+}
+
+func NewSubscriptionServer(repo repository.SubscriptionRepository) *SubscriptionServer {
+	return &SubscriptionServer{repo: repo}
+}
+
+func (s *SubscriptionServer) CreateSubscription(ctx context.Context, req *subscriptionsv1.CreateSubscriptionRequest) (*subscriptionsv1.Subscription, error) {
+	if req.GetSubscription() == nil {
+		return nil, status.Error(codes.InvalidArgument, "subscription is required")
+	}
+	sub, errs := fromProto(req.GetSubscription())
+	if len(errs) > 0 {
+		return nil, invalidArgument(errs)
+	}
+	sub.ID = uuid.Nil
+
+	// без user_id подписка создается для самого клиента
+	if p, ok := auth.PrincipalFromContext(ctx); ok && sub.UserID == uuid.Nil && !p.Admin {
+		sub.UserID = p.UserID
+	}
+	if errs := validation.Subscription(*sub); len(errs) > 0 {
+		return nil, invalidArgument(errs)
+	}
+	if err := authorizeUser(ctx, sub.UserID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, sub); err != nil {
+		return nil, repositoryError(ctx, err)
+	}
+
+	metrics.SubscriptionCreated(sub.ServiceName)
+	logger.Log.Info("Подписка создана", "trace_id", trace.TraceIDFromContext(ctx), "subscription", sub)
+
+	return toProto(sub), nil
+}
+
+func (s *SubscriptionServer) fetchSubscription(ctx context.Context, rawID string) (*models.Subscription, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid id")
+	}
+	sub, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, repositoryError(ctx, err)
+	}
+	if err := authorizeUser(ctx, sub.UserID); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *SubscriptionServer) GetSubscription(ctx context.Context, req *subscriptionsv1.GetSubscriptionRequest) (*subscriptionsv1.Subscription, error) {
+	sub, err := s.fetchSubscription(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return toProto(sub), nil
+}
+
+func (s *SubscriptionServer) UpdateSubscription(ctx context.Context, req *subscriptionsv1.UpdateSubscriptionRequest) (*subscriptionsv1.Subscription, error) {
+	if req.GetSubscription() == nil {
+		return nil, status.Error(codes.InvalidArgument, "subscription is required")
+	}
+	sub, errs := fromProto(req.GetSubscription())
+	if len(errs) > 0 {
+		return nil, invalidArgument(errs)
+	}
+
+	existing, err := s.fetchSubscription(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if errs := validation.SubscriptionUpdate(*existing, *sub); len(errs) > 0 {
+		return nil, invalidArgument(errs)
+	}
+	if sub.UserID != uuid.Nil {
+		if err := authorizeUser(ctx, sub.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Update(ctx, existing.ID, sub); err != nil {
+		return nil, repositoryError(ctx, err)
+	}
+
+	updated, err := s.fetchSubscription(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Info("Подписка обновлена", "trace_id", trace.TraceIDFromContext(ctx), "subscription", updated)
+
+	return toProto(updated), nil
+}
+
+func (s *SubscriptionServer) DeleteSubscription(ctx context.Context, req *subscriptionsv1.DeleteSubscriptionRequest) (*subscriptionsv1.DeleteSubscriptionResponse, error) {
+	sub, err := s.fetchSubscription(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Delete(ctx, sub.ID); err != nil {
+		return nil, repositoryError(ctx, err)
+	}
+	logger.Log.Info("Подписка удалена", "trace_id", trace.TraceIDFromContext(ctx), "subscription", sub)
+
+	return &subscriptionsv1.DeleteSubscriptionResponse{}, nil
+}
+
+// ListSubscriptions отдает подписки в порядке ListByUser, читая их страницами по listPageSize
+func (s *SubscriptionServer) ListSubscriptions(req *subscriptionsv1.ListSubscriptionsRequest, stream subscriptionsv1.SubscriptionService_ListSubscriptionsServer) error {
+	ctx := stream.Context()
+	q, err := parseQuery(ctx, req.GetUserId(), req.ServiceName, req.GetStartDate(), req.GetEndDate())
+	if err != nil {
+		return err
+	}
+
+	for offset := 0; ; offset += listPageSize {
+		subs, err := s.repo.ListByUser(ctx, q.userID, q.serviceName, q.start, q.end, listPageSize, offset)
+		if err != nil {
+			return repositoryError(ctx, err)
+		}
+		for i := range subs {
+			if err := stream.Send(toProto(&subs[i])); err != nil {
+				return err
+			}
+		}
+		if len(subs) < listPageSize {
+			return nil
+		}
+	}
+}
+
+func (s *SubscriptionServer) SumSubscriptions(ctx context.Context, req *subscriptionsv1.SumSubscriptionsRequest) (*subscriptionsv1.SumSubscriptionsResponse, error) {
+	q, err := parseQuery(ctx, req.GetUserId(), req.ServiceName, req.GetStartDate(), req.GetEndDate())
+	if err != nil {
+		return nil, err
+	}
+	overlap, err := repository.ParseOverlapPolicy(req.GetOverlap())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	sum, err := s.repo.SumByUserAndService(ctx, q.userID, q.serviceName, q.start, q.end, overlap)
+	if err != nil {
+		return nil, repositoryError(ctx, err)
+	}
+	collapsed, err := s.repo.CollapsedOverlaps(ctx, q.userID, q.serviceName, q.start, q.end, overlap)
+	if err != nil {
+		return nil, repositoryError(ctx, err)
+	}
+
+	resp := &subscriptionsv1.SumSubscriptionsResponse{
+		Sum:       int64(sum),
+		Overlap:   string(overlap),
+		Collapsed: make([]*subscriptionsv1.CollapsedOverlap, 0, len(collapsed)),
+	}
+	for _, c := range collapsed {
+		resp.Collapsed = append(resp.Collapsed, &subscriptionsv1.CollapsedOverlap{
+			ServiceName: c.ServiceName,
+			KeptId:      c.KeptID.String(),
+			CollapsedId: c.CollapsedID.String(),
+			StartMonth:  yearMonth(time.Time(c.StartMonth)),
+			EndMonth:    yearMonth(time.Time(c.EndMonth)),
+			Months:      int32(c.Months),
+		})
+	}
+	return resp, nil
+}
+
+// query - параметры List и Sum, аналог handlers.QueryParams
+type query struct {
+	userID      uuid.UUID
+	serviceName *string
+	start, end  *time.Time
+}
+
+func parseQuery(ctx context.Context, rawUserID string, serviceName *string, start, end *subscriptionsv1.YearMonth) (*query, error) {
+	userID, err := uuid.Parse(rawUserID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid user_id")
+	}
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	q := &query{userID: userID}
+	if serviceName != nil && *serviceName != "" {
+		q.serviceName = serviceName
+	}
+	if start != nil {
+		t, err := parseYearMonth(start, "start_date")
+		if err != nil {
+			return nil, err
+		}
+		q.start = &t
+	}
+	if end != nil {
+		t, err := parseYearMonth(end, "end_date")
+		if err != nil {
+			return nil, err
+		}
+		q.end = &t
+	}
+	return q, nil
+}
+
+// authorizeUser проверяет, что клиент вызова может работать с данными пользователя userID
+func authorizeUser(ctx context.Context, userID uuid.UUID) error {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, auth.ErrNoPrincipal.Error())
+	}
+	if !p.CanAccess(userID) {
+		return status.Error(codes.PermissionDenied, "access denied")
+	}
+	return nil
+}
+
+// repositoryError переводит ошибку репозитория в статус gRPC по правилам apierror.From.
+// Текст внутренних ошибок не уходит клиенту: он пишется в лог с trace_id вызова.
+func repositoryError(ctx context.Context, err error) error {
+	e := apierror.From(err)
+	switch e.Code {
+	case apierror.CodeNotFound:
+		return status.Error(codes.NotFound, "subscription not found")
+	case apierror.CodeTimeout:
+		return status.Error(codes.DeadlineExceeded, e.Detail)
+	case apierror.CodeRequestCanceled:
+		return status.Error(codes.Canceled, e.Detail)
+	default:
+		logger.Log.Error("Ошибка обработки вызова", "trace_id", trace.TraceIDFromContext(ctx), "error", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func toProto(sub *models.Subscription) *subscriptionsv1.Subscription {
+	pb := &subscriptionsv1.Subscription{
+		Id:          sub.ID.String(),
+		ServiceName: sub.ServiceName,
+		Price:       int64(sub.Price),
+		UserId:      sub.UserID.String(),
+		StartDate:   yearMonth(time.Time(sub.StartDate)),
+	}
+	if sub.EndDate != nil {
+		pb.EndDate = yearMonth(time.Time(*sub.EndDate))
+	}
+	return pb
+}
+
+// fromProto переводит подписку из запроса в модель; пустые поля остаются нулевыми,
+// чтобы Update их не меняли. Поля, которые не удалось разобрать, возвращаются списком.
+func fromProto(pb *subscriptionsv1.Subscription) (*models.Subscription, []apierror.FieldError) {
+	sub := &models.Subscription{
+		ServiceName: pb.GetServiceName(),
+		Price:       int(pb.GetPrice()),
+	}
+	var errs []apierror.FieldError
+	if pb.GetPrice() < 0 {
+		errs = append(errs, validation.Field("price", "must be greater than 0"))
+	}
+	if pb.GetId() != "" {
+		id, err := uuid.Parse(pb.GetId())
+		if err != nil {
+			errs = append(errs, validation.Field("id", "must be a UUID"))
+		}
+		sub.ID = id
+	}
+	if pb.GetUserId() != "" {
+		userID, err := uuid.Parse(pb.GetUserId())
+		switch {
+		case err != nil:
+			errs = append(errs, validation.Field("user_id", "must be a UUID"))
+		case userID == uuid.Nil:
+			errs = append(errs, validation.Field("user_id", "must not be the nil UUID"))
+		}
+		sub.UserID = userID
+	}
+	if pb.GetStartDate() != nil {
+		start, err := parseYearMonth(pb.GetStartDate(), "start_date")
+		if err != nil {
+			errs = append(errs, validation.Field("start_date", "must be a valid year and month"))
+		}
+		sub.StartDate = models.MonthYearDate(start)
+	}
+	if pb.GetEndDate() != nil {
+		end, err := parseYearMonth(pb.GetEndDate(), "end_date")
+		if err != nil {
+			errs = append(errs, validation.Field("end_date", "must be a valid year and month"))
+		}
+		endDate := models.MonthYearDate(end)
+		sub.EndDate = &endDate
+	}
+	return sub, errs
+}
+
+// invalidArgument возвращает InvalidArgument с нарушениями в деталях BadRequest,
+// как поле errors в problem+json REST
+func invalidArgument(fields []apierror.FieldError) error {
+	st := status.New(codes.InvalidArgument, "request validation failed")
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(fields))
+	for i, fe := range fields {
+		violations[i] = &errdetails.BadRequest_FieldViolation{Field: fe.Field, Description: fe.Message}
+	}
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// yearMonth - первое число месяца t, как MM-YYYY в REST
+func yearMonth(t time.Time) *subscriptionsv1.YearMonth {
+	return &subscriptionsv1.YearMonth{Year: int32(t.Year()), Month: int32(t.Month())}
+}
+
+func parseYearMonth(ym *subscriptionsv1.YearMonth, field string) (time.Time, error) {
+	if ym.GetYear() < 1 || ym.GetYear() > 9999 || ym.GetMonth() < 1 || ym.GetMonth() > 12 {
+		return time.Time{}, status.Error(codes.InvalidArgument, "invalid "+field)
+	}
+	return time.Date(int(ym.GetYear()), time.Month(ym.GetMonth()), 1, 0, 0, 0, 0, time.UTC), nil
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// authorizeUser проверяет, что клиент запроса может работать с данными пользователя userID
+func authorizeUser(c *gin.Context, userID uuid.UUID) error {
+	p, ok := auth.PrincipalFromContext(c.Request.Context())
+	if !ok {
+		return apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, auth.ErrNoPrincipal.Error())
+	}
+	if !p.CanAccess(userID) {
+		return apierror.New(http.StatusForbidden, apierror.CodeForbidden, "access denied")
+	}
+	return nil
+}
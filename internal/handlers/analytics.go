@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"net/http"
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AnalyticsHandler struct {
+	repo repository.AnalyticsRepository
+}
+
+func NewAnalyticsHandler(repo repository.AnalyticsRepository) *AnalyticsHandler {
+	return &AnalyticsHandler{repo: repo}
+}
+
+func (h *AnalyticsHandler) RegisterRoutes(r gin.IRouter) {
+	r = r.Group("", apierror.Middleware())
+	r.GET("/analytics/popular-services", h.GetPopularServices)
+	r.GET("/analytics/average-spend", h.GetAverageSpend)
+	r.GET("/analytics/churn", h.GetChurn)
+	r.GET("/analytics/price-distribution", h.GetPriceDistribution)
+}
+
+// GetPopularServices godoc
+// @Summary Самые популярные сервисы
+// @ID getPopularServices
+// @Description Сервисы с наибольшим числом пользователей, у которых была подписка в периоде
+// @Tags analytics
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param start_date query string false "Начало периода (MM-YYYY)" format(month)
+// @Param end_date query string false "Конец периода (MM-YYYY)" format(month)
+// @Param limit query int false "Количество сервисов, от 1 до 100" default(10)
+// @Success 200 {array} models.ServicePopularity
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
+// @Router /admin/analytics/popular-services [get]
+func (h *AnalyticsHandler) GetPopularServices(c *gin.Context) {
+	start, end, err := parsePeriod(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var params struct {
+		Limit int `form:"limit,default=10"`
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.Error(apierror.InvalidField("limit", "must be an integer"))
+		return
+	}
+	if params.Limit < 1 || params.Limit > 100 {
+		params.Limit = 10
+	}
+
+	ctx := c.Request.Context()
+	services, err := h.repo.PopularServices(ctx, start, end, params.Limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, services)
+}
+
+type AverageSpendResponse struct {
+	models.SpendAverage
+	Months []models.MonthlySpendAverage `json:"months"`
+}
+
+// GetAverageSpend godoc
+// @Summary Средние расходы на пользователя
+// @ID getAverageSpend
+// @Description Суммарные и средние расходы пользователей на подписки за период и по месяцам
+// @Tags analytics
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param start_date query string false "Начало периода (MM-YYYY)" format(month)
+// @Param end_date query string false "Конец периода (MM-YYYY)" format(month)
+// @Success 200 {object} AverageSpendResponse
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
+// @Router /admin/analytics/average-spend [get]
+func (h *AnalyticsHandler) GetAverageSpend(c *gin.Context) {
+	start, end, err := parsePeriod(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	total, months, err := h.repo.AverageSpend(ctx, start, end)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, AverageSpendResponse{SpendAverage: *total, Months: months})
+}
+
+// GetChurn godoc
+// @Summary Отток подписок по месяцам
+// @ID getChurn
+// @Description Число начавшихся, действующих и закончившихся подписок по месяцам периода
+// @Tags analytics
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param start_date query string false "Начало периода (MM-YYYY)" format(month)
+// @Param end_date query string false "Конец периода (MM-YYYY)" format(month)
+// @Success 200 {array} models.MonthlyChurn
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
+// @Router /admin/analytics/churn [get]
+func (h *AnalyticsHandler) GetChurn(c *gin.Context) {
+	start, end, err := parsePeriod(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	months, err := h.repo.ChurnByMonth(ctx, start, end)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, months)
+}
+
+// GetPriceDistribution godoc
+// @Summary Распределение цен по сервисам
+// @ID getPriceDistribution
+// @Description Минимальная, максимальная, средняя цена и квартили цен подписок каждого сервиса
+// @Tags analytics
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param service_name query string false "Название сервиса"
+// @Param start_date query string false "Начало периода (MM-YYYY)" format(month)
+// @Param end_date query string false "Конец периода (MM-YYYY)" format(month)
+// @Success 200 {array} models.PriceDistribution
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
+// @Router /admin/analytics/price-distribution [get]
+func (h *AnalyticsHandler) GetPriceDistribution(c *gin.Context) {
+	start, end, err := parsePeriod(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var serviceName *string
+	if v := c.Query("service_name"); v != "" {
+		serviceName = &v
+	}
+
+	ctx := c.Request.Context()
+	services, err := h.repo.PriceDistribution(ctx, serviceName, start, end)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, services)
+}
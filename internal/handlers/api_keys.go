@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type APIKeyHandler struct {
+	repo repository.APIKeyRepository
+}
+
+func NewAPIKeyHandler(repo repository.APIKeyRepository) *APIKeyHandler {
+	return &APIKeyHandler{repo: repo}
+}
+
+func (h *APIKeyHandler) RegisterRoutes(r gin.IRouter) {
+	r = r.Group("", apierror.Middleware())
+	r.POST("/api-keys", h.CreateAPIKey)
+	r.GET("/api-keys", h.GetAPIKeyList)
+	r.DELETE("/api-keys/:id", h.RevokeAPIKey)
+}
+
+// CreateAPIKey godoc
+// @Summary Создать API-ключ
+// @ID createAPIKey
+// @Description Создает ключ для сервисных клиентов. Ключ возвращается один раз, в БД хранится только его хэш.
+// @Description Используется в заголовке Authorization: ApiKey <key>.
+// @Tags api-keys
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param api_key body apiv1.CreateAPIKeyRequest true "API key data"
+// @Success 201 {object} apiv1.CreateAPIKeyResponse
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
+// @Router /admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req apiv1.CreateAPIKeyRequest
+	if err := validation.DecodeJSON(c.Request.Body, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := validation.Struct(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	apiKey := req.Model(prefix, hash)
+
+	ctx := c.Request.Context()
+	if err := h.repo.CreateAPIKey(ctx, &apiKey); err != nil {
+		c.Error(err)
+		return
+	}
+
+	traceID, _ := c.Get("trace_id")
+	logger.Log.Info("API-ключ создан", "trace_id", traceID, "api_key", apiKey)
+
+	c.JSON(http.StatusCreated, apiv1.CreateAPIKeyResponse{APIKey: apiv1.NewAPIKey(apiKey), Key: key})
+}
+
+// GetAPIKeyList godoc
+// @Summary Получить список API-ключей
+// @ID listAPIKeys
+// @Description Все ключи, включая отозванные, со временем и числом использований
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} apiv1.APIKey
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
+// @Router /admin/api-keys [get]
+func (h *APIKeyHandler) GetAPIKeyList(c *gin.Context) {
+	ctx := c.Request.Context()
+	keys, err := h.repo.ListAPIKeys(ctx)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiv1.NewAPIKeys(keys))
+}
+
+// RevokeAPIKey godoc
+// @Summary Отозвать API-ключ
+// @ID revokeAPIKey
+// @Description Отозванный ключ больше не принимается
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "UUID ключа" format(uuid)
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 404 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
+// @Router /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.InvalidField("id", "must be a UUID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.repo.RevokeAPIKey(ctx, id); err != nil {
+		c.Error(apierror.NotFound(err, apierror.CodeAPIKeyNotFound, "api key not found"))
+		return
+	}
+
+	traceID, _ := c.Get("trace_id")
+	logger.Log.Info("API-ключ отозван", "trace_id", traceID, "api_key_id", id)
+
+	c.JSON(http.StatusOK, gin.H{"message": "api key revoked"})
+}
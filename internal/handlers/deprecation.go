@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated помечает ответы устаревших маршрутов заголовком Deprecation (RFC 9745)
+// со временем, с которого маршрут устарел, и ссылкой на тот же путь в версии successor
+func Deprecated(since time.Time, successor string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", since.Unix())
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", successor, c.Request.URL.Path))
+		c.Next()
+	}
+}
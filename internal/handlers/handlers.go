@@ -1,18 +1,20 @@
 package handlers
 
 import (
-	"errors"
-	"fmt"
 	"net/http"
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/metrics"
 	"subscriptions-service/internal/models"
 	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/validation"
 	"time"
 
 	"subscriptions-service/internal/logger"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 type Handler struct {
@@ -23,76 +25,115 @@ func NewHandler(repo repository.SubscriptionRepository) *Handler {
 	return &Handler{repo: repo}
 }
 
-func (h *Handler) RegisterRoutes(r *gin.Engine) {
-	r.POST("/subscriptions", h.CreateSubscription)
-	r.GET("/subscriptions/:id", h.GetSubscription)
-	r.PUT("/subscriptions/:id", h.UpdateSubscription)
-	r.DELETE("/subscriptions/:id", h.DeleteSubscription)
-	r.GET("/subscriptions/list", h.GetSubscriptionList)
-	r.GET("/subscriptions/sum", h.GetSubscriptionSum)
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	r = r.Group("", apierror.Middleware())
+	read := auth.RequireScope(auth.ScopeSubscriptionsRead)
+	write := auth.RequireScope(auth.ScopeSubscriptionsWrite)
+
+	r.POST("/subscriptions", write, h.CreateSubscription)
+	r.GET("/subscriptions/:id", read, h.GetSubscription)
+	r.PUT("/subscriptions/:id", write, h.UpdateSubscription)
+	r.DELETE("/subscriptions/:id", write, h.DeleteSubscription)
+	r.GET("/subscriptions/list", read, h.GetSubscriptionList)
+	r.GET("/subscriptions/sum", read, h.GetSubscriptionSum)
+	r.GET("/subscriptions/sum/monthly", read, h.GetSubscriptionMonthlySum)
+
+	r.POST("/subscriptions/:id/discounts", write, h.CreateDiscount)
+	r.GET("/subscriptions/:id/discounts", read, h.GetDiscountList)
+	r.DELETE("/subscriptions/:id/discounts/:discount_id", write, h.DeleteDiscount)
+
+	r.GET("/users/:user_id/subscriptions/conflicts", read, h.GetSubscriptionConflicts)
 }
 
 // CreateSubscription godoc
 // @Summary Создать подписку
+// @ID createSubscription
 // @Description Создает новую запись подписки
 // @Tags subscriptions
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
-// @Param subscription body models.Subscription true "Subscription data"
-// @Success 201 {object} models.Subscription
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Param subscription body apiv1.CreateSubscriptionRequest true "Subscription data"
+// @Success 201 {object} apiv1.Subscription
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
 // @Router /subscriptions [post]
 func (h *Handler) CreateSubscription(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	var sub models.Subscription
-	if err := c.ShouldBindJSON(&sub); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req apiv1.CreateSubscriptionRequest
+	if err := validation.DecodeJSON(c.Request.Body, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// без user_id подписка создается для самого клиента. У API-ключа своего
+	// пользователя нет, и user_id для него остается обязательным.
+	if p, ok := auth.PrincipalFromContext(ctx); ok && req.UserID == "" && !p.Admin && p.UserID != uuid.Nil {
+		req.UserID = p.UserID.String()
+	}
+	if err := validation.Struct(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	sub := req.Model()
+	if err := authorizeUser(c, sub.UserID); err != nil {
+		c.Error(err)
 		return
 	}
 
 	if err := h.repo.Create(ctx, &sub); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	metrics.SubscriptionCreated(sub.ServiceName)
+
 	traceID, _ := c.Get("trace_id")
 	logger.Log.Info("Подписка создана", "trace_id", traceID, "subscription", sub)
 
-	c.JSON(http.StatusCreated, sub)
+	c.JSON(http.StatusCreated, apiv1.NewSubscription(sub))
 }
 
 func (h *Handler) fetchSubscription(c *gin.Context, id uuid.UUID) (*models.Subscription, bool) {
 	ctx := c.Request.Context()
 	sub, err := h.repo.Get(ctx, id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		c.Error(apierror.NotFound(err, apierror.CodeSubscriptionNotFound, "subscription not found"))
 		return nil, false // false означает, что объект не получен
 	}
+	if err := authorizeUser(c, sub.UserID); err != nil {
+		c.Error(err)
+		return nil, false
+	}
 	return sub, true
 }
 
 // GetSubscription godoc
 // @Summary Получить подписку по ID
+// @ID getSubscription
 // @Description Получаем подписку по уникальному ID
 // @Tags subscriptions
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
-// @Param id path string true "UUID подписки"
-// @Success 200 {object} models.Subscription
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Param id path string true "UUID подписки" format(uuid)
+// @Success 200 {object} apiv1.Subscription
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 404 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
 // @Router /subscriptions/{id} [get]
 func (h *Handler) GetSubscription(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		c.Error(apierror.InvalidField("id", "must be a UUID"))
 		return
 	}
 
@@ -101,41 +142,63 @@ func (h *Handler) GetSubscription(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, sub)
+	c.JSON(http.StatusOK, apiv1.NewSubscription(*sub))
 }
 
 // UpdateSubscription godoc
 // @Summary Обновить подписку
+// @ID updateSubscription
 // @Description Обновляет данные подписки по ID
 // @Tags subscriptions
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
-// @Param id path string true "UUID подписки"
-// @Param subscription body models.Subscription true "Updated subscription"
-// @Success 200 {object} models.Subscription
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Param id path string true "UUID подписки" format(uuid)
+// @Param subscription body apiv1.UpdateSubscriptionRequest true "Updated subscription"
+// @Success 200 {object} apiv1.Subscription
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 404 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
 // @Router /subscriptions/{id} [put]
 func (h *Handler) UpdateSubscription(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		c.Error(apierror.InvalidField("id", "must be a UUID"))
 		return
 	}
 
-	var sub models.Subscription
-	if err := c.ShouldBindJSON(&sub); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req apiv1.UpdateSubscriptionRequest
+	if err := validation.DecodeJSON(c.Request.Body, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := validation.Struct(&req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	ctx := c.Request.Context()
-	if err := h.repo.Update(ctx, id, &sub); err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+	existing, ok := h.fetchSubscription(c, id)
+	if !ok {
+		return
+	}
+	sub := req.Model()
+	if errs := validation.SubscriptionUpdate(*existing, sub); len(errs) > 0 {
+		c.Error(apierror.Validation("request validation failed", errs...))
+		return
+	}
+	if sub.UserID != uuid.Nil {
+		if err := authorizeUser(c, sub.UserID); err != nil {
+			c.Error(err)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+
+	ctx := c.Request.Context()
+	if err := h.repo.Update(ctx, id, &sub); err != nil {
+		c.Error(apierror.NotFound(err, apierror.CodeSubscriptionNotFound, "subscription not found"))
 		return
 	}
 
@@ -146,24 +209,30 @@ func (h *Handler) UpdateSubscription(c *gin.Context) {
 	traceID, _ := c.Get("trace_id")
 	logger.Log.Info("Подписка обновлена", "trace_id", traceID, "subscription", sub_updated)
 
-	c.JSON(http.StatusOK, sub_updated)
+	c.JSON(http.StatusOK, apiv1.NewSubscription(*sub_updated))
 }
 
 // DeleteSubscription godoc
 // @Summary Удалить подписку
+// @ID deleteSubscription
 // @Description Удаляет подписку по ID
 // @Tags subscriptions
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
-// @Param id path string true "UUID подписки"
+// @Param id path string true "UUID подписки" format(uuid)
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 404 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
 // @Router /subscriptions/{id} [delete]
 func (h *Handler) DeleteSubscription(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		c.Error(apierror.InvalidField("id", "must be a UUID"))
 		return
 	}
 
@@ -174,7 +243,7 @@ func (h *Handler) DeleteSubscription(c *gin.Context) {
 
 	ctx := c.Request.Context()
 	if err := h.repo.Delete(ctx, id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -194,14 +263,37 @@ type QueryParams struct {
 func parseQueryParams(c *gin.Context) (*QueryParams, error) {
 	userID, err := uuid.Parse(c.Query("user_id"))
 	if err != nil {
-		return nil, fmt.Errorf("invalid user_id")
+		return nil, apierror.InvalidField("user_id", "must be a UUID")
+	}
+	if err := authorizeUser(c, userID); err != nil {
+		return nil, err
 	}
 
+	startDate, endDate, err := parsePeriod(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var serviceName *string
+	if v := c.Query("service_name"); v != "" {
+		serviceName = &v
+	}
+
+	return &QueryParams{
+		UserID:      userID,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		ServiceName: serviceName,
+	}, nil
+}
+
+// parsePeriod разбирает необязательные start_date и end_date в формате MM-YYYY
+func parsePeriod(c *gin.Context) (*time.Time, *time.Time, error) {
 	var startDate *time.Time
 	if v := c.Query("start_date"); v != "" {
 		t, err := time.Parse("01-2006", v)
 		if err != nil {
-			return nil, fmt.Errorf("invalid start_date")
+			return nil, nil, apierror.InvalidField("start_date", "must be in MM-YYYY format")
 		}
 		startDate = &t
 	}
@@ -210,44 +302,48 @@ func parseQueryParams(c *gin.Context) (*QueryParams, error) {
 	if v := c.Query("end_date"); v != "" {
 		t, err := time.Parse("01-2006", v)
 		if err != nil {
-			return nil, fmt.Errorf("invalid end_date")
+			return nil, nil, apierror.InvalidField("end_date", "must be in MM-YYYY format")
 		}
 		endDate = &t
 	}
 
-	var serviceName *string
-	if v := c.Query("service_name"); v != "" {
-		serviceName = &v
-	}
+	return startDate, endDate, nil
+}
 
-	return &QueryParams{
-		UserID:      userID,
-		StartDate:   startDate,
-		EndDate:     endDate,
-		ServiceName: serviceName,
-	}, nil
+// parseOverlap разбирает необязательный параметр overlap
+func parseOverlap(c *gin.Context) (repository.OverlapPolicy, error) {
+	overlap, err := repository.ParseOverlapPolicy(c.Query("overlap"))
+	if err != nil {
+		return "", apierror.InvalidField("overlap", "must be one of max, sum, latest")
+	}
+	return overlap, nil
 }
 
 // GetSubscriptionList godoc
 // @Summary Получить список подписок пользователя
+// @ID listSubscriptions
 // @Description Список подписок пользователя за период с фильтрацией по сервису
 // @Tags subscriptions
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
-// @Param user_id query string true "UUID пользователя"
+// @Param user_id query string true "UUID пользователя" format(uuid)
 // @Param service_name query string false "Название сервиса"
-// @Param start_date query string false "Начало периода (MM-YYYY)"
-// @Param end_date query string false "Конец периода (MM-YYYY)"
-// @Param limit query int false "Количество элементов на странице (по умолчанию 10)"
-// @Param offset query int false "Смещение (по умолчанию 0)"
-// @Success 200 {array} models.Subscription
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Param start_date query string false "Начало периода (MM-YYYY)" format(month)
+// @Param end_date query string false "Конец периода (MM-YYYY)" format(month)
+// @Param limit query int false "Количество элементов на странице, от 1 до 100" default(10)
+// @Param offset query int false "Смещение" default(0)
+// @Success 200 {array} apiv1.Subscription
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
 // @Router /subscriptions/list [get]
 func (h *Handler) GetSubscriptionList(c *gin.Context) {
 	params, err := parseQueryParams(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -257,7 +353,7 @@ func (h *Handler) GetSubscriptionList(c *gin.Context) {
 	}
 	var pages_params PagesParams
 	if err := c.ShouldBindQuery(&pages_params); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apierror.Validation("limit and offset must be integers"))
 		return
 	}
 	if pages_params.Limit < 1 || pages_params.Limit > 100 {
@@ -270,40 +366,287 @@ func (h *Handler) GetSubscriptionList(c *gin.Context) {
 	ctx := c.Request.Context()
 	subs, err := h.repo.ListByUser(ctx, params.UserID, params.ServiceName, params.StartDate, params.EndDate, pages_params.Limit, pages_params.Offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, subs)
+	c.JSON(http.StatusOK, apiv1.NewSubscriptions(subs))
 }
 
 // GetSubscriptionSum godoc
 // @Summary Получить сумму подписок
-// @Description Считает общую стоимость подписок пользователя по сервису за период
+// @ID getSubscriptionSum
+// @Description Считает общую стоимость подписок пользователя по сервису за период.
+// @Description Пересекающиеся записи одного сервиса учитываются согласно overlap, схлопнутые записи перечисляются в collapsed.
 // @Tags subscriptions
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
-// @Param user_id query string true "UUID пользователя"
+// @Param user_id query string true "UUID пользователя" format(uuid)
 // @Param service_name query string false "Название сервиса"
-// @Param start_date query string false "Начало периода (MM-YYYY)"
-// @Param end_date query string false "Конец периода (MM-YYYY)"
-// @Success 200 {object} map[string]int
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Param start_date query string false "Начало периода (MM-YYYY)" format(month)
+// @Param end_date query string false "Конец периода (MM-YYYY)" format(month)
+// @Param overlap query string false "Политика пересечений: max (по умолчанию), sum, latest" Enums(max, sum, latest) default(max)
+// @Success 200 {object} apiv1.SumResponse
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
 // @Router /subscriptions/sum [get]
 func (h *Handler) GetSubscriptionSum(c *gin.Context) {
 	params, err := parseQueryParams(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
+		return
+	}
+	overlap, err := parseOverlap(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	sum, err := h.repo.SumByUserAndService(ctx, params.UserID, params.ServiceName, params.StartDate, params.EndDate, overlap)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	collapsed, err := h.repo.CollapsedOverlaps(ctx, params.UserID, params.ServiceName, params.StartDate, params.EndDate, overlap)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiv1.SumResponse{
+		Sum:       sum,
+		Overlap:   string(overlap),
+		Collapsed: apiv1.NewCollapsedOverlaps(collapsed),
+	})
+}
+
+// GetSubscriptionMonthlySum godoc
+// @Summary Получить помесячную разбивку суммы подписок
+// @ID getSubscriptionMonthlySum
+// @Description Для каждого месяца периода возвращает сумму до скидок, размер скидки и итоговую сумму
+// @Tags subscriptions
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param user_id query string true "UUID пользователя" format(uuid)
+// @Param service_name query string false "Название сервиса"
+// @Param start_date query string false "Начало периода (MM-YYYY)" format(month)
+// @Param end_date query string false "Конец периода (MM-YYYY)" format(month)
+// @Param overlap query string false "Политика пересечений: max (по умолчанию), sum, latest" Enums(max, sum, latest) default(max)
+// @Success 200 {object} apiv1.MonthlySumResponse
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
+// @Router /subscriptions/sum/monthly [get]
+func (h *Handler) GetSubscriptionMonthlySum(c *gin.Context) {
+	params, err := parseQueryParams(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	overlap, err := parseOverlap(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	months, err := h.repo.MonthlyBreakdown(ctx, params.UserID, params.ServiceName, params.StartDate, params.EndDate, overlap)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	collapsed, err := h.repo.CollapsedOverlaps(ctx, params.UserID, params.ServiceName, params.StartDate, params.EndDate, overlap)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	resp := apiv1.NewMonthlySumResponse(months, string(overlap), collapsed)
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateDiscount godoc
+// @Summary Добавить скидку к подписке
+// @ID createDiscount
+// @Description Добавляет процентную или фиксированную скидку на диапазон месяцев подписки
+// @Tags discounts
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID подписки" format(uuid)
+// @Param discount body apiv1.CreateDiscountRequest true "Discount data"
+// @Success 201 {object} apiv1.Discount
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 404 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
+// @Router /subscriptions/{id}/discounts [post]
+func (h *Handler) CreateDiscount(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.InvalidField("id", "must be a UUID"))
+		return
+	}
+
+	var req apiv1.CreateDiscountRequest
+	if err := validation.DecodeJSON(c.Request.Body, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := validation.Struct(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if _, ok := h.fetchSubscription(c, id); !ok {
+		return
+	}
+
+	discount := req.Model(id)
+
+	ctx := c.Request.Context()
+	if err := h.repo.CreateDiscount(ctx, &discount); err != nil {
+		c.Error(err)
+		return
+	}
+
+	traceID, _ := c.Get("trace_id")
+	logger.Log.Info("Скидка добавлена", "trace_id", traceID, "discount", discount)
+
+	c.JSON(http.StatusCreated, apiv1.NewDiscount(discount))
+}
+
+// GetDiscountList godoc
+// @Summary Получить скидки подписки
+// @ID listDiscounts
+// @Description Список скидок, привязанных к подписке
+// @Tags discounts
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID подписки" format(uuid)
+// @Success 200 {array} apiv1.Discount
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 404 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
+// @Router /subscriptions/{id}/discounts [get]
+func (h *Handler) GetDiscountList(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.InvalidField("id", "must be a UUID"))
+		return
+	}
+
+	if _, ok := h.fetchSubscription(c, id); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	discounts, err := h.repo.ListDiscounts(ctx, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiv1.NewDiscounts(discounts))
+}
+
+// DeleteDiscount godoc
+// @Summary Удалить скидку
+// @ID deleteDiscount
+// @Description Удаляет скидку подписки по ID
+// @Tags discounts
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID подписки" format(uuid)
+// @Param discount_id path string true "UUID скидки" format(uuid)
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 404 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
+// @Router /subscriptions/{id}/discounts/{discount_id} [delete]
+func (h *Handler) DeleteDiscount(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.InvalidField("id", "must be a UUID"))
+		return
+	}
+	discountID, err := uuid.Parse(c.Param("discount_id"))
+	if err != nil {
+		c.Error(apierror.InvalidField("discount_id", "must be a UUID"))
+		return
+	}
+
+	if _, ok := h.fetchSubscription(c, id); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.repo.DeleteDiscount(ctx, id, discountID); err != nil {
+		c.Error(apierror.NotFound(err, apierror.CodeDiscountNotFound, "discount not found"))
+		return
+	}
+
+	traceID, _ := c.Get("trace_id")
+	logger.Log.Info("Скидка удалена", "trace_id", traceID, "subscription_id", id, "discount_id", discountID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "discount deleted"})
+}
+
+// GetSubscriptionConflicts godoc
+// @Summary Найти конфликты в подписках пользователя
+// @ID getSubscriptionConflicts
+// @Description Ищет пересекающиеся периоды одного сервиса, похожие названия сервисов
+// @Description и подписки с end_date раньше start_date. Для каждой находки предлагается исправление.
+// @Tags subscriptions
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param user_id path string true "UUID пользователя" format(uuid)
+// @Success 200 {array} apiv1.Conflict
+// @Failure 400 {object} apierror.Problem
+// @Failure 401 {object} apierror.Problem
+// @Failure 403 {object} apierror.Problem
+// @Failure 500 {object} apierror.Problem
+// @Router /users/{user_id}/subscriptions/conflicts [get]
+func (h *Handler) GetSubscriptionConflicts(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.Error(apierror.InvalidField("user_id", "must be a UUID"))
+		return
+	}
+	if err := authorizeUser(c, userID); err != nil {
+		c.Error(err)
 		return
 	}
 
 	ctx := c.Request.Context()
-	sum, err := h.repo.SumByUserAndService(ctx, params.UserID, params.ServiceName, params.StartDate, params.EndDate)
+	conflicts, err := h.repo.FindConflicts(ctx, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"sum": sum})
+	c.JSON(http.StatusOK, apiv1.NewConflicts(conflicts))
 }
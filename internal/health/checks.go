@@ -0,0 +1,40 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// DBPing проверяет соединение с БД
+func DBPing(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// SchemaVersion сверяет версию схемы в schema_migrations (таблица golang-migrate)
+// с последней миграцией, которую ожидает сборка
+func SchemaVersion(db *sql.DB, expected uint) CheckFunc {
+	return func(ctx context.Context) error {
+		var (
+			version uint
+			dirty   bool
+		)
+		err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("no migrations applied")
+		}
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		if version != expected {
+			return fmt.Errorf("schema version %d, expected %d", version, expected)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc проверяет одну зависимость. Ошибка означает, что зависимость недоступна.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	// critical - провал проверки делает сервис неготовым; остальные только отображаются
+	critical bool
+	fn       CheckFunc
+}
+
+// CheckResult - результат одной проверки в ответе /readyz
+type CheckResult struct {
+	Status     string         `json:"status"`
+	Critical   bool           `json:"critical"`
+	DurationMs int64          `json:"duration_ms"`
+	Error      string         `json:"error,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Checker собирает проверки готовности сервиса
+type Checker struct {
+	timeout      time.Duration
+	checks       []check
+	workers      []*Worker
+	shuttingDown atomic.Bool
+}
+
+// NewChecker создает Checker, timeout ограничивает каждую проверку
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+func (h *Checker) Add(name string, critical bool, fn CheckFunc) {
+	h.checks = append(h.checks, check{name: name, critical: critical, fn: fn})
+}
+
+// AddWorker показывает состояние фонового задания в /readyz, не влияя на готовность:
+// без фонового пересчета запросы все равно обслуживаются
+func (h *Checker) AddWorker(w *Worker) {
+	h.workers = append(h.workers, w)
+}
+
+// SetShuttingDown переводит /readyz в 503, чтобы балансировщик перестал
+// направлять запросы до остановки сервера
+func (h *Checker) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Ready выполняет все проверки параллельно
+func (h *Checker) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(h.checks)+len(h.workers)+1)}
+
+	if h.shuttingDown.Load() {
+		report.Status = StatusFail
+		report.Checks["shutdown"] = CheckResult{Status: StatusFail, Critical: true, Error: "service is shutting down"}
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			result := h.run(ctx, c)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if result.Status != StatusOK && c.critical {
+				report.Status = StatusFail
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	for _, w := range h.workers {
+		report.Checks["worker:"+w.name] = w.result()
+	}
+	return report
+}
+
+func (h *Checker) run(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	result := CheckResult{Status: StatusOK, Critical: c.critical, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LiveHandler отвечает, пока процесс способен обслуживать HTTP, без проверки зависимостей
+func LiveHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": StatusOK})
+	}
+}
+
+// ReadyHandler отвечает 200, если пройдены все критичные проверки, иначе 503
+func (h *Checker) ReadyHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := h.Ready(c.Request.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	}
+}
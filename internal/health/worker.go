@@ -0,0 +1,64 @@
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+// Worker - состояние фонового задания для /readyz
+type Worker struct {
+	name string
+
+	mu       sync.Mutex
+	running  bool
+	lastRun  time.Time
+	lastErr  error
+	runCount int64
+}
+
+func NewWorker(name string) *Worker {
+	return &Worker{name: name}
+}
+
+func (w *Worker) Started() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running = true
+}
+
+func (w *Worker) Stopped() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running = false
+}
+
+// Report сохраняет итог очередного запуска задания
+func (w *Worker) Report(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastRun = time.Now()
+	w.lastErr = err
+	w.runCount++
+}
+
+func (w *Worker) result() CheckResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	result := CheckResult{
+		Status:  StatusOK,
+		Details: map[string]any{"running": w.running, "runs": w.runCount},
+	}
+	if !w.lastRun.IsZero() {
+		result.Details["last_run"] = w.lastRun.UTC().Format(time.RFC3339)
+	}
+	switch {
+	case !w.running:
+		result.Status = StatusFail
+		result.Error = "worker is not running"
+	case w.lastErr != nil:
+		result.Status = StatusFail
+		result.Error = w.lastErr.Error()
+	}
+	return result
+}
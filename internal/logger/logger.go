@@ -8,26 +8,32 @@ import (
 
 var Log *slog.Logger
 
+// level позволяет поменять уровень после загрузки конфигурации,
+// не пересоздавая логгер, уже сохраненный другими пакетами
+var level = new(slog.LevelVar)
+
 func init() {
 	InitLogger()
 }
 
 func InitLogger() {
-	levelStr := strings.ToLower(os.Getenv("LOG_LEVEL"))
+	SetLevel(os.Getenv("LOG_LEVEL"))
+
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+
+	Log = slog.New(handler)
+}
 
-	var level slog.Level
-	switch levelStr {
+// SetLevel задает уровень логирования: debug, info, warn или error
+func SetLevel(levelStr string) {
+	switch strings.ToLower(levelStr) {
 	case "debug":
-		level = slog.LevelDebug
+		level.Set(slog.LevelDebug)
 	case "warn":
-		level = slog.LevelWarn
+		level.Set(slog.LevelWarn)
 	case "error":
-		level = slog.LevelError
+		level.Set(slog.LevelError)
 	default:
-		level = slog.LevelInfo
+		level.Set(slog.LevelInfo)
 	}
-
-	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
-
-	Log = slog.New(handler)
 }
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ActivitySource - источник бизнес-метрик
+type ActivitySource interface {
+	ActiveSubscriptions(ctx context.Context) ([]models.TenantActivity, error)
+}
+
+const (
+	// activityCacheTTL - как долго используются посчитанные значения, чтобы частые
+	// опросы Prometheus не нагружали БД
+	activityCacheTTL = time.Minute
+	activityTimeout  = 5 * time.Second
+)
+
+var (
+	activeSubscriptionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "active"),
+		"Число подписок, действующих в текущем месяце.",
+		[]string{"tenant"}, nil,
+	)
+	activeUsersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "active_users"),
+		"Число пользователей с действующими в текущем месяце подписками.",
+		[]string{"tenant"}, nil,
+	)
+)
+
+type activityCollector struct {
+	source ActivitySource
+
+	mu        sync.Mutex
+	cached    []models.TenantActivity
+	updatedAt time.Time
+}
+
+// RegisterActivity публикует бизнес-метрики по арендаторам
+func RegisterActivity(source ActivitySource) error {
+	return prometheus.Register(&activityCollector{source: source})
+}
+
+func (c *activityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeSubscriptionsDesc
+	ch <- activeUsersDesc
+}
+
+func (c *activityCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, a := range c.activity() {
+		ch <- prometheus.MustNewConstMetric(activeSubscriptionsDesc, prometheus.GaugeValue, float64(a.Subscriptions), a.TenantID)
+		ch <- prometheus.MustNewConstMetric(activeUsersDesc, prometheus.GaugeValue, float64(a.Users), a.TenantID)
+	}
+}
+
+// activity возвращает закэшированные значения, а при ошибке запроса - предыдущие
+func (c *activityCollector) activity() []models.TenantActivity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.updatedAt) < activityCacheTTL {
+		return c.cached
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), activityTimeout)
+	defer cancel()
+
+	activity, err := c.source.ActiveSubscriptions(ctx)
+	if err != nil {
+		logger.Log.Error("Ошибка расчета бизнес-метрик", "error", err)
+		return c.cached
+	}
+	c.cached, c.updatedAt = activity, time.Now()
+	return c.cached
+}
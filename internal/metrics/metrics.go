@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "subscriptions"
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Длительность HTTP-запросов по маршруту и статусу.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Длительность SQL-запросов по типу операции.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation"})
+
+	dbQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Число SQL-запросов, завершившихся ошибкой.",
+	}, []string{"operation"})
+
+	subscriptionsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "created_total",
+		Help:      "Число созданных подписок по сервисам.",
+	}, []string{"service_name"})
+)
+
+// Middleware измеряет длительность запросов. Маршрут берется из шаблона пути,
+// чтобы id в путях не раздували число рядов.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveQuery учитывает выполненный SQL-запрос. failed - запрос завершился ошибкой,
+// а не просто не нашел строк.
+func ObserveQuery(operation string, duration time.Duration, failed bool) {
+	dbQueryDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if failed {
+		dbQueryErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// RegisterDB публикует статистику пула соединений из sql.DB.Stats()
+func RegisterDB(db *sql.DB) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// maxServiceLabels ограничивает число названий сервисов в метриках: названия
+// приходят от клиентов, остальные учитываются как "other"
+const maxServiceLabels = 200
+
+var (
+	serviceLabelsMu sync.Mutex
+	serviceLabels   = make(map[string]struct{})
+)
+
+func serviceLabel(name string) string {
+	serviceLabelsMu.Lock()
+	defer serviceLabelsMu.Unlock()
+
+	if _, ok := serviceLabels[name]; ok {
+		return name
+	}
+	if len(serviceLabels) >= maxServiceLabels {
+		return "other"
+	}
+	serviceLabels[name] = struct{}{}
+	return name
+}
+
+func SubscriptionCreated(serviceName string) {
+	subscriptionsCreated.WithLabelValues(serviceLabel(serviceName)).Inc()
+}
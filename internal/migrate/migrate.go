@@ -0,0 +1,346 @@
+// Package migrate применяет SQL-миграции. Версия схемы хранится в таблице
+// schema_migrations в том же формате, что у golang-migrate, поэтому базы,
+// размеченные внешним migrate, продолжают работать без изменений.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// lockID - ключ advisory lock, под которым миграции выполняет только одна реплика
+const lockID int64 = 7_402_913_551
+
+var (
+	ErrDirty          = errors.New("schema is dirty, fix the failed migration and force the version")
+	ErrUnknownVersion = errors.New("unknown migration version")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+// Status - текущая версия схемы и список миграций. Version 0 - миграции не применялись.
+type Status struct {
+	Version    uint              `json:"version"`
+	Dirty      bool              `json:"dirty"`
+	Migrations []MigrationStatus `json:"migrations"`
+}
+
+type Runner struct {
+	db         *sql.DB
+	migrations []Migration
+	// sqlite - база SQLite: без advisory lock и statement_timeout
+	sqlite bool
+}
+
+// New читает миграции из fsys и проверяет, что у каждой есть up и down
+func New(db *sql.DB, fsys fs.FS) (*Runner, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{db: db, migrations: migrations}, nil
+}
+
+// NewSQLite - New для базы SQLite. Файл базы использует один процесс, поэтому
+// миграции разных вызовов упорядочиваются блокировкой внутри процесса.
+func NewSQLite(db *sql.DB, fsys fs.FS) (*Runner, error) {
+	r, err := New(db, fsys)
+	if err != nil {
+		return nil, err
+	}
+	r.sqlite = true
+	return r, nil
+}
+
+// sqliteLock заменяет advisory lock для баз SQLite
+var sqliteLock sync.Mutex
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", e.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[uint(version)]
+		if !ok {
+			mig = &Migration{Version: uint(version), Name: m[2]}
+			byVersion[uint(version)] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return migrations, nil
+}
+
+// Latest - версия последней миграции, с которой совместима сборка
+func (r *Runner) Latest() uint {
+	if len(r.migrations) == 0 {
+		return 0
+	}
+	return r.migrations[len(r.migrations)-1].Version
+}
+
+// Up применяет все неприменённые миграции и возвращает их число
+func (r *Runner) Up(ctx context.Context) (int, error) {
+	return r.migrateTo(ctx, r.Latest())
+}
+
+// Down откатывает последние steps миграций
+func (r *Runner) Down(ctx context.Context, steps int) (int, error) {
+	if steps < 1 {
+		return 0, fmt.Errorf("steps must be positive")
+	}
+
+	var n int
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := r.current(ctx, conn)
+		if err != nil {
+			return err
+		}
+		idx := r.index(version)
+		if idx < 0 {
+			return nil
+		}
+		target := uint(0)
+		if idx-steps >= 0 {
+			target = r.migrations[idx-steps].Version
+		}
+		n, err = r.apply(ctx, conn, version, target)
+		return err
+	})
+	return n, err
+}
+
+// Goto переводит схему на указанную версию вверх или вниз
+func (r *Runner) Goto(ctx context.Context, version uint) (int, error) {
+	if r.index(version) < 0 {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return r.migrateTo(ctx, version)
+}
+
+// Force записывает версию без выполнения миграций и снимает признак dirty.
+// Нужна после ручного исправления упавшей миграции.
+func (r *Runner) Force(ctx context.Context, version uint) error {
+	if version != 0 && r.index(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return r.withLock(ctx, func(conn *sql.Conn) error {
+		return setVersion(ctx, conn, version, false)
+	})
+}
+
+func (r *Runner) Status(ctx context.Context) (Status, error) {
+	var status Status
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		var err error
+		status.Version, status.Dirty, err = readVersion(ctx, conn)
+		return err
+	})
+	if err != nil {
+		return status, err
+	}
+
+	for _, m := range r.migrations {
+		status.Migrations = append(status.Migrations, MigrationStatus{
+			Version: m.Version,
+			Name:    m.Name,
+			Applied: m.Version <= status.Version,
+		})
+	}
+	return status, nil
+}
+
+func (r *Runner) migrateTo(ctx context.Context, target uint) (int, error) {
+	var n int
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := r.current(ctx, conn)
+		if err != nil {
+			return err
+		}
+		n, err = r.apply(ctx, conn, version, target)
+		return err
+	})
+	return n, err
+}
+
+// current возвращает версию схемы, если она чистая и известна сборке
+func (r *Runner) current(ctx context.Context, conn *sql.Conn) (uint, error) {
+	version, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w: version %d", ErrDirty, version)
+	}
+	if version != 0 && r.index(version) < 0 {
+		return 0, fmt.Errorf("%w: database is at %d", ErrUnknownVersion, version)
+	}
+	return version, nil
+}
+
+// apply выполняет миграции от version до target. Перед каждой миграцией версия
+// помечается dirty, чтобы упавшая на середине миграция не осталась незамеченной.
+func (r *Runner) apply(ctx context.Context, conn *sql.Conn, version, target uint) (int, error) {
+	var n int
+	for version < target {
+		next := r.migrations[r.index(version)+1]
+		if err := run(ctx, conn, next.Version, next.Up, next.Version); err != nil {
+			return n, fmt.Errorf("migration %d_%s up: %w", next.Version, next.Name, err)
+		}
+		version = next.Version
+		n++
+	}
+	for version > target {
+		idx := r.index(version)
+		prev := uint(0)
+		if idx > 0 {
+			prev = r.migrations[idx-1].Version
+		}
+		m := r.migrations[idx]
+		if err := run(ctx, conn, m.Version, m.Down, prev); err != nil {
+			return n, fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+		}
+		version = prev
+		n++
+	}
+	return n, nil
+}
+
+func run(ctx context.Context, conn *sql.Conn, dirtyVersion uint, query string, version uint) error {
+	if err := setVersion(ctx, conn, dirtyVersion, true); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	return setVersion(ctx, conn, version, false)
+}
+
+// index возвращает позицию миграции с версией version, для 0 - -1
+func (r *Runner) index(version uint) int {
+	return slices.IndexFunc(r.migrations, func(m Migration) bool { return m.Version == version })
+}
+
+// withLock выполняет fn на отдельном соединении под advisory lock
+func (r *Runner) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if r.sqlite {
+		sqliteLock.Lock()
+		defer sqliteLock.Unlock()
+		if err := createVersionTable(ctx, conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	}
+
+	// ожидание блокировки и сами миграции могут идти дольше statement_timeout пула
+	if _, err := conn.ExecContext(ctx, `SET statement_timeout = 0`); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `RESET statement_timeout`)
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	if err := createVersionTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func createVersionTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`,
+	)
+	return err
+}
+
+func readVersion(ctx context.Context, conn *sql.Conn) (uint, bool, error) {
+	var (
+		version uint
+		dirty   bool
+	)
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// setVersion хранит в schema_migrations одну строку, как golang-migrate; версия 0 - пустая таблица
+func setVersion(ctx context.Context, conn *sql.Conn, version uint, dirty bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version != 0 || dirty {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, version, dirty); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
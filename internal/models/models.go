@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"subscriptions-service/internal/logger"
 	"time"
 
@@ -42,6 +43,7 @@ func (myd MonthYearDate) Value() (driver.Value, error) {
 
 type Subscription struct {
 	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID    string         `gorm:"not null;default:default;index" json:"-"`
 	ServiceName string         `gorm:"not null" json:"service_name"`
 	Price       int            `gorm:"not null" json:"price"`
 	UserID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
@@ -50,3 +52,225 @@ type Subscription struct {
 	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"-"`
 	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"-"`
 }
+
+type DiscountType string
+
+const (
+	DiscountPercent DiscountType = "percent"
+	DiscountFixed   DiscountType = "fixed"
+)
+
+// Discount - скидка или промо-цена, действующая на подписку в диапазоне месяцев.
+// Для percent Value - процент от цены, для fixed - сумма в рублях.
+type Discount struct {
+	ID             uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SubscriptionID uuid.UUID      `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	Type           DiscountType   `gorm:"not null" json:"type"`
+	Value          int            `gorm:"not null" json:"value"`
+	StartDate      MonthYearDate  `gorm:"not null" json:"start_date"`
+	EndDate        *MonthYearDate `json:"end_date"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"-"`
+}
+
+func (Discount) TableName() string {
+	return "subscription_discounts"
+}
+
+// MonthlySpend - расходы пользователя за один месяц до и после скидок
+type MonthlySpend struct {
+	Month    MonthYearDate `json:"month" format:"month" example:"07-2025"`
+	Gross    int           `json:"gross"`
+	Discount int           `json:"discount"`
+	Net      int           `json:"net"`
+}
+
+// ServiceMonthSpend - расходы пользователя на один сервис за месяц
+type ServiceMonthSpend struct {
+	UserID      uuid.UUID     `json:"user_id"`
+	ServiceName string        `json:"service_name"`
+	Month       MonthYearDate `json:"month" format:"month" example:"07-2025"`
+	Gross       int           `json:"gross"`
+	Discount    int           `json:"discount"`
+	Net         int           `json:"net"`
+}
+
+// CollapsedOverlap - запись, исключенная из расчета суммы, так как в те же месяцы
+// по тому же сервису учтена другая запись
+type CollapsedOverlap struct {
+	ServiceName string        `json:"service_name"`
+	KeptID      uuid.UUID     `json:"kept_id"`
+	CollapsedID uuid.UUID     `json:"collapsed_id"`
+	StartMonth  MonthYearDate `json:"start_month"`
+	EndMonth    MonthYearDate `json:"end_month"`
+	Months      int           `json:"months"`
+}
+
+type ConflictKind string
+
+const (
+	ConflictOverlap       ConflictKind = "overlap"
+	ConflictSimilarName   ConflictKind = "similar_name"
+	ConflictInvalidPeriod ConflictKind = "invalid_period"
+)
+
+type FixAction string
+
+const (
+	FixMerge     FixAction = "merge"
+	FixTrim      FixAction = "trim"
+	FixSplit     FixAction = "split"
+	FixRemove    FixAction = "remove"
+	FixRename    FixAction = "rename"
+	FixSwapDates FixAction = "swap_dates"
+)
+
+// Conflict - найденная проблема в данных пользователя вместе с предлагаемым исправлением
+type Conflict struct {
+	Kind            ConflictKind `json:"kind"`
+	SubscriptionIDs []uuid.UUID  `json:"subscription_ids"`
+	Description     string       `json:"description"`
+	Fix             ConflictFix  `json:"fix"`
+}
+
+// ConflictFix - исправление конфликта: записи из Update нужно сохранить
+// в указанном состоянии, записи из Create - создать, записи из Delete - удалить
+type ConflictFix struct {
+	Action FixAction      `json:"action"`
+	Update []Subscription `json:"update,omitempty"`
+	Create []Subscription `json:"create,omitempty"`
+	Delete []uuid.UUID    `json:"delete,omitempty"`
+}
+
+// ServicePopularity - число подписок и пользователей сервиса за период
+type ServicePopularity struct {
+	ServiceName   string `json:"service_name"`
+	Subscriptions int    `json:"subscriptions"`
+	Users         int    `json:"users"`
+}
+
+// SpendAverage - суммарные и средние расходы на одного пользователя
+type SpendAverage struct {
+	Users   int     `json:"users"`
+	Total   int     `json:"total"`
+	Average float64 `json:"average"`
+}
+
+type MonthlySpendAverage struct {
+	Month MonthYearDate `json:"month" format:"month" example:"07-2025"`
+	SpendAverage
+}
+
+// MonthlyChurn - сколько подписок началось, действовало и закончилось в месяце.
+// ChurnRate - доля закончившихся от действовавших.
+type MonthlyChurn struct {
+	Month     MonthYearDate `json:"month" format:"month" example:"07-2025"`
+	Started   int           `json:"started"`
+	Active    int           `json:"active"`
+	Ended     int           `json:"ended"`
+	ChurnRate float64       `json:"churn_rate"`
+}
+
+// PriceDistribution - распределение цен подписок одного сервиса
+type PriceDistribution struct {
+	ServiceName   string  `json:"service_name"`
+	Subscriptions int     `json:"subscriptions"`
+	Min           int     `json:"min"`
+	Max           int     `json:"max"`
+	Average       float64 `json:"average"`
+	P25           float64 `json:"p25"`
+	Median        float64 `json:"median"`
+	P75           float64 `json:"p75"`
+}
+
+// TenantActivity - действующие в текущем месяце подписки арендатора
+type TenantActivity struct {
+	TenantID      string `json:"tenant_id"`
+	Subscriptions int    `json:"subscriptions"`
+	Users         int    `json:"users"`
+}
+
+// SpendMismatch - расхождение между сохраненным агрегатом monthly_user_service_spend
+// и живым расчетом. nil означает, что строки с одной из сторон нет.
+type SpendMismatch struct {
+	TenantID       string        `json:"tenant_id"`
+	UserID         uuid.UUID     `json:"user_id"`
+	ServiceName    string        `json:"service_name"`
+	Overlap        string        `json:"overlap"`
+	Month          MonthYearDate `json:"month" format:"month" example:"07-2025"`
+	StoredGross    *int          `json:"stored_gross"`
+	StoredDiscount *int          `json:"stored_discount"`
+	StoredNet      *int          `json:"stored_net"`
+	LiveGross      *int          `json:"live_gross"`
+	LiveDiscount   *int          `json:"live_discount"`
+	LiveNet        *int          `json:"live_net"`
+}
+
+// StringList хранится в БД одной строкой через пробел
+type StringList []string
+
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+	case string:
+		*l = strings.Fields(v)
+	case []byte:
+		*l = strings.Fields(string(v))
+	default:
+		return fmt.Errorf("failed to scan StringList")
+	}
+	return nil
+}
+
+func (l StringList) Value() (driver.Value, error) {
+	return strings.Join(l, " "), nil
+}
+
+// UUIDList хранится в БД одной строкой через пробел
+type UUIDList []uuid.UUID
+
+func (l *UUIDList) Scan(value interface{}) error {
+	var fields StringList
+	if err := fields.Scan(value); err != nil {
+		return fmt.Errorf("failed to scan UUIDList")
+	}
+	ids := make(UUIDList, 0, len(fields))
+	for _, f := range fields {
+		id, err := uuid.Parse(f)
+		if err != nil {
+			return fmt.Errorf("failed to scan UUIDList: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	*l = ids
+	return nil
+}
+
+func (l UUIDList) Value() (driver.Value, error) {
+	fields := make([]string, len(l))
+	for i, id := range l {
+		fields[i] = id.String()
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// APIKey - ключ доступа для сервисных клиентов. Сам ключ не хранится, только его хэш.
+// Пустой AllowedUserIDs разрешает работу с подписками любых пользователей.
+type APIKey struct {
+	ID             uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID       string     `gorm:"not null;default:default" json:"tenant_id"`
+	Name           string     `gorm:"not null" json:"name"`
+	Prefix         string     `gorm:"not null;uniqueIndex" json:"prefix"`
+	KeyHash        string     `gorm:"not null" json:"-"`
+	Scopes         StringList `gorm:"type:text;not null" json:"scopes"`
+	AllowedUserIDs UUIDList   `gorm:"type:text;not null" json:"allowed_user_ids"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	UsageCount     int64      `gorm:"not null;default:0" json:"usage_count"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,78 @@
+package openapi
+
+import (
+	"errors"
+	"strings"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/validation"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// requestError переводит ошибки проверки запроса в validation_failed с нарушением
+// по каждому параметру и полю тела
+func requestError(err error) error {
+	var fields []apierror.FieldError
+	for _, e := range unwrapMulti(err) {
+		var reqErr *openapi3filter.RequestError
+		if !errors.As(e, &reqErr) {
+			return apierror.Validation("request does not match the API specification: " + e.Error())
+		}
+
+		switch {
+		case reqErr.Parameter != nil:
+			fields = append(fields, apierror.FieldError{
+				Field:   reqErr.Parameter.Name,
+				Message: reason(reqErr),
+			})
+		case reqErr.RequestBody != nil:
+			schemaErrs := schemaErrors(reqErr.Err)
+			if len(schemaErrs) == 0 {
+				return apierror.Validation("invalid request body: " + reason(reqErr))
+			}
+			for _, se := range schemaErrs {
+				fields = append(fields, validation.Field(strings.Join(se.JSONPointer(), "."), se.Reason))
+			}
+		default:
+			return apierror.Validation(reason(reqErr))
+		}
+	}
+	return apierror.Validation("request does not match the API specification", fields...)
+}
+
+func reason(e *openapi3filter.RequestError) string {
+	var se *openapi3.SchemaError
+	var pe *openapi3filter.ParseError
+	switch {
+	case errors.Is(e.Err, openapi3filter.ErrInvalidRequired):
+		return "is required"
+	case errors.As(e.Err, &se):
+		return se.Reason
+	case errors.As(e.Err, &pe):
+		return pe.Reason
+	case e.Reason != "":
+		return e.Reason
+	}
+	return e.Error()
+}
+
+func schemaErrors(err error) []*openapi3.SchemaError {
+	var out []*openapi3.SchemaError
+	for _, e := range unwrapMulti(err) {
+		var se *openapi3.SchemaError
+		if errors.As(e, &se) {
+			out = append(out, se)
+		}
+	}
+	return out
+}
+
+func unwrapMulti(err error) []error {
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		return multi
+	}
+	return []error{err}
+}
@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"subscriptions-service/internal/apierror"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+)
+
+// Options - что проверять по спецификации. Для проверки ответа тело буферизуется
+// целиком, поэтому она включается в тестах, а не в рабочем сервисе.
+type Options struct {
+	Requests  bool
+	Responses bool
+}
+
+// Middleware проверяет запросы и ответы маршрутов, описанных в doc. prefix - префикс
+// группы, которого нет в путях спецификации (/v1). Маршруты без описания
+// в спецификации, например /graphql, не проверяются.
+//
+// Запрос, не соответствующий спецификации, отклоняется с validation_failed до обработчика.
+// Ответ, не соответствующий спецификации, заменяется на 500 с описанием расхождения.
+func Middleware(doc *openapi3.T, prefix string, opts Options) gin.HandlerFunc {
+	if !opts.Requests && !opts.Responses {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	filterOpts := &openapi3filter.Options{
+		MultiError:            true,
+		SkipSettingDefaults:   true,
+		IncludeResponseStatus: true,
+		// аутентификацию выполняет auth.Middleware до этой проверки
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(c *gin.Context) {
+		route, ok := findRoute(doc, prefix, c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams(c.Params),
+			Route:      route,
+			Options:    filterOpts,
+		}
+		if opts.Requests {
+			if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+				apierror.Abort(c, requestError(err))
+				return
+			}
+		}
+
+		if !opts.Responses {
+			c.Next()
+			return
+		}
+		validateResponse(c, input)
+	}
+}
+
+func validateResponse(c *gin.Context, input *openapi3filter.RequestValidationInput) {
+	rec := &recorder{ResponseWriter: c.Writer}
+	c.Writer = rec
+	c.Next()
+	c.Writer = rec.ResponseWriter
+
+	resp := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 rec.Status(),
+		Header:                 rec.Header(),
+		Options:                input.Options,
+	}
+	if err := openapi3filter.ValidateResponse(c.Request.Context(), resp.SetBodyBytes(rec.body.Bytes())); err != nil {
+		c.Writer.Header().Del("Content-Type")
+		apierror.Respond(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal,
+			"response does not match the API specification: "+err.Error()))
+		return
+	}
+
+	c.Writer.WriteHeaderNow()
+	_, _ = c.Writer.Write(rec.body.Bytes())
+}
+
+func findRoute(doc *openapi3.T, prefix string, c *gin.Context) (*routers.Route, bool) {
+	full := c.FullPath()
+	if full == "" || !strings.HasPrefix(full, prefix) {
+		return nil, false
+	}
+	path := specPath(strings.TrimPrefix(full, prefix))
+
+	item := doc.Paths.Value(path)
+	if item == nil {
+		return nil, false
+	}
+	op := item.GetOperation(c.Request.Method)
+	if op == nil {
+		return nil, false
+	}
+	return &routers.Route{
+		Spec:      doc,
+		Path:      path,
+		PathItem:  item,
+		Method:    c.Request.Method,
+		Operation: op,
+	}, true
+}
+
+func pathParams(params gin.Params) map[string]string {
+	out := make(map[string]string, len(params))
+	for _, p := range params {
+		out[p.Key] = p.Value
+	}
+	return out
+}
+
+// recorder придерживает ответ обработчика до проверки. Written сообщает о записанном
+// теле, чтобы apierror.Middleware не дописывал к нему вторую ошибку.
+type recorder struct {
+	gin.ResponseWriter
+	body    bytes.Buffer
+	written bool
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *recorder) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *recorder) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *recorder) Written() bool {
+	return w.written
+}
+
+func (w *recorder) Size() int {
+	return w.body.Len()
+}
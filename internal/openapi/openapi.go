@@ -0,0 +1,65 @@
+// Package openapi проверяет запросы и ответы REST API по спецификации,
+// которую swag генерирует в internal/swagger
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/swagger"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Load читает swagger.json и переводит его в OpenAPI 3. Swagger 2.0 не позволяет
+// указать тип содержимого отдельного ответа, поэтому ответы с ошибками получают
+// application/problem+json здесь.
+func Load() (*openapi3.T, error) {
+	var doc2 openapi2.T
+	if err := json.Unmarshal(swagger.JSON, &doc2); err != nil {
+		return nil, fmt.Errorf("parse swagger.json: %w", err)
+	}
+	doc, err := openapi2conv.ToV3(&doc2)
+	if err != nil {
+		return nil, fmt.Errorf("convert swagger.json to OpenAPI 3: %w", err)
+	}
+	// без host конвертер не переносит basePath в servers
+	if len(doc.Servers) == 0 && doc2.BasePath != "" {
+		doc.Servers = openapi3.Servers{{URL: doc2.BasePath}}
+	}
+
+	for _, item := range doc.Paths.Map() {
+		for _, op := range item.Operations() {
+			for status, resp := range op.Responses.Map() {
+				if code, err := strconv.Atoi(status); err != nil || code < 400 || resp.Value == nil {
+					continue
+				}
+				if mt := resp.Value.Content.Get("application/json"); mt != nil {
+					resp.Value.Content = openapi3.Content{apierror.ContentType: mt}
+				}
+			}
+		}
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	return doc, nil
+}
+
+// specPath переводит /subscriptions/:id в /subscriptions/{id}
+func specPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,88 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// MonthPattern - месяц в формате MM-YYYY, который принимают и отдают все ручки
+const MonthPattern = `^(0[1-9]|1[0-2])-[0-9]{4}$`
+
+const schemaRefPrefix = "#/components/schemas/"
+
+// V31 переводит документ в OpenAPI 3.1 для клиентов и генераторов кода:
+//   - nullable заменяется типом null;
+//   - строки с форматом month получают шаблон MM-YYYY;
+//   - BearerAuth описан как http-схема bearer с JWT вместо apiKey из Swagger 2.0;
+//   - схемы называются без пакета Go: Subscription вместо apiv1.Subscription.
+func V31(doc *openapi3.T) ([]byte, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal OpenAPI document: %w", err)
+	}
+	var root map[string]any
+	if err := json.Unmarshal(b, &root); err != nil {
+		return nil, fmt.Errorf("unmarshal OpenAPI document: %w", err)
+	}
+	root["openapi"] = "3.1.0"
+
+	components, _ := root["components"].(map[string]any)
+	if schemas, ok := components["schemas"].(map[string]any); ok {
+		renamed := make(map[string]any, len(schemas))
+		for name, schema := range schemas {
+			short := schemaName(name)
+			if _, dup := renamed[short]; dup {
+				return nil, fmt.Errorf("schema name %q is used by several packages", short)
+			}
+			renamed[short] = schema
+		}
+		components["schemas"] = renamed
+	}
+	if schemes, ok := components["securitySchemes"].(map[string]any); ok {
+		if bearer, ok := schemes["BearerAuth"].(map[string]any); ok {
+			schemes["BearerAuth"] = map[string]any{
+				"type":         "http",
+				"scheme":       "bearer",
+				"bearerFormat": "JWT",
+				"description":  bearer["description"],
+			}
+		}
+	}
+
+	upgrade(root)
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// upgrade обходит документ и переписывает конструкции OpenAPI 3.0, которых нет в 3.1
+func upgrade(node any) {
+	switch v := node.(type) {
+	case []any:
+		for _, item := range v {
+			upgrade(item)
+		}
+	case map[string]any:
+		for _, item := range v {
+			upgrade(item)
+		}
+		if ref, ok := v["$ref"].(string); ok && strings.HasPrefix(ref, schemaRefPrefix) {
+			v["$ref"] = schemaRefPrefix + schemaName(strings.TrimPrefix(ref, schemaRefPrefix))
+		}
+		if format, _ := v["format"].(string); format == "month" {
+			v["pattern"] = MonthPattern
+		}
+		if nullable, _ := v["nullable"].(bool); nullable {
+			delete(v, "nullable")
+			if typ, ok := v["type"].(string); ok {
+				v["type"] = []any{typ, "null"}
+			}
+		}
+	}
+}
+
+// schemaName убирает пакет Go из имени схемы swag
+func schemaName(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
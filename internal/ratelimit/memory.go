@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore хранит ведра в памяти процесса. Лимиты действуют на каждую реплику отдельно.
+type MemoryStore struct {
+	cfg Config
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// maxIdleBuckets - после скольких ведер начинается удаление полностью наполненных
+const maxIdleBuckets = 10000
+
+func NewMemoryStore(cfg Config) *MemoryStore {
+	return &MemoryStore{cfg: cfg, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, cost int) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= maxIdleBuckets {
+			s.prune(now)
+		}
+		b = &bucket{tokens: float64(s.cfg.Capacity), updated: now}
+		s.buckets[key] = b
+	}
+	return b.take(s.cfg, cost, now), nil
+}
+
+// prune удаляет ведра, которые успели наполниться: они ничем не отличаются от новых
+func (s *MemoryStore) prune(now time.Time) {
+	window := s.cfg.Window()
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= window {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/logger"
+	"subscriptions-service/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Costs - стоимость маршрутов в токенах по ключу "METHOD /full/path".
+// Маршруты, которых нет в списке, стоят 1 токен.
+type Costs map[string]int
+
+func (c Costs) of(method, path string) int {
+	if cost, ok := c[method+" "+path]; ok {
+		return cost
+	}
+	return 1
+}
+
+// Middleware списывает стоимость маршрута из ведра клиента и отвечает 429,
+// если токенов не хватает. Заголовки RateLimit-* соответствуют черновику IETF
+// draft-ietf-httpapi-ratelimit-headers. Должен стоять после аутентификации
+// и tenant.Middleware: у клиента в каждом арендаторе свое ведро.
+// При недоступности хранилища запросы пропускаются.
+func Middleware(store Store, cfg Config, costs Costs) gin.HandlerFunc {
+	policy := fmt.Sprintf("%d;w=%d", cfg.Capacity, ceilSeconds(cfg.Window()))
+
+	return func(c *gin.Context) {
+		key := bucketKey(c)
+		cost := costs.of(c.Request.Method, c.FullPath())
+
+		res, err := store.Take(c.Request.Context(), key, cost)
+		if err != nil {
+			traceID, _ := c.Get("trace_id")
+			logger.Log.Error("Ошибка проверки лимита запросов", "trace_id", traceID, "key", key, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			traceID, _ := c.Get("trace_id")
+			logger.Log.Warn("Превышен лимит запросов", "trace_id", traceID, "key", key, "cost", cost)
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			apierror.Abort(c, apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimitExceeded, "rate limit exceeded"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// bucketKey - ведро клиента в арендаторе запроса. Один пользователь в разных арендаторах
+// - разные клиенты, и нагрузка в одном не должна расходовать лимит в другом.
+func bucketKey(c *gin.Context) string {
+	key := auth.ClientKey(c)
+	if tenantID, ok := tenant.FromContext(c.Request.Context()); ok {
+		return "tenant:" + tenantID + ":" + key
+	}
+	return key
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostgresStore хранит ведра в таблице rate_limit_buckets, поэтому лимит общий
+// для всех реплик. Строка ведра блокируется на время списания, время берется из БД,
+// чтобы расхождение часов реплик не влияло на пополнение.
+type PostgresStore struct {
+	db  *gorm.DB
+	cfg Config
+}
+
+func NewPostgresStore(db *gorm.DB, cfg Config) *PostgresStore {
+	return &PostgresStore{db: db, cfg: cfg}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, cost int) (Result, error) {
+	var res Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES (?, ?, now())
+			ON CONFLICT (key) DO NOTHING
+		`, key, s.cfg.Capacity).Error
+		if err != nil {
+			return err
+		}
+
+		var row struct {
+			Tokens    float64
+			UpdatedAt time.Time
+			Now       time.Time
+		}
+		err = tx.Raw(`
+			SELECT tokens, updated_at, now() AS now
+			FROM rate_limit_buckets
+			WHERE key = ?
+			FOR UPDATE
+		`, key).Scan(&row).Error
+		if err != nil {
+			return err
+		}
+
+		b := bucket{tokens: row.Tokens, updated: row.UpdatedAt}
+		res = b.take(s.cfg, cost, row.Now)
+
+		return tx.Exec(`UPDATE rate_limit_buckets SET tokens = ?, updated_at = ? WHERE key = ?`, b.tokens, b.updated, key).Error
+	})
+	return res, err
+}
+
+// DeleteIdle удаляет ведра, которые не использовались дольше окна наполнения
+func (s *PostgresStore) DeleteIdle(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Exec(
+		`DELETE FROM rate_limit_buckets WHERE updated_at < now() - make_interval(secs => ?)`,
+		s.cfg.Window().Seconds(),
+	)
+	return result.RowsAffected, result.Error
+}
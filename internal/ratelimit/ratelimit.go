@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Config - параметры token bucket: в ведре помещается Capacity токенов,
+// за секунду добавляется RefillPerSecond. Запрос тратит столько токенов,
+// сколько стоит его маршрут.
+type Config struct {
+	Capacity        int
+	RefillPerSecond float64
+}
+
+// Window - время, за которое пустое ведро наполняется целиком
+func (c Config) Window() time.Duration {
+	return time.Duration(float64(c.Capacity) / c.RefillPerSecond * float64(time.Second))
+}
+
+// Result - итог списания токенов
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset - через сколько ведро снова будет полным
+	Reset time.Duration
+	// RetryAfter - через сколько хватит токенов на отклоненный запрос
+	RetryAfter time.Duration
+}
+
+// Store хранит состояние ведер клиентов
+type Store interface {
+	Take(ctx context.Context, key string, cost int) (Result, error)
+}
+
+// bucket - состояние ведра на момент updated
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take пополняет ведро на момент now и списывает cost токенов, если их хватает.
+// Стоимость больше емкости ограничивается емкостью, иначе запрос не прошел бы никогда.
+func (b *bucket) take(cfg Config, cost int, now time.Time) Result {
+	capacity := float64(cfg.Capacity)
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*cfg.RefillPerSecond)
+	}
+	b.updated = now
+
+	need := math.Min(float64(cost), capacity)
+	res := Result{Limit: cfg.Capacity}
+	if b.tokens >= need {
+		b.tokens -= need
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsDuration((need - b.tokens) / cfg.RefillPerSecond)
+	}
+	res.Remaining = int(math.Floor(b.tokens))
+	res.Reset = secondsDuration((capacity - b.tokens) / cfg.RefillPerSecond)
+	return res
+}
+
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
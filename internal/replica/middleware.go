@@ -0,0 +1,65 @@
+package replica
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"subscriptions-service/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPins - после этого числа клиентов истекшие закрепления удаляются
+const maxPins = 10000
+
+// pins хранит, до какого момента клиент читает с основной базы
+type pins struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func (p *pins) pinned(key string, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return now.Before(p.until[key])
+}
+
+func (p *pins) pin(key string, until time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.until) >= maxPins {
+		now := time.Now()
+		for k, t := range p.until {
+			if !now.Before(t) {
+				delete(p.until, k)
+			}
+		}
+	}
+	p.until[key] = until
+}
+
+// ReadYourWrites после успешного изменяющего запроса закрепляет клиента за основной
+// базой на window, чтобы он сразу видел свои изменения, даже если реплика отстает.
+// Закрепления хранятся в памяти реплики сервиса. Должен стоять после аутентификации.
+func ReadYourWrites(window time.Duration) gin.HandlerFunc {
+	store := &pins{until: make(map[string]time.Time)}
+
+	return func(c *gin.Context) {
+		key := auth.ClientKey(c)
+		if store.pinned(key, time.Now()) {
+			c.Request = c.Request.WithContext(WithPrimary(c.Request.Context()))
+		}
+
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		if c.Writer.Status() < http.StatusBadRequest {
+			store.pin(key, time.Now().Add(window))
+		}
+	}
+}
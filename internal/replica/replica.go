@@ -0,0 +1,68 @@
+// Package replica направляет часть чтений на реплики Postgres. Реплики регистрируются
+// в dbresolver под именем Name, а не глобально, поэтому на них уходят только запросы,
+// явно выбранные через Reader; все остальное по-прежнему идет на основную базу.
+package replica
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// Name - имя resolver-а с репликами
+const Name = "replicas"
+
+// Pool - настройки пула соединений каждой реплики
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// Register подключает реплики по строкам подключения dsns. Без реплик ничего не делает,
+// и Reader возвращает основную базу.
+func Register(db *gorm.DB, dsns []string, pool Pool) error {
+	if len(dsns) == 0 {
+		return nil
+	}
+
+	replicas := make([]gorm.Dialector, len(dsns))
+	for i, dsn := range dsns {
+		replicas[i] = postgres.Open(dsn)
+	}
+
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   dbresolver.RandomPolicy{},
+	}, Name).
+		SetMaxOpenConns(pool.MaxOpenConns).
+		SetMaxIdleConns(pool.MaxIdleConns).
+		SetConnMaxLifetime(pool.ConnMaxLifetime).
+		SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	return db.Use(resolver)
+}
+
+type primaryKey struct{}
+
+// WithPrimary помечает контекст: чтения через Reader пойдут на основную базу
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func PrimaryPinned(ctx context.Context) bool {
+	pinned, _ := ctx.Value(primaryKey{}).(bool)
+	return pinned
+}
+
+// Reader возвращает db, читающий с реплики, если контекст не закреплен за основной базой.
+// Транзакция, начатая от него, целиком выполняется на выбранной реплике.
+func Reader(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if PrimaryPinned(ctx) {
+		return db
+	}
+	return db.Clauses(dbresolver.Use(Name), dbresolver.Read)
+}
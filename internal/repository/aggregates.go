@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/tenant"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Таблица monthly_user_service_spend хранит помесячные суммы по паре пользователь/сервис
+// арендатора для каждой политики пересечений. Триггеры в БД только помечают пару устаревшей
+// в monthly_spend_refresh, а пересчет выполняется здесь тем же запросом, что и живой
+// расчет: перед чтением сумм пользователя и фоновым заданием RefreshStaleSpend.
+// Пары с бессрочными подписками устаревают и с началом нового месяца.
+
+// SpendAggregates - обслуживание агрегатов monthly_user_service_spend
+type SpendAggregates interface {
+	// RefreshStaleSpend пересчитывает все устаревшие пары и возвращает их число
+	RefreshStaleSpend(ctx context.Context) (int, error)
+	// RebuildSpend пересчитывает агрегаты всех пар с нуля
+	RebuildSpend(ctx context.Context) (int, error)
+	// CheckSpend сравнивает актуальные агрегаты с живым расчетом
+	CheckSpend(ctx context.Context) ([]models.SpendMismatch, error)
+}
+
+func NewSpendAggregates(db *gorm.DB) SpendAggregates {
+	return &gormSubscriptionRepository{db: db}
+}
+
+var overlapPolicies = []OverlapPolicy{OverlapMax, OverlapSum, OverlapLatest}
+
+// Границы периода, за который агрегаты хранят все месяцы подписок
+var (
+	aggregateFrom = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	aggregateTo   = time.Date(9999, 12, 1, 0, 0, 0, 0, time.UTC)
+)
+
+const stalePairsSQL = `
+	SELECT refresh.tenant_id, refresh.user_id, refresh.service_name
+	FROM monthly_spend_refresh AS refresh
+	WHERE (refresh.refreshed_month IS NULL OR (
+		refresh.refreshed_month < date_trunc('month', CURRENT_DATE)
+		AND EXISTS (
+			SELECT 1 FROM subscriptions AS s
+			WHERE s.tenant_id = refresh.tenant_id
+				AND s.user_id = refresh.user_id
+				AND s.service_name = refresh.service_name
+				AND s.end_date IS NULL
+		)
+	))`
+
+type userService struct {
+	TenantID    string
+	UserID      uuid.UUID
+	ServiceName string
+}
+
+// stalePairs ищет устаревшие пары арендатора tenantID, для tenant.All - всех арендаторов.
+// userIDs ограничивает поиск пользователями, nil - все пользователи.
+func stalePairs(ctx context.Context, db *gorm.DB, tenantID string, userIDs []uuid.UUID) ([]userService, error) {
+	query, args := stalePairsSQL, []interface{}{}
+	if tenantID != tenant.All {
+		query += " AND refresh.tenant_id = ?"
+		args = append(args, tenantID)
+	}
+	if userIDs != nil {
+		query += " AND refresh.user_id IN ?"
+		args = append(args, userIDs)
+	}
+
+	var pairs []userService
+	err := tenantTx(ctx, db, tenantID, func(tx *gorm.DB) error {
+		return tx.Raw(query, args...).Scan(&pairs).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+func (r *gormSubscriptionRepository) refreshStale(ctx context.Context, tenantID string, userIDs []uuid.UUID) (int, error) {
+	pairs, err := stalePairs(ctx, r.db, tenantID, userIDs)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range pairs {
+		if err := r.refreshPair(ctx, p); err != nil {
+			return 0, err
+		}
+	}
+	return len(pairs), nil
+}
+
+// refreshPair пересчитывает агрегаты одной пары. Строка пары в monthly_spend_refresh
+// блокируется на время пересчета, поэтому изменения, сделанные параллельно,
+// снова пометят пару устаревшей уже после коммита.
+func (r *gormSubscriptionRepository) refreshPair(ctx context.Context, p userService) error {
+	return tenantTx(ctx, r.db, p.TenantID, func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO monthly_spend_refresh (tenant_id, user_id, service_name) VALUES (?, ?, ?)
+			ON CONFLICT (tenant_id, user_id, service_name) DO NOTHING
+		`, p.TenantID, p.UserID, p.ServiceName).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec(`
+			SELECT 1 FROM monthly_spend_refresh
+			WHERE tenant_id = ? AND user_id = ? AND service_name = ?
+			FOR UPDATE
+		`, p.TenantID, p.UserID, p.ServiceName).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec(`
+			DELETE FROM monthly_user_service_spend WHERE tenant_id = ? AND user_id = ? AND service_name = ?
+		`, p.TenantID, p.UserID, p.ServiceName).Error
+		if err != nil {
+			return err
+		}
+
+		for _, overlap := range overlapPolicies {
+			err = tx.Exec(overlap.discountedRowsCTE()+`
+				INSERT INTO monthly_user_service_spend (tenant_id, user_id, service_name, overlap, month, gross, discount, net)
+				SELECT tenant_id, user_id, service_name, ?, month, SUM(gross), SUM(discount), SUM(gross - discount)
+				FROM discounted
+				GROUP BY tenant_id, user_id, service_name, month
+			`, r.baseQuery(tx, p.TenantID, p.UserID, &p.ServiceName, &aggregateFrom, &aggregateTo), aggregateFrom, aggregateTo, string(overlap)).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Exec(`
+			UPDATE monthly_spend_refresh SET refreshed_month = date_trunc('month', CURRENT_DATE)
+			WHERE tenant_id = ? AND user_id = ? AND service_name = ?
+		`, p.TenantID, p.UserID, p.ServiceName).Error
+	})
+}
+
+func (r *gormSubscriptionRepository) RefreshStaleSpend(ctx context.Context) (int, error) {
+	return r.refreshStale(ctx, tenant.All, nil)
+}
+
+func (r *gormSubscriptionRepository) RebuildSpend(ctx context.Context) (int, error) {
+	err := tenantTx(ctx, r.db, tenant.All, func(tx *gorm.DB) error {
+		if err := tx.Exec(`DELETE FROM monthly_user_service_spend`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM monthly_spend_refresh`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+			INSERT INTO monthly_spend_refresh (tenant_id, user_id, service_name, refreshed_month)
+			SELECT DISTINCT tenant_id, user_id, service_name, NULL::date FROM subscriptions
+		`).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return r.refreshStale(ctx, tenant.All, nil)
+}
+
+// CheckSpend сравнивает агрегаты с живым расчетом по всем парам, кроме устаревших:
+// для них расхождение ожидаемо и исчезнет после пересчета.
+func (r *gormSubscriptionRepository) CheckSpend(ctx context.Context) ([]models.SpendMismatch, error) {
+	mismatches := []models.SpendMismatch{}
+	err := tenantTx(ctx, r.db, tenant.All, func(tx *gorm.DB) error {
+		for _, overlap := range overlapPolicies {
+			var found []models.SpendMismatch
+			all := activeInPeriod(tx.Model(&models.Subscription{}), &aggregateFrom, &aggregateTo)
+			err := tx.Raw(overlap.discountedRowsCTE()+`,
+				live AS (
+					SELECT tenant_id, user_id, service_name, month,
+						SUM(gross)::bigint AS gross,
+						SUM(discount)::bigint AS discount,
+						SUM(gross - discount)::bigint AS net
+					FROM discounted
+					GROUP BY tenant_id, user_id, service_name, month
+				),
+				stored AS (
+					SELECT tenant_id, user_id, service_name, month, gross, discount, net
+					FROM monthly_user_service_spend
+					WHERE overlap = ?
+				)
+				SELECT COALESCE(live.tenant_id, stored.tenant_id) AS tenant_id,
+					COALESCE(live.user_id, stored.user_id) AS user_id,
+					COALESCE(live.service_name, stored.service_name) AS service_name,
+					? AS overlap,
+					COALESCE(live.month, stored.month) AS month,
+					stored.gross AS stored_gross,
+					stored.discount AS stored_discount,
+					stored.net AS stored_net,
+					live.gross AS live_gross,
+					live.discount AS live_discount,
+					live.net AS live_net
+				FROM live
+				FULL OUTER JOIN stored
+					ON stored.tenant_id = live.tenant_id
+					AND stored.user_id = live.user_id
+					AND stored.service_name = live.service_name
+					AND stored.month = live.month
+				WHERE (live.gross, live.discount, live.net) IS DISTINCT FROM (stored.gross, stored.discount, stored.net)
+					AND (
+						COALESCE(live.tenant_id, stored.tenant_id),
+						COALESCE(live.user_id, stored.user_id),
+						COALESCE(live.service_name, stored.service_name)
+					) NOT IN (`+stalePairsSQL+`)
+				ORDER BY 1, 2, 3, 5
+			`, all, aggregateFrom, aggregateTo, string(overlap), string(overlap)).Scan(&found).Error
+			if err != nil {
+				return err
+			}
+			mismatches = append(mismatches, found...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"subscriptions-service/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AnalyticsRepository - агрегированные отчеты по подпискам всех пользователей арендатора
+type AnalyticsRepository interface {
+	PopularServices(ctx context.Context, start, end *time.Time, limit int) ([]models.ServicePopularity, error)
+	AverageSpend(ctx context.Context, start, end *time.Time) (*models.SpendAverage, []models.MonthlySpendAverage, error)
+	ChurnByMonth(ctx context.Context, start, end *time.Time) ([]models.MonthlyChurn, error)
+	PriceDistribution(ctx context.Context, serviceName *string, start, end *time.Time) ([]models.PriceDistribution, error)
+}
+
+type gormAnalyticsRepository struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsRepository(db *gorm.DB) AnalyticsRepository {
+	return &gormAnalyticsRepository{db: db}
+}
+
+// periodQuery - подписки арендатора, действовавшие в периоде. Отчеты строятся
+// только по данным арендатора запроса.
+func periodQuery(tx *gorm.DB, tenantID string, start, end *time.Time) *gorm.DB {
+	return activeInPeriod(tx.Model(&models.Subscription{}).Where("tenant_id = ?", tenantID), start, end)
+}
+
+func (r *gormAnalyticsRepository) PopularServices(ctx context.Context, start, end *time.Time, limit int) ([]models.ServicePopularity, error) {
+	services := []models.ServicePopularity{}
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		return periodQuery(tx, tenantID, start, end).
+			Select("service_name, COUNT(*) AS subscriptions, COUNT(DISTINCT user_id) AS users").
+			Group("service_name").
+			Order("users DESC, subscriptions DESC, service_name").
+			Limit(limit).
+			Scan(&services).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// AverageSpend считает расходы так же, как сумма подписок пользователя (политика max
+// и скидки), и делит их на число пользователей - за весь период и по месяцам.
+func (r *gormAnalyticsRepository) AverageSpend(ctx context.Context, start, end *time.Time) (*models.SpendAverage, []models.MonthlySpendAverage, error) {
+	var total models.SpendAverage
+	months := []models.MonthlySpendAverage{}
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		err := tx.Raw(OverlapMax.discountedRowsCTE()+`
+			SELECT COUNT(DISTINCT user_id) AS users,
+				COALESCE(SUM(gross - discount), 0)::bigint AS total,
+				COALESCE(SUM(gross - discount)::float8 / NULLIF(COUNT(DISTINCT user_id), 0), 0) AS average
+			FROM discounted
+		`, periodQuery(tx, tenantID, start, end), start, end).Scan(&total).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Raw(OverlapMax.discountedRowsCTE()+`
+			SELECT month,
+				COUNT(DISTINCT user_id) AS users,
+				SUM(gross - discount)::bigint AS total,
+				SUM(gross - discount)::float8 / COUNT(DISTINCT user_id) AS average
+			FROM discounted
+			GROUP BY month
+			ORDER BY month
+		`, periodQuery(tx, tenantID, start, end), start, end).Scan(&months).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &total, months, nil
+}
+
+// ChurnByMonth считает по месяцам начавшиеся, действующие и закончившиеся подписки.
+// Подписка считается закончившейся в месяце своей end_date. В отчет попадает каждый
+// месяц периода, даже без начал и окончаний; без start_date период начинается с первой
+// подписки арендатора, без end_date - заканчивается текущим месяцем.
+func (r *gormAnalyticsRepository) ChurnByMonth(ctx context.Context, start, end *time.Time) ([]models.MonthlyChurn, error) {
+	months := []models.MonthlyChurn{}
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		return tx.Raw(`
+			WITH bounds AS (
+				SELECT date_trunc('month', COALESCE(?, MIN(start_date)::timestamp)) AS first,
+					date_trunc('month', COALESCE(?, CURRENT_DATE::timestamp)) AS last
+				FROM subscriptions
+				WHERE tenant_id = ?
+			),
+			series AS (
+				SELECT generate_series(first, last, interval '1 month')::date AS month
+				FROM bounds
+			),
+			events AS (
+				SELECT date_trunc('month', start_date)::date AS month, 1 AS started, 0 AS ended
+				FROM subscriptions
+				WHERE tenant_id = ?
+				UNION ALL
+				SELECT date_trunc('month', end_date)::date AS month, 0 AS started, 1 AS ended
+				FROM subscriptions
+				WHERE tenant_id = ? AND end_date IS NOT NULL
+			),
+			per_month AS (
+				SELECT series.month,
+					COALESCE(SUM(events.started), 0)::bigint AS started,
+					COALESCE(SUM(events.ended), 0)::bigint AS ended
+				FROM series
+				LEFT JOIN events ON events.month = series.month
+				GROUP BY series.month
+			),
+			with_active AS (
+				SELECT per_month.*,
+					(
+						SELECT COUNT(*)
+						FROM subscriptions AS s
+						WHERE s.tenant_id = ?
+							AND date_trunc('month', s.start_date) <= per_month.month
+							AND (s.end_date IS NULL OR date_trunc('month', s.end_date) >= per_month.month)
+					) AS active
+				FROM per_month
+			)
+			SELECT month, started, active, ended,
+				COALESCE(ended::float8 / NULLIF(active, 0), 0) AS churn_rate
+			FROM with_active
+			ORDER BY month
+		`, start, end, tenantID, tenantID, tenantID, tenantID).Scan(&months).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return months, nil
+}
+
+func (r *gormAnalyticsRepository) PriceDistribution(ctx context.Context, serviceName *string, start, end *time.Time) ([]models.PriceDistribution, error) {
+	services := []models.PriceDistribution{}
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		q := periodQuery(tx, tenantID, start, end)
+		if serviceName != nil {
+			q = q.Where("service_name = ?", serviceName)
+		}
+
+		return q.
+			Select(`service_name,
+				COUNT(*) AS subscriptions,
+				MIN(price) AS min,
+				MAX(price) AS max,
+				AVG(price)::float8 AS average,
+				percentile_cont(0.25) WITHIN GROUP (ORDER BY price) AS p25,
+				percentile_cont(0.5) WITHIN GROUP (ORDER BY price) AS median,
+				percentile_cont(0.75) WITHIN GROUP (ORDER BY price) AS p75`).
+			Group("service_name").
+			Order("subscriptions DESC, service_name").
+			Scan(&services).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
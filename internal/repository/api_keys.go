@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/tenant"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	// RevokeAPIKey отзывает действующий ключ или возвращает gorm.ErrRecordNotFound
+	RevokeAPIKey(ctx context.Context, id uuid.UUID) error
+	FindAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	RecordAPIKeyUsage(ctx context.Context, id uuid.UUID, at time.Time, count int) error
+}
+
+type gormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &gormAPIKeyRepository{db: db}
+}
+
+// Ключи создаются и управляются в рамках арендатора запроса. Поиск по префиксу
+// не ограничен арендатором: он выполняется при аутентификации, до того как арендатор
+// известен, поэтому на api_keys нет политик RLS.
+
+func (r *gormAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+	key.TenantID = tenantID
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *gormAPIKeyRepository) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	var keys []models.APIKey
+	if err := r.db.WithContext(ctx).Where("tenant_id = ?", tenantID).Order("created_at, id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *gormAPIKeyRepository) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+	result := r.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("id = ? AND tenant_id = ? AND revoked_at IS NULL", id, tenantID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *gormAPIKeyRepository) FindAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.WithContext(ctx).First(&key, "prefix = ?", prefix).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *gormAPIKeyRepository) RecordAPIKeyUsage(ctx context.Context, id uuid.UUID, at time.Time, count int) error {
+	return r.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_used_at": at,
+			"usage_count":  gorm.Expr("usage_count + ?", count),
+		}).Error
+}
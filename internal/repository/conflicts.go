@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"subscriptions-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// normalizedName - SQL-выражение для названия сервиса без регистра, пробелов и знаков препинания
+func normalizedName(column string) string {
+	return "lower(regexp_replace(" + column + ", '[^[:alnum:]]+', '', 'g'))"
+}
+
+// similarNameThreshold - минимальная триграммная схожесть нормализованных названий,
+// при которой они считаются вероятными дублями
+const similarNameThreshold = 0.5
+
+type overlapPair struct {
+	FirstID  uuid.UUID
+	SecondID uuid.UUID
+}
+
+type similarNamePair struct {
+	FirstName  string
+	SecondName string
+}
+
+func (r *gormSubscriptionRepository) FindConflicts(ctx context.Context, userID uuid.UUID) ([]models.Conflict, error) {
+	var (
+		subs     []models.Subscription
+		overlaps []overlapPair
+		similar  []similarNamePair
+	)
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		if err := tx.Where("tenant_id = ? AND user_id = ?", tenantID, userID).Order("start_date, id").Find(&subs).Error; err != nil {
+			return err
+		}
+
+		err := tx.Raw(`
+			SELECT a.id AS first_id, b.id AS second_id
+			FROM subscriptions AS a
+			JOIN subscriptions AS b
+				ON b.tenant_id = a.tenant_id
+				AND b.user_id = a.user_id
+				AND (b.start_date, b.id) > (a.start_date, a.id)
+				AND `+normalizedName("b.service_name")+` = `+normalizedName("a.service_name")+`
+				AND b.start_date <= COALESCE(a.end_date, 'infinity'::date)
+				AND a.start_date <= COALESCE(b.end_date, 'infinity'::date)
+			WHERE a.tenant_id = ? AND a.user_id = ?
+				AND (a.end_date IS NULL OR a.end_date >= a.start_date)
+				AND (b.end_date IS NULL OR b.end_date >= b.start_date)
+			ORDER BY a.start_date, b.start_date
+		`, tenantID, userID).Scan(&overlaps).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Raw(`
+			WITH names AS (
+				SELECT DISTINCT service_name, `+normalizedName("service_name")+` AS normalized
+				FROM subscriptions
+				WHERE tenant_id = ? AND user_id = ?
+			)
+			SELECT a.service_name AS first_name, b.service_name AS second_name
+			FROM names AS a
+			JOIN names AS b
+				ON a.service_name < b.service_name
+				AND (a.normalized = b.normalized OR similarity(a.normalized, b.normalized) >= ?)
+			ORDER BY a.service_name, b.service_name
+		`, tenantID, userID, similarNameThreshold).Scan(&similar).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]models.Subscription, len(subs))
+	byName := make(map[string][]models.Subscription)
+	for _, s := range subs {
+		byID[s.ID] = s
+		byName[s.ServiceName] = append(byName[s.ServiceName], s)
+	}
+
+	conflicts := []models.Conflict{}
+
+	for _, s := range subs {
+		if s.EndDate != nil && time.Time(*s.EndDate).Before(time.Time(s.StartDate)) {
+			conflicts = append(conflicts, invalidPeriodConflict(s))
+		}
+	}
+	overlapping := make(map[[2]string]bool)
+	for _, p := range overlaps {
+		first, second := byID[p.FirstID], byID[p.SecondID]
+		conflicts = append(conflicts, overlapConflict(first, second))
+		overlapping[namePair(first.ServiceName, second.ServiceName)] = true
+	}
+	for _, p := range similar {
+		// у пересекающихся записей уже есть исправление из overlap
+		if overlapping[namePair(p.FirstName, p.SecondName)] {
+			continue
+		}
+		conflicts = append(conflicts, similarNameConflict(byName[p.FirstName], byName[p.SecondName]))
+	}
+
+	return conflicts, nil
+}
+
+func invalidPeriodConflict(s models.Subscription) models.Conflict {
+	fixed := s
+	start := models.MonthYearDate(time.Time(*s.EndDate))
+	end := s.StartDate
+	fixed.StartDate = start
+	fixed.EndDate = &end
+
+	return models.Conflict{
+		Kind:            models.ConflictInvalidPeriod,
+		SubscriptionIDs: []uuid.UUID{s.ID},
+		Description:     "end_date раньше start_date",
+		Fix: models.ConflictFix{
+			Action: models.FixSwapDates,
+			Update: []models.Subscription{fixed},
+		},
+	}
+}
+
+// overlapConflict предлагает исправление для двух пересекающихся записей одного сервиса,
+// first начинается не позже second. Записи с одинаковой ценой объединяются. Иначе у более
+// дешевой записи остаются только месяцы вне периода дорогой: она обрезается, делится на
+// две части или удаляется, если целиком вложена. Так исправление не меняет сумму по
+// политике max, а дорогая запись никогда не удаляется.
+func overlapConflict(first, second models.Subscription) models.Conflict {
+	conflict := models.Conflict{
+		Kind:            models.ConflictOverlap,
+		SubscriptionIDs: []uuid.UUID{first.ID, second.ID},
+		Description:     fmt.Sprintf("периоды подписок на %q пересекаются", first.ServiceName),
+	}
+
+	if first.Price == second.Price {
+		merged := first
+		merged.EndDate = laterEndDate(first.EndDate, second.EndDate)
+		conflict.Fix = models.ConflictFix{
+			Action: models.FixMerge,
+			Update: []models.Subscription{merged},
+			Delete: []uuid.UUID{second.ID},
+		}
+		return conflict
+	}
+
+	cheap, expensive := first, second
+	if first.Price > second.Price {
+		cheap, expensive = second, first
+	}
+	// месяцы дешевой записи до начала и после окончания дорогой
+	head := cheap
+	head.EndDate = shiftMonth(expensive.StartDate, -1)
+	tail := cheap
+	hasHead := time.Time(cheap.StartDate).Before(time.Time(expensive.StartDate))
+	hasTail := endsBefore(expensive.EndDate, cheap.EndDate)
+	if hasTail {
+		tail.StartDate = *shiftMonth(*expensive.EndDate, 1)
+	}
+
+	switch {
+	case hasHead && hasTail:
+		tail.ID = uuid.Nil
+		conflict.Fix = models.ConflictFix{
+			Action: models.FixSplit,
+			Update: []models.Subscription{head},
+			Create: []models.Subscription{tail},
+		}
+	case hasHead:
+		conflict.Fix = models.ConflictFix{
+			Action: models.FixTrim,
+			Update: []models.Subscription{head},
+		}
+	case hasTail:
+		conflict.Fix = models.ConflictFix{
+			Action: models.FixTrim,
+			Update: []models.Subscription{tail},
+		}
+	default:
+		conflict.Fix = models.ConflictFix{
+			Action: models.FixRemove,
+			Delete: []uuid.UUID{cheap.ID},
+		}
+	}
+	return conflict
+}
+
+// similarNameConflict предлагает переименовать записи с менее распространенным
+// вариантом названия в более распространенный
+func similarNameConflict(first, second []models.Subscription) models.Conflict {
+	canonical, renamed := first, second
+	if len(second) > len(first) {
+		canonical, renamed = second, first
+	}
+
+	ids := make([]uuid.UUID, 0, len(first)+len(second))
+	for _, s := range append(append([]models.Subscription{}, first...), second...) {
+		ids = append(ids, s.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+
+	update := make([]models.Subscription, 0, len(renamed))
+	for _, s := range renamed {
+		s.ServiceName = canonical[0].ServiceName
+		update = append(update, s)
+	}
+
+	return models.Conflict{
+		Kind:            models.ConflictSimilarName,
+		SubscriptionIDs: ids,
+		Description:     fmt.Sprintf("названия %q и %q похожи на один сервис", first[0].ServiceName, second[0].ServiceName),
+		Fix: models.ConflictFix{
+			Action: models.FixRename,
+			Update: update,
+		},
+	}
+}
+
+// endsBefore сообщает, заканчивается ли период с концом a строго раньше периода с концом b.
+// nil означает бессрочную подписку.
+func endsBefore(a, b *models.MonthYearDate) bool {
+	if a == nil {
+		return false
+	}
+	if b == nil {
+		return true
+	}
+	return time.Time(*a).Before(time.Time(*b))
+}
+
+func laterEndDate(a, b *models.MonthYearDate) *models.MonthYearDate {
+	if endsBefore(a, b) {
+		return b
+	}
+	return a
+}
+
+// shiftMonth возвращает месяц m, сдвинутый на months
+func shiftMonth(m models.MonthYearDate, months int) *models.MonthYearDate {
+	shifted := models.MonthYearDate(time.Time(m).AddDate(0, months, 0))
+	return &shifted
+}
+
+// namePair - ключ пары названий, не зависящий от их порядка
+func namePair(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
@@ -0,0 +1,390 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/tenant"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// memorySubscriptionRepository хранит подписки и скидки в памяти процесса - для тестов
+// и демонстраций без Postgres. Повторяет поведение gormSubscriptionRepository, включая
+// помесячный расчет сумм с политиками пересечений и скидками, но считает все на лету,
+// без агрегатов monthly_user_service_spend. Ошибки "не найдено" - gorm.ErrRecordNotFound,
+// как у основной реализации, чтобы обработчики не различали хранилища.
+type memorySubscriptionRepository struct {
+	mu        sync.RWMutex
+	subs      map[uuid.UUID]models.Subscription
+	discounts map[uuid.UUID]models.Discount
+	// now заменяет CURRENT_DATE: бессрочные подписки и пустой конец периода доходят до текущего месяца
+	now func() time.Time
+}
+
+func NewMemorySubscriptionRepository() SubscriptionRepository {
+	return &memorySubscriptionRepository{
+		subs:      make(map[uuid.UUID]models.Subscription),
+		discounts: make(map[uuid.UUID]models.Discount),
+		now:       time.Now,
+	}
+}
+
+func (r *memorySubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if sub.ID == uuid.Nil {
+		sub.ID = uuid.New()
+	}
+	if _, exists := r.subs[sub.ID]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	now := r.now()
+	if sub.CreatedAt.IsZero() {
+		sub.CreatedAt = now
+	}
+	sub.UpdatedAt = now
+	sub.TenantID = tenantID
+	r.subs[sub.ID] = copySubscription(*sub)
+	return nil
+}
+
+func (r *memorySubscriptionRepository) Get(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, ok := r.subs[id]
+	if !ok || sub.TenantID != tenantID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	sub = copySubscription(sub)
+	return &sub, nil
+}
+
+// Update, как Updates в GORM, меняет только непустые поля s
+func (r *memorySubscriptionRepository) Update(ctx context.Context, id uuid.UUID, s *models.Subscription) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subs[id]
+	if !ok || sub.TenantID != tenantID {
+		return gorm.ErrRecordNotFound
+	}
+	if s.ServiceName != "" {
+		sub.ServiceName = s.ServiceName
+	}
+	if s.Price != 0 {
+		sub.Price = s.Price
+	}
+	if s.UserID != uuid.Nil {
+		sub.UserID = s.UserID
+	}
+	if !time.Time(s.StartDate).IsZero() {
+		sub.StartDate = s.StartDate
+	}
+	if s.EndDate != nil {
+		end := *s.EndDate
+		sub.EndDate = &end
+	}
+	if !s.CreatedAt.IsZero() {
+		sub.CreatedAt = s.CreatedAt
+	}
+	s.UpdatedAt = r.now()
+	sub.UpdatedAt = s.UpdatedAt
+	r.subs[id] = sub
+	return nil
+}
+
+func (r *memorySubscriptionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subs[id]
+	if !ok || sub.TenantID != tenantID {
+		return nil
+	}
+	for discountID, d := range r.discounts {
+		if d.SubscriptionID == id {
+			delete(r.discounts, discountID)
+		}
+	}
+	delete(r.subs, id)
+	return nil
+}
+
+// filter - аналог baseQuery: подписки пользователя арендатора, действовавшие в периоде
+func (r *memorySubscriptionRepository) filter(tenantID string, userID uuid.UUID, serviceName *string, start, end *time.Time) []models.Subscription {
+	var subs []models.Subscription
+	for _, s := range r.subs {
+		if s.TenantID != tenantID || s.UserID != userID {
+			continue
+		}
+		if serviceName != nil && s.ServiceName != *serviceName {
+			continue
+		}
+		if start != nil && s.EndDate != nil && time.Time(*s.EndDate).Before(*start) {
+			continue
+		}
+		if end != nil && time.Time(s.StartDate).After(*end) {
+			continue
+		}
+		subs = append(subs, copySubscription(s))
+	}
+	return subs
+}
+
+func (r *memorySubscriptionRepository) ListByUser(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, limit, offset int) ([]models.Subscription, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	r.mu.RLock()
+	subs := r.filter(tenantID, userID, serviceName, start, end)
+	r.mu.RUnlock()
+
+	sort.Slice(subs, func(i, j int) bool {
+		a, b := subs[i], subs[j]
+		if !time.Time(a.StartDate).Equal(time.Time(b.StartDate)) {
+			return time.Time(a.StartDate).Before(time.Time(b.StartDate))
+		}
+		if a.ServiceName != b.ServiceName {
+			return a.ServiceName < b.ServiceName
+		}
+		return bytes.Compare(a.ID[:], b.ID[:]) < 0
+	})
+
+	if offset > len(subs) {
+		offset = len(subs)
+	}
+	if offset > 0 {
+		subs = subs[offset:]
+	}
+	if limit >= 0 && limit < len(subs) {
+		subs = subs[:limit]
+	}
+	return append([]models.Subscription{}, subs...), nil
+}
+
+func (r *memorySubscriptionRepository) ListByUsers(ctx context.Context, userIDs []uuid.UUID, serviceName *string, start, end *time.Time) ([]models.Subscription, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	r.mu.RLock()
+	subs := r.filterUsers(tenantID, userIDs, serviceName, start, end)
+	r.mu.RUnlock()
+
+	sortByUser(subs)
+	return subs, nil
+}
+
+// filterUsers - filter для нескольких пользователей
+func (r *memorySubscriptionRepository) filterUsers(tenantID string, userIDs []uuid.UUID, serviceName *string, start, end *time.Time) []models.Subscription {
+	subs := []models.Subscription{}
+	seen := make(map[uuid.UUID]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		subs = append(subs, r.filter(tenantID, userID, serviceName, start, end)...)
+	}
+	return subs
+}
+
+func (r *memorySubscriptionRepository) SumByUserAndService(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) (int, error) {
+	months, err := r.MonthlyBreakdown(ctx, userID, serviceName, start, end, overlap)
+	if err != nil {
+		return 0, err
+	}
+
+	sum := 0
+	for _, m := range months {
+		sum += m.Net
+	}
+	return sum, nil
+}
+
+func (r *memorySubscriptionRepository) MonthlyBreakdown(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.MonthlySpend, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := r.filter(tenantID, userID, serviceName, start, end)
+	return monthlySpend(rankMonths(subs, start, end, r.now(), overlap), overlap, r.discountsBySubscription()), nil
+}
+
+func (r *memorySubscriptionRepository) ServiceMonthlySpend(ctx context.Context, userIDs []uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.ServiceMonthSpend, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := r.filterUsers(tenantID, userIDs, serviceName, start, end)
+	return usersServiceMonthlySpend(subs, start, end, r.now(), overlap, r.discountsBySubscription()), nil
+}
+
+func (r *memorySubscriptionRepository) CollapsedOverlaps(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.CollapsedOverlap, error) {
+	collapsed := []models.CollapsedOverlap{}
+	if !overlap.collapses() {
+		return collapsed, nil
+	}
+
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := r.filter(tenantID, userID, serviceName, start, end)
+	return collapsedOverlaps(rankMonths(subs, start, end, r.now(), overlap)), nil
+}
+
+func (r *memorySubscriptionRepository) FindConflicts(ctx context.Context, userID uuid.UUID) ([]models.Conflict, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	r.mu.RLock()
+	subs := r.filter(tenantID, userID, nil, nil, nil)
+	r.mu.RUnlock()
+
+	return subscriptionConflicts(subs), nil
+}
+
+// discountsBySubscription группирует скидки по подпискам для расчета сумм
+func (r *memorySubscriptionRepository) discountsBySubscription() map[uuid.UUID][]models.Discount {
+	bySub := make(map[uuid.UUID][]models.Discount)
+	for _, d := range r.discounts {
+		bySub[d.SubscriptionID] = append(bySub[d.SubscriptionID], d)
+	}
+	return bySub
+}
+
+func (r *memorySubscriptionRepository) CreateDiscount(ctx context.Context, d *models.Discount) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if sub, ok := r.subs[d.SubscriptionID]; !ok || sub.TenantID != tenantID {
+		return gorm.ErrRecordNotFound
+	}
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	if _, exists := r.discounts[d.ID]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = r.now()
+	}
+	r.discounts[d.ID] = copyDiscount(*d)
+	return nil
+}
+
+func (r *memorySubscriptionRepository) ListDiscounts(ctx context.Context, subscriptionID uuid.UUID) ([]models.Discount, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	discounts := []models.Discount{}
+	if sub, ok := r.subs[subscriptionID]; !ok || sub.TenantID != tenantID {
+		return discounts, nil
+	}
+	for _, d := range r.discounts {
+		if d.SubscriptionID == subscriptionID {
+			discounts = append(discounts, copyDiscount(d))
+		}
+	}
+	sort.Slice(discounts, func(i, j int) bool {
+		a, b := time.Time(discounts[i].StartDate), time.Time(discounts[j].StartDate)
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return discounts[i].CreatedAt.Before(discounts[j].CreatedAt)
+	})
+	return discounts, nil
+}
+
+func (r *memorySubscriptionRepository) DeleteDiscount(ctx context.Context, subscriptionID, discountID uuid.UUID) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.discounts[discountID]
+	if !ok || d.SubscriptionID != subscriptionID {
+		return gorm.ErrRecordNotFound
+	}
+	if sub, ok := r.subs[subscriptionID]; !ok || sub.TenantID != tenantID {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.discounts, discountID)
+	return nil
+}
+
+// copySubscription копирует EndDate, чтобы вызывающий код не менял хранимую запись
+func copySubscription(s models.Subscription) models.Subscription {
+	if s.EndDate != nil {
+		end := *s.EndDate
+		s.EndDate = &end
+	}
+	return s
+}
+
+func copyDiscount(d models.Discount) models.Discount {
+	if d.EndDate != nil {
+		end := *d.EndDate
+		d.EndDate = &end
+	}
+	return d
+}
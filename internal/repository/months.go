@@ -0,0 +1,336 @@
+package repository
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"subscriptions-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Расчет сумм и поиск конфликтов на стороне Go - для хранилищ без generate_series,
+// date_trunc и pg_trgm. Повторяет rankedRowsCTE, discountedRowsCTE и запросы FindConflicts.
+
+// monthRow - подписка в одном месяце, строка expanded_rows из rankedRowsCTE
+type monthRow struct {
+	sub   models.Subscription
+	month time.Time
+}
+
+// rankMonths разворачивает подписки помесячно в границах периода и группирует
+// записи одного сервиса в каждом месяце в порядке политики overlap: первая запись
+// группы учитывается, остальные схлопываются, если политика это предполагает.
+// today заменяет CURRENT_DATE для бессрочных подписок и пустого конца периода.
+func rankMonths(subs []models.Subscription, start, end *time.Time, today time.Time, overlap OverlapPolicy) [][]monthRow {
+	today = monthStart(today)
+	from, to := monthStart(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)), today
+	if start != nil {
+		from = monthStart(*start)
+	}
+	if end != nil {
+		to = monthStart(*end)
+	}
+
+	type groupKey struct {
+		month       time.Time
+		serviceName string
+	}
+	groups := make(map[groupKey][]monthRow)
+	for _, s := range subs {
+		last := today
+		if s.EndDate != nil {
+			last = monthStart(time.Time(*s.EndDate))
+		}
+		for m := monthStart(time.Time(s.StartDate)); !m.After(last); m = m.AddDate(0, 1, 0) {
+			if m.Before(from) || m.After(to) {
+				continue
+			}
+			key := groupKey{month: m, serviceName: s.ServiceName}
+			groups[key] = append(groups[key], monthRow{sub: s, month: m})
+		}
+	}
+
+	ranked := make([][]monthRow, 0, len(groups))
+	for _, rows := range groups {
+		sort.Slice(rows, func(i, j int) bool {
+			return overlap.ranksBefore(rows[i].sub, rows[j].sub)
+		})
+		ranked = append(ranked, rows)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i][0], ranked[j][0]
+		if !a.month.Equal(b.month) {
+			return a.month.Before(b.month)
+		}
+		return a.sub.ServiceName < b.sub.ServiceName
+	})
+	return ranked
+}
+
+// ranksBefore - порядок rankOrder для записей в памяти
+func (p OverlapPolicy) ranksBefore(a, b models.Subscription) bool {
+	aStart, bStart := time.Time(a.StartDate), time.Time(b.StartDate)
+	if p != OverlapLatest && a.Price != b.Price {
+		return a.Price > b.Price
+	}
+	if !aStart.Equal(bStart) {
+		return aStart.After(bStart)
+	}
+	if p == OverlapLatest && !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return bytes.Compare(a.ID[:], b.ID[:]) < 0
+}
+
+// monthlySpend суммирует учтенные политикой записи по месяцам, discounts - скидки по подпискам
+func monthlySpend(ranked [][]monthRow, overlap OverlapPolicy, discounts map[uuid.UUID][]models.Discount) []models.MonthlySpend {
+	months := []models.MonthlySpend{}
+	for _, rows := range ranked {
+		if overlap.collapses() {
+			rows = rows[:1]
+		}
+
+		if len(months) == 0 || !time.Time(months[len(months)-1].Month).Equal(rows[0].month) {
+			months = append(months, models.MonthlySpend{Month: models.MonthYearDate(rows[0].month)})
+		}
+		spend := &months[len(months)-1]
+		for _, row := range rows {
+			discount := monthDiscount(row, discounts[row.sub.ID])
+			spend.Gross += row.sub.Price
+			spend.Discount += discount
+			spend.Net += row.sub.Price - discount
+		}
+	}
+	return months
+}
+
+// serviceMonthlySpend - monthlySpend без суммирования по сервисам: строка на каждую группу rankMonths
+func serviceMonthlySpend(ranked [][]monthRow, overlap OverlapPolicy, discounts map[uuid.UUID][]models.Discount) []models.ServiceMonthSpend {
+	spend := make([]models.ServiceMonthSpend, 0, len(ranked))
+	for _, rows := range ranked {
+		if overlap.collapses() {
+			rows = rows[:1]
+		}
+
+		s := models.ServiceMonthSpend{
+			UserID:      rows[0].sub.UserID,
+			ServiceName: rows[0].sub.ServiceName,
+			Month:       models.MonthYearDate(rows[0].month),
+		}
+		for _, row := range rows {
+			discount := monthDiscount(row, discounts[row.sub.ID])
+			s.Gross += row.sub.Price
+			s.Discount += discount
+			s.Net += row.sub.Price - discount
+		}
+		spend = append(spend, s)
+	}
+	return spend
+}
+
+// usersServiceMonthlySpend считает serviceMonthlySpend для подписок нескольких пользователей:
+// rankMonths группирует записи только по месяцу и сервису, поэтому пользователи считаются по отдельности
+func usersServiceMonthlySpend(subs []models.Subscription, start, end *time.Time, today time.Time, overlap OverlapPolicy, discounts map[uuid.UUID][]models.Discount) []models.ServiceMonthSpend {
+	byUser := make(map[uuid.UUID][]models.Subscription)
+	var userIDs []uuid.UUID
+	for _, s := range subs {
+		if _, ok := byUser[s.UserID]; !ok {
+			userIDs = append(userIDs, s.UserID)
+		}
+		byUser[s.UserID] = append(byUser[s.UserID], s)
+	}
+	sort.Slice(userIDs, func(i, j int) bool {
+		return bytes.Compare(userIDs[i][:], userIDs[j][:]) < 0
+	})
+
+	spend := []models.ServiceMonthSpend{}
+	for _, userID := range userIDs {
+		ranked := rankMonths(byUser[userID], start, end, today, overlap)
+		spend = append(spend, serviceMonthlySpend(ranked, overlap, discounts)...)
+	}
+	return spend
+}
+
+// sortByUser упорядочивает подписки как ListByUsers: по пользователю, дате начала, сервису и id
+func sortByUser(subs []models.Subscription) {
+	sort.Slice(subs, func(i, j int) bool {
+		a, b := subs[i], subs[j]
+		if c := bytes.Compare(a.UserID[:], b.UserID[:]); c != 0 {
+			return c < 0
+		}
+		if !time.Time(a.StartDate).Equal(time.Time(b.StartDate)) {
+			return time.Time(a.StartDate).Before(time.Time(b.StartDate))
+		}
+		if a.ServiceName != b.ServiceName {
+			return a.ServiceName < b.ServiceName
+		}
+		return bytes.Compare(a.ID[:], b.ID[:]) < 0
+	})
+}
+
+// monthDiscount - сумма скидок на подписку в месяце, не больше ее цены, как в discountedRowsCTE
+func monthDiscount(row monthRow, discounts []models.Discount) int {
+	total := 0
+	for _, d := range discounts {
+		if row.month.Before(monthStart(time.Time(d.StartDate))) {
+			continue
+		}
+		if d.EndDate != nil && row.month.After(monthStart(time.Time(*d.EndDate))) {
+			continue
+		}
+		switch d.Type {
+		case models.DiscountPercent:
+			total += row.sub.Price * d.Value / 100
+		default:
+			total += d.Value
+		}
+	}
+	return min(total, row.sub.Price)
+}
+
+// collapsedOverlaps собирает схлопнутые записи каждой группы rankMonths по парам
+// учтенная/исключенная запись
+func collapsedOverlaps(ranked [][]monthRow) []models.CollapsedOverlap {
+	type pairKey struct {
+		keptID, collapsedID uuid.UUID
+	}
+	collapsed := []models.CollapsedOverlap{}
+	index := make(map[pairKey]int)
+	for _, rows := range ranked {
+		kept := rows[0]
+		for _, dropped := range rows[1:] {
+			key := pairKey{keptID: kept.sub.ID, collapsedID: dropped.sub.ID}
+			month := models.MonthYearDate(dropped.month)
+			if i, ok := index[key]; ok {
+				collapsed[i].EndMonth = month
+				collapsed[i].Months++
+				continue
+			}
+			index[key] = len(collapsed)
+			collapsed = append(collapsed, models.CollapsedOverlap{
+				ServiceName: dropped.sub.ServiceName,
+				KeptID:      kept.sub.ID,
+				CollapsedID: dropped.sub.ID,
+				StartMonth:  month,
+				EndMonth:    month,
+				Months:      1,
+			})
+		}
+	}
+
+	sort.SliceStable(collapsed, func(i, j int) bool {
+		a, b := collapsed[i], collapsed[j]
+		if !time.Time(a.StartMonth).Equal(time.Time(b.StartMonth)) {
+			return time.Time(a.StartMonth).Before(time.Time(b.StartMonth))
+		}
+		return a.ServiceName < b.ServiceName
+	})
+	return collapsed
+}
+
+// subscriptionConflicts ищет конфликты среди всех подписок одного пользователя
+func subscriptionConflicts(subs []models.Subscription) []models.Conflict {
+	sort.Slice(subs, func(i, j int) bool {
+		a, b := time.Time(subs[i].StartDate), time.Time(subs[j].StartDate)
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return bytes.Compare(subs[i].ID[:], subs[j].ID[:]) < 0
+	})
+
+	byName := make(map[string][]models.Subscription)
+	for _, s := range subs {
+		byName[s.ServiceName] = append(byName[s.ServiceName], s)
+	}
+
+	conflicts := []models.Conflict{}
+
+	for _, s := range subs {
+		if !validPeriod(s) {
+			conflicts = append(conflicts, invalidPeriodConflict(s))
+		}
+	}
+
+	// subs упорядочены по (start_date, id), поэтому b всегда идет после a
+	overlapping := make(map[[2]string]bool)
+	for i, a := range subs {
+		for _, b := range subs[i+1:] {
+			if validPeriod(a) && validPeriod(b) &&
+				normalizeName(a.ServiceName) == normalizeName(b.ServiceName) &&
+				!endsBefore(a.EndDate, &b.StartDate) && !endsBefore(b.EndDate, &a.StartDate) {
+				conflicts = append(conflicts, overlapConflict(a, b))
+				overlapping[namePair(a.ServiceName, b.ServiceName)] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			// у пересекающихся записей уже есть исправление из overlap
+			if overlapping[namePair(a, b)] {
+				continue
+			}
+			na, nb := normalizeName(a), normalizeName(b)
+			if na == nb || trigramSimilarity(na, nb) >= similarNameThreshold {
+				conflicts = append(conflicts, similarNameConflict(byName[a], byName[b]))
+			}
+		}
+	}
+
+	return conflicts
+}
+
+func validPeriod(s models.Subscription) bool {
+	return s.EndDate == nil || !time.Time(*s.EndDate).Before(time.Time(s.StartDate))
+}
+
+// normalizeName - normalizedName для Go: название без регистра, пробелов и знаков препинания
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// trigramSimilarity повторяет similarity из pg_trgm для одного слова: слово дополняется
+// двумя пробелами в начале и одним в конце, схожесть - доля общих триграмм в объединении
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+func trigrams(word string) map[string]struct{} {
+	set := make(map[string]struct{})
+	if word == "" {
+		return set
+	}
+	padded := []rune("  " + word + " ")
+	for i := 0; i+3 <= len(padded); i++ {
+		set[string(padded[i:i+3])] = struct{}{}
+	}
+	return set
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,99 @@
+package repository
+
+import "fmt"
+
+// OverlapPolicy определяет, как считать записи одного сервиса,
+// периоды которых пересекаются в одном месяце.
+type OverlapPolicy string
+
+const (
+	// OverlapMax - учитывается только запись с максимальной ценой
+	OverlapMax OverlapPolicy = "max"
+	// OverlapSum - учитываются все записи (например, несколько аккаунтов в одном сервисе)
+	OverlapSum OverlapPolicy = "sum"
+	// OverlapLatest - учитывается запись, начавшаяся позже остальных
+	OverlapLatest OverlapPolicy = "latest"
+)
+
+func ParseOverlapPolicy(v string) (OverlapPolicy, error) {
+	switch p := OverlapPolicy(v); p {
+	case "":
+		return OverlapMax, nil
+	case OverlapMax, OverlapSum, OverlapLatest:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid overlap policy %q", v)
+	}
+}
+
+// collapses сообщает, схлопывает ли политика пересекающиеся записи в одну
+func (p OverlapPolicy) collapses() bool {
+	return p != OverlapSum
+}
+
+// rankOrder - порядок записей внутри месяца и сервиса, первая запись остается в расчете
+func (p OverlapPolicy) rankOrder() string {
+	switch p {
+	case OverlapLatest:
+		return "start_date DESC, created_at DESC, id"
+	default:
+		return "price DESC, start_date DESC, id"
+	}
+}
+
+// rankedRowsCTE разворачивает подписки помесячно и ранжирует записи одного сервиса
+// у одного пользователя в каждом месяце согласно политике. Ожидает параметры: подзапрос с подписками, начало и конец периода.
+func (p OverlapPolicy) rankedRowsCTE() string {
+	return `
+		WITH expanded_rows AS (
+			SELECT filtered_subs.id,
+				filtered_subs.tenant_id,
+				filtered_subs.user_id,
+				filtered_subs.service_name,
+				filtered_subs.price,
+				filtered_subs.start_date,
+				filtered_subs.created_at,
+				date_trunc('month', months.month)::date AS month
+			FROM (?) AS filtered_subs
+			CROSS JOIN LATERAL generate_series(
+				date_trunc('month', filtered_subs.start_date),
+				date_trunc('month', COALESCE(filtered_subs.end_date, CURRENT_DATE)),
+				interval '1 month'
+			) AS months(month)
+			WHERE months.month BETWEEN
+				date_trunc('month', COALESCE(?, '2000-01-01'::timestamp)) AND
+				date_trunc('month', COALESCE(?, CURRENT_DATE))
+		),
+		ranked_rows AS (
+			SELECT expanded_rows.*,
+				ROW_NUMBER() OVER (PARTITION BY tenant_id, user_id, month, service_name ORDER BY ` + p.rankOrder() + `) AS overlap_rank
+			FROM expanded_rows
+		)`
+}
+
+// discountedRowsCTE дополняет rankedRowsCTE строками discounted: по одной на каждую
+// учтенную политикой запись и месяц, с ценой до скидок и размером скидки.
+func (p OverlapPolicy) discountedRowsCTE() string {
+	keptRows := "SELECT id, tenant_id, user_id, service_name, price, month FROM ranked_rows"
+	if p.collapses() {
+		keptRows += " WHERE overlap_rank = 1"
+	}
+
+	return p.rankedRowsCTE() + `,
+		kept_rows AS (` + keptRows + `),
+		discounted AS (
+			SELECT tenant_id,
+				user_id,
+				service_name,
+				month,
+				price AS gross,
+				LEAST(price, COALESCE((
+					SELECT SUM(CASE d.type WHEN 'percent' THEN kept_rows.price * d.value / 100 ELSE d.value END)
+					FROM subscription_discounts AS d
+					WHERE d.subscription_id = kept_rows.id
+						AND kept_rows.month >= date_trunc('month', d.start_date)
+						AND (d.end_date IS NULL OR kept_rows.month <= date_trunc('month', d.end_date))
+				), 0)::int) AS discount
+			FROM kept_rows
+		)`
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/replica"
+	"subscriptions-service/internal/tenant"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,8 +17,23 @@ type SubscriptionRepository interface {
 	Get(ctx context.Context, id uuid.UUID) (*models.Subscription, error)
 	Update(ctx context.Context, id uuid.UUID, s *models.Subscription) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	// ListByUser возвращает страницу подписок пользователя, упорядоченных по дате начала,
+	// сервису и id, чтобы страницы не пересекались и не пропускали записи
 	ListByUser(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, limit, offset int) ([]models.Subscription, error)
-	SumByUserAndService(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time) (int, error)
+	// ListByUsers возвращает подписки нескольких пользователей одним запросом,
+	// упорядоченные по пользователю, дате начала и сервису
+	ListByUsers(ctx context.Context, userIDs []uuid.UUID, serviceName *string, start, end *time.Time) ([]models.Subscription, error)
+	SumByUserAndService(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) (int, error)
+	MonthlyBreakdown(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.MonthlySpend, error)
+	// ServiceMonthlySpend возвращает помесячные расходы нескольких пользователей в разбивке
+	// по сервисам, упорядоченные по пользователю, месяцу и сервису
+	ServiceMonthlySpend(ctx context.Context, userIDs []uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.ServiceMonthSpend, error)
+	CollapsedOverlaps(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.CollapsedOverlap, error)
+	FindConflicts(ctx context.Context, userID uuid.UUID) ([]models.Conflict, error)
+
+	CreateDiscount(ctx context.Context, d *models.Discount) error
+	ListDiscounts(ctx context.Context, subscriptionID uuid.UUID) ([]models.Discount, error)
+	DeleteDiscount(ctx context.Context, subscriptionID, discountID uuid.UUID) error
 }
 
 type gormSubscriptionRepository struct {
@@ -28,39 +45,72 @@ func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
 }
 
 func (r *gormSubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) error {
-	return r.db.WithContext(ctx).Create(sub).Error
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		sub.TenantID = tenantID
+		return tx.Create(sub).Error
+	})
 }
 
 func (r *gormSubscriptionRepository) Get(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
 	var sub models.Subscription
-	if err := r.db.WithContext(ctx).First(&sub, "id = ?", id).Error; err != nil {
+	err := withTenantRead(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		return tx.First(&sub, "id = ? AND tenant_id = ?", id, tenantID).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &sub, nil
 }
 
 func (r *gormSubscriptionRepository) Update(ctx context.Context, id uuid.UUID, s *models.Subscription) error {
-	result := r.db.WithContext(ctx).
-		Model(&models.Subscription{}).
-		Where("id = ?", id).
-		Omit("id").
-		Updates(s)
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		result := tx.
+			Model(&models.Subscription{}).
+			Where("id = ? AND tenant_id = ?", id, tenantID).
+			Omit("id", "tenant_id").
+			Updates(s)
 
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
-	}
-	return result.Error
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return result.Error
+	})
 }
 
 func (r *gormSubscriptionRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Subscription{}, "id = ?", id).Error
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		if err := tenantDiscounts(tx, tenantID, id).Delete(&models.Discount{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Subscription{}, "id = ? AND tenant_id = ?", id, tenantID).Error
+	})
+}
+
+func (r *gormSubscriptionRepository) baseQuery(tx *gorm.DB, tenantID string, userID uuid.UUID, serviceName *string, start, end *time.Time) *gorm.DB {
+	q := tx.Model(&models.Subscription{}).Where("tenant_id = ? AND user_id = ?", tenantID, userID)
+	if serviceName != nil {
+		q = q.Where("service_name = ?", serviceName)
+	}
+	return activeInPeriod(q, start, end)
 }
 
-func (r *gormSubscriptionRepository) baseQuery(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time) *gorm.DB {
-	q := r.db.WithContext(ctx).Model(&models.Subscription{}).Where("user_id = ?", userID)
+// usersQuery - baseQuery для нескольких пользователей
+func (r *gormSubscriptionRepository) usersQuery(tx *gorm.DB, tenantID string, userIDs []uuid.UUID, serviceName *string, start, end *time.Time) *gorm.DB {
+	q := tx.Model(&models.Subscription{}).Where("tenant_id = ? AND user_id IN ?", tenantID, userIDs)
 	if serviceName != nil {
 		q = q.Where("service_name = ?", serviceName)
 	}
+	return activeInPeriod(q, start, end)
+}
+
+// tenantDiscounts - скидки подписки subscriptionID, если она принадлежит арендатору
+func tenantDiscounts(tx *gorm.DB, tenantID string, subscriptionID uuid.UUID) *gorm.DB {
+	return tx.Where("subscription_id = ? AND subscription_id IN (?)", subscriptionID,
+		tx.Session(&gorm.Session{NewDB: true}).Model(&models.Subscription{}).Select("id").Where("tenant_id = ?", tenantID))
+}
+
+// activeInPeriod оставляет подписки, действовавшие хотя бы один месяц периода
+func activeInPeriod(q *gorm.DB, start, end *time.Time) *gorm.DB {
 	if start != nil {
 		q = q.Where("end_date >= ? OR end_date IS NULL", start)
 	}
@@ -72,44 +122,210 @@ func (r *gormSubscriptionRepository) baseQuery(ctx context.Context, userID uuid.
 
 func (r *gormSubscriptionRepository) ListByUser(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, limit, offset int) ([]models.Subscription, error) {
 	var subs []models.Subscription
-	if err := r.baseQuery(ctx, userID, serviceName, start, end).Limit(limit).Offset(offset).Find(&subs).Order("start, service_name").Error; err != nil {
+	err := withTenantRead(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		return r.baseQuery(tx, tenantID, userID, serviceName, start, end).
+			Order("start_date, service_name, id").
+			Limit(limit).Offset(offset).
+			Find(&subs).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (r *gormSubscriptionRepository) ListByUsers(ctx context.Context, userIDs []uuid.UUID, serviceName *string, start, end *time.Time) ([]models.Subscription, error) {
+	subs := []models.Subscription{}
+	if len(userIDs) == 0 {
+		return subs, nil
+	}
+	err := withTenantRead(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		return r.usersQuery(tx, tenantID, userIDs, serviceName, start, end).
+			Order("user_id, start_date, service_name, id").
+			Find(&subs).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return subs, nil
 }
 
-func (r *gormSubscriptionRepository) SumByUserAndService(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time) (int, error) {
-	var sum int64
-
-	err := r.db.WithContext(ctx).Raw(`
-		SELECT SUM(service_max_price) AS total_sum
-		FROM (
-			SELECT month, SUM(max_price) AS service_max_price
-			FROM (
-				SELECT month, service_name, MAX(price) AS max_price
-				FROM (
-					SELECT date_trunc('month', generate_series) AS month,
-						service_name,
-						price
-					FROM (
-						SELECT service_name,
-							price,
-							generate_series(
-								date_trunc('month', start_date),
-								date_trunc('month', COALESCE(end_date, CURRENT_DATE)),
-								interval '1 month'
-							) AS generate_series
-						FROM (?) AS filtered_subs
-					) AS expanded_rows
-				) AS per_service_month
-				WHERE month BETWEEN
-					date_trunc('month', COALESCE(?, '2000-01-01'::timestamp)) AND
-					date_trunc('month', COALESCE(?, CURRENT_DATE))
-				GROUP BY month, service_name
-			) AS max_per_service
-			GROUP BY month
-    	) AS month_sums
-	`, r.baseQuery(ctx, userID, serviceName, start, end), start, end).Scan(&sum).Error
-
-	return int(sum), err
+// SumByUserAndService читает агрегаты с реплики, если у пользователя там нет устаревших
+// пар. Иначе агрегаты пересчитываются и читаются на основной базе, как в MonthlyBreakdown.
+func (r *gormSubscriptionRepository) SumByUserAndService(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) (int, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return 0, tenant.ErrNoTenant
+	}
+
+	reader := replica.Reader(ctx, r.db)
+	stale, err := stalePairs(ctx, reader, tenantID, []uuid.UUID{userID})
+	if err != nil {
+		return 0, err
+	}
+
+	var months []models.MonthlySpend
+	if len(stale) > 0 {
+		months, err = r.MonthlyBreakdown(ctx, userID, serviceName, start, end, overlap)
+	} else {
+		months, err = spendByMonth(ctx, reader, tenantID, userID, serviceName, start, end, overlap)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	sum := 0
+	for _, m := range months {
+		sum += m.Net
+	}
+	return sum, nil
+}
+
+// MonthlyBreakdown возвращает для каждого месяца сумму до скидок, размер скидки и итог
+// из агрегатов monthly_user_service_spend, предварительно пересчитав устаревшие пары
+// пользователя. Пересечения периодов одного сервиса разрешаются политикой overlap.
+func (r *gormSubscriptionRepository) MonthlyBreakdown(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.MonthlySpend, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	if _, err := r.refreshStale(ctx, tenantID, []uuid.UUID{userID}); err != nil {
+		return nil, err
+	}
+	return spendByMonth(ctx, r.db, tenantID, userID, serviceName, start, end, overlap)
+}
+
+// spendByMonth читает помесячные суммы пользователя из monthly_user_service_spend
+func spendByMonth(ctx context.Context, db *gorm.DB, tenantID string, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.MonthlySpend, error) {
+	var months []models.MonthlySpend
+	err := tenantTx(ctx, db, tenantID, func(tx *gorm.DB) error {
+		q := tx.
+			Table("monthly_user_service_spend").
+			Where("tenant_id = ? AND user_id = ? AND overlap = ?", tenantID, userID, string(overlap))
+		if serviceName != nil {
+			q = q.Where("service_name = ?", serviceName)
+		}
+
+		return q.
+			Where(`month BETWEEN
+				date_trunc('month', COALESCE(?, '2000-01-01'::timestamp)) AND
+				date_trunc('month', COALESCE(?, CURRENT_DATE))`, start, end).
+			Select("month, SUM(gross)::bigint AS gross, SUM(discount)::bigint AS discount, SUM(net)::bigint AS net").
+			Group("month").
+			Order("month").
+			Scan(&months).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return months, nil
+}
+
+// ServiceMonthlySpend, как MonthlyBreakdown, пересчитывает устаревшие пары пользователей
+// и читает агрегаты monthly_user_service_spend, но без суммирования по сервисам
+func (r *gormSubscriptionRepository) ServiceMonthlySpend(ctx context.Context, userIDs []uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.ServiceMonthSpend, error) {
+	spend := []models.ServiceMonthSpend{}
+	if len(userIDs) == 0 {
+		return spend, nil
+	}
+
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	if _, err := r.refreshStale(ctx, tenantID, userIDs); err != nil {
+		return nil, err
+	}
+
+	err := tenantTx(ctx, r.db, tenantID, func(tx *gorm.DB) error {
+		q := tx.
+			Table("monthly_user_service_spend").
+			Where("tenant_id = ? AND user_id IN ? AND overlap = ?", tenantID, userIDs, string(overlap))
+		if serviceName != nil {
+			q = q.Where("service_name = ?", serviceName)
+		}
+
+		return q.
+			Where(`month BETWEEN
+				date_trunc('month', COALESCE(?, '2000-01-01'::timestamp)) AND
+				date_trunc('month', COALESCE(?, CURRENT_DATE))`, start, end).
+			Select("user_id, service_name, month, gross, discount, net").
+			Order("user_id, month, service_name").
+			Scan(&spend).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spend, nil
+}
+
+// CollapsedOverlaps возвращает записи, не попавшие в расчет из-за пересечения
+// с другой записью того же сервиса, вместе с записью, которая была учтена вместо них.
+func (r *gormSubscriptionRepository) CollapsedOverlaps(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.CollapsedOverlap, error) {
+	collapsed := []models.CollapsedOverlap{}
+	if !overlap.collapses() {
+		return collapsed, nil
+	}
+
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		return tx.Raw(overlap.rankedRowsCTE()+`
+			SELECT dropped.service_name,
+				kept.id AS kept_id,
+				dropped.id AS collapsed_id,
+				MIN(dropped.month) AS start_month,
+				MAX(dropped.month) AS end_month,
+				COUNT(*) AS months
+			FROM ranked_rows AS dropped
+			JOIN ranked_rows AS kept
+				ON kept.month = dropped.month
+				AND kept.user_id = dropped.user_id
+				AND kept.service_name = dropped.service_name
+				AND kept.overlap_rank = 1
+			WHERE dropped.overlap_rank > 1
+			GROUP BY dropped.service_name, kept.id, dropped.id
+			ORDER BY MIN(dropped.month), dropped.service_name
+		`, r.baseQuery(tx, tenantID, userID, serviceName, start, end), start, end).Scan(&collapsed).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return collapsed, nil
+}
+
+func (r *gormSubscriptionRepository) CreateDiscount(ctx context.Context, d *models.Discount) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		var count int64
+		err := tx.Model(&models.Subscription{}).Where("id = ? AND tenant_id = ?", d.SubscriptionID, tenantID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(d).Error
+	})
+}
+
+func (r *gormSubscriptionRepository) ListDiscounts(ctx context.Context, subscriptionID uuid.UUID) ([]models.Discount, error) {
+	var discounts []models.Discount
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		return tenantDiscounts(tx, tenantID, subscriptionID).Order("start_date").Find(&discounts).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return discounts, nil
+}
+
+func (r *gormSubscriptionRepository) DeleteDiscount(ctx context.Context, subscriptionID, discountID uuid.UUID) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		result := tenantDiscounts(tx, tenantID, subscriptionID).Delete(&models.Discount{}, "id = ?", discountID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
 }
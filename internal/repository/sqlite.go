@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"subscriptions-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// sqliteSubscriptionRepository - SubscriptionRepository для драйвера sqlite. Записи
+// читаются и изменяются теми же запросами GORM, что в основной реализации, а суммы,
+// пересечения и конфликты считаются в Go (months.go): в SQLite нет generate_series,
+// date_trunc и pg_trgm, а агрегаты monthly_user_service_spend не ведутся.
+//
+// Даты SQLite хранит строками и сравнивает как строки, поэтому все даты
+// приводятся к UTC перед записью и в условиях запросов.
+type sqliteSubscriptionRepository struct {
+	gormSubscriptionRepository
+}
+
+func NewSQLiteSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &sqliteSubscriptionRepository{gormSubscriptionRepository{db: db}}
+}
+
+func (r *sqliteSubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) error {
+	sub.StartDate, sub.EndDate = utcDates(sub.StartDate, sub.EndDate)
+	return r.gormSubscriptionRepository.Create(ctx, sub)
+}
+
+func (r *sqliteSubscriptionRepository) Update(ctx context.Context, id uuid.UUID, s *models.Subscription) error {
+	if !time.Time(s.StartDate).IsZero() {
+		s.StartDate = models.MonthYearDate(time.Time(s.StartDate).UTC())
+	}
+	if s.EndDate != nil {
+		end := models.MonthYearDate(time.Time(*s.EndDate).UTC())
+		s.EndDate = &end
+	}
+	return r.gormSubscriptionRepository.Update(ctx, id, s)
+}
+
+func (r *sqliteSubscriptionRepository) ListByUser(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, limit, offset int) ([]models.Subscription, error) {
+	return r.gormSubscriptionRepository.ListByUser(ctx, userID, serviceName, utcTime(start), utcTime(end), limit, offset)
+}
+
+func (r *sqliteSubscriptionRepository) ListByUsers(ctx context.Context, userIDs []uuid.UUID, serviceName *string, start, end *time.Time) ([]models.Subscription, error) {
+	return r.gormSubscriptionRepository.ListByUsers(ctx, userIDs, serviceName, utcTime(start), utcTime(end))
+}
+
+// load читает подписки пользователей, действовавшие в периоде, и их скидки
+func (r *sqliteSubscriptionRepository) load(ctx context.Context, userIDs []uuid.UUID, serviceName *string, start, end *time.Time) ([]models.Subscription, map[uuid.UUID][]models.Discount, error) {
+	var (
+		subs      []models.Subscription
+		discounts []models.Discount
+	)
+	err := withTenantRead(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		q := r.usersQuery(tx, tenantID, userIDs, serviceName, utcTime(start), utcTime(end))
+		if err := q.Find(&subs).Error; err != nil {
+			return err
+		}
+		if len(subs) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, 0, len(subs))
+		for _, s := range subs {
+			ids = append(ids, s.ID)
+		}
+		return tx.Where("subscription_id IN ?", ids).Find(&discounts).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bySub := make(map[uuid.UUID][]models.Discount)
+	for _, d := range discounts {
+		bySub[d.SubscriptionID] = append(bySub[d.SubscriptionID], d)
+	}
+	return subs, bySub, nil
+}
+
+func (r *sqliteSubscriptionRepository) SumByUserAndService(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) (int, error) {
+	months, err := r.MonthlyBreakdown(ctx, userID, serviceName, start, end, overlap)
+	if err != nil {
+		return 0, err
+	}
+
+	sum := 0
+	for _, m := range months {
+		sum += m.Net
+	}
+	return sum, nil
+}
+
+func (r *sqliteSubscriptionRepository) MonthlyBreakdown(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.MonthlySpend, error) {
+	subs, discounts, err := r.load(ctx, []uuid.UUID{userID}, serviceName, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return monthlySpend(rankMonths(subs, start, end, time.Now(), overlap), overlap, discounts), nil
+}
+
+func (r *sqliteSubscriptionRepository) ServiceMonthlySpend(ctx context.Context, userIDs []uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.ServiceMonthSpend, error) {
+	if len(userIDs) == 0 {
+		return []models.ServiceMonthSpend{}, nil
+	}
+	subs, discounts, err := r.load(ctx, userIDs, serviceName, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return usersServiceMonthlySpend(subs, start, end, time.Now(), overlap, discounts), nil
+}
+
+func (r *sqliteSubscriptionRepository) CollapsedOverlaps(ctx context.Context, userID uuid.UUID, serviceName *string, start, end *time.Time, overlap OverlapPolicy) ([]models.CollapsedOverlap, error) {
+	if !overlap.collapses() {
+		return []models.CollapsedOverlap{}, nil
+	}
+
+	subs, _, err := r.load(ctx, []uuid.UUID{userID}, serviceName, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return collapsedOverlaps(rankMonths(subs, start, end, time.Now(), overlap)), nil
+}
+
+func (r *sqliteSubscriptionRepository) FindConflicts(ctx context.Context, userID uuid.UUID) ([]models.Conflict, error) {
+	var subs []models.Subscription
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID string) error {
+		return tx.Where("tenant_id = ? AND user_id = ?", tenantID, userID).Find(&subs).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscriptionConflicts(subs), nil
+}
+
+func (r *sqliteSubscriptionRepository) CreateDiscount(ctx context.Context, d *models.Discount) error {
+	d.StartDate, d.EndDate = utcDates(d.StartDate, d.EndDate)
+	return r.gormSubscriptionRepository.CreateDiscount(ctx, d)
+}
+
+func utcDates(start models.MonthYearDate, end *models.MonthYearDate) (models.MonthYearDate, *models.MonthYearDate) {
+	start = models.MonthYearDate(time.Time(start).UTC())
+	if end != nil {
+		utc := models.MonthYearDate(time.Time(*end).UTC())
+		end = &utc
+	}
+	return start, end
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/tenant"
+
+	"gorm.io/gorm"
+)
+
+// StatsRepository - служебная статистика по всем арендаторам для метрик
+type StatsRepository interface {
+	ActiveSubscriptions(ctx context.Context) ([]models.TenantActivity, error)
+}
+
+type gormStatsRepository struct {
+	db *gorm.DB
+}
+
+func NewStatsRepository(db *gorm.DB) StatsRepository {
+	return &gormStatsRepository{db: db}
+}
+
+func (r *gormStatsRepository) ActiveSubscriptions(ctx context.Context) ([]models.TenantActivity, error) {
+	activity := []models.TenantActivity{}
+	err := tenantTx(ctx, r.db, tenant.All, func(tx *gorm.DB) error {
+		now := time.Now()
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return activeInPeriod(tx.Model(&models.Subscription{}), &month, &month).
+			Select("tenant_id, COUNT(*) AS subscriptions, COUNT(DISTINCT user_id) AS users").
+			Group("tenant_id").
+			Order("tenant_id").
+			Scan(&activity).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
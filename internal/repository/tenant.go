@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"subscriptions-service/internal/database"
+	"subscriptions-service/internal/replica"
+	"subscriptions-service/internal/tenant"
+
+	"gorm.io/gorm"
+)
+
+// tenantTx выполняет fn в транзакции с app.tenant_id = tenantID - это SET LOCAL
+// с параметром, значение сбрасывается в конце транзакции. Запросы репозитория сами
+// фильтруют по tenant_id, политики RLS в Postgres страхуют от пропущенного фильтра.
+// В SQLite нет RLS, там остается только фильтр в запросах.
+func tenantTx(ctx context.Context, db *gorm.DB, tenantID string, fn func(tx *gorm.DB) error) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if database.IsSQLite(tx) {
+			return fn(tx)
+		}
+		if err := tx.Exec("SELECT set_config('app.tenant_id', ?, true)", tenantID).Error; err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// withTenant - tenantTx для арендатора из контекста запроса
+func withTenant(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB, tenantID string) error) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+	return tenantTx(ctx, db, tenantID, func(tx *gorm.DB) error {
+		return fn(tx, tenantID)
+	})
+}
+
+// withTenantRead - withTenant для чтений, допускающих отставание реплики.
+// Без реплик и для клиентов, закрепленных за основной базой, читает с основной.
+func withTenantRead(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB, tenantID string) error) error {
+	return withTenant(ctx, replica.Reader(ctx, db), fn)
+}
@@ -0,0 +1,8 @@
+package swagger
+
+import _ "embed"
+
+// JSON - сгенерированная swag спецификация, по ней проверяются запросы и ответы
+//
+//go:embed swagger.json
+var JSON []byte
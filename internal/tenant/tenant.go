@@ -0,0 +1,84 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Default - арендатор запросов, для которых он не указан ни в токене, ни в заголовке
+const Default = "default"
+
+// All - служебное значение app.tenant_id для фоновых заданий и CLI,
+// которым видны данные всех арендаторов. Из запроса его получить нельзя.
+const All = "*"
+
+var ErrNoTenant = errors.New("tenant is not set")
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Valid сообщает, является ли id допустимым идентификатором арендатора
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+type ctxKey struct{}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Middleware определяет арендатора запроса. Арендатор из токена или API-ключа
+// имеет приоритет, заголовок header с другим значением отклоняется.
+// Субъекту без арендатора заголовок доверяется, только если trustHeader: аутентификация
+// отключена или шлюз перед сервисом сам выставляет и вырезает заголовок. Иначе такой
+// запрос отклоняется с 403, чтобы токен без арендатора не открывал данные любого из них.
+// Без заголовка доверенный запрос получает Default.
+// Должен стоять после middleware аутентификации.
+func Middleware(header string, trustHeader bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(header)
+		if id != "" && !Valid(id) {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidTenant, "invalid tenant"))
+			return
+		}
+
+		p, ok := auth.PrincipalFromContext(c.Request.Context())
+		switch {
+		case ok && p.TenantID != "":
+			if !Valid(p.TenantID) {
+				apierror.Abort(c, apierror.New(http.StatusForbidden, apierror.CodeInvalidTenant, "invalid tenant"))
+				return
+			}
+			if id != "" && id != p.TenantID {
+				traceID, _ := c.Get("trace_id")
+				logger.Log.Warn("Арендатор в заголовке не совпадает с токеном", "trace_id", traceID,
+					"header", id, "token", p.TenantID)
+				apierror.Abort(c, apierror.New(http.StatusForbidden, apierror.CodeTenantMismatch, "tenant mismatch"))
+				return
+			}
+			id = p.TenantID
+		case !trustHeader:
+			apierror.Abort(c, apierror.New(http.StatusForbidden, apierror.CodeInvalidTenant, "principal has no tenant"))
+			return
+		case id == "":
+			id = Default
+		}
+
+		c.Set("tenant_id", id)
+		c.Request = c.Request.WithContext(WithID(c.Request.Context(), id))
+		c.Next()
+	}
+}
@@ -1,6 +1,10 @@
 package trace
 
-import "context"
+import (
+	"context"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
 
 type ctxKey string
 
@@ -10,7 +14,12 @@ func WithTraceID(ctx context.Context, traceID string) context.Context {
 	return context.WithValue(ctx, TraceIDKey, traceID)
 }
 
+// TraceIDFromContext возвращает trace id текущего спана OpenTelemetry,
+// а без него - значение, сохраненное WithTraceID
 func TraceIDFromContext(ctx context.Context) string {
+	if sc := oteltrace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
 	if v := ctx.Value(TraceIDKey); v != nil {
 		if tid, ok := v.(string); ok {
 			return tid
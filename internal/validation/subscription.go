@@ -0,0 +1,69 @@
+package validation
+
+import (
+	"time"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// maxServiceName - длина service_name, как в теге max=255 запросов REST
+const maxServiceName = 255
+
+// Subscription проверяет правила подписки, общие для REST и gRPC: сервис указан,
+// цена положительна, пользователь задан, а период не заканчивается раньше начала.
+// Вызывается для уже разобранной модели, поэтому форматы полей здесь не проверяются.
+func Subscription(sub models.Subscription) []apierror.FieldError {
+	var errs []apierror.FieldError
+	if sub.ServiceName == "" {
+		errs = append(errs, Field("service_name", "is required"))
+	} else if len([]rune(sub.ServiceName)) > maxServiceName {
+		errs = append(errs, Field("service_name", "must be at most 255 characters long"))
+	}
+	if sub.Price <= 0 {
+		errs = append(errs, Field("price", "must be greater than 0"))
+	}
+	if sub.UserID == uuid.Nil {
+		errs = append(errs, Field("user_id", "is required"))
+	}
+	if time.Time(sub.StartDate).IsZero() {
+		errs = append(errs, Field("start_date", "is required"))
+	} else if sub.EndDate != nil && time.Time(*sub.EndDate).Before(time.Time(sub.StartDate)) {
+		errs = append(errs, Field("end_date", "must not be before start_date"))
+	}
+	return errs
+}
+
+// SubscriptionUpdate проверяет подписку existing после изменений changes, в которых
+// нулевые поля не меняются. Если период нарушен измененной датой начала, ошибка
+// относится к start_date, а не к end_date, которого нет в запросе.
+func SubscriptionUpdate(existing, changes models.Subscription) []apierror.FieldError {
+	merged := existing
+	if changes.ServiceName != "" {
+		merged.ServiceName = changes.ServiceName
+	}
+	if changes.Price != 0 {
+		merged.Price = changes.Price
+	}
+	if changes.UserID != uuid.Nil {
+		merged.UserID = changes.UserID
+	}
+	if !time.Time(changes.StartDate).IsZero() {
+		merged.StartDate = changes.StartDate
+	}
+	if changes.EndDate != nil {
+		merged.EndDate = changes.EndDate
+	}
+
+	errs := Subscription(merged)
+	if changes.EndDate == nil {
+		for i, fe := range errs {
+			if fe.Field == "end_date" {
+				errs[i] = Field("start_date", "must not be after end_date")
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,177 @@
+// Package validation проверяет тела запросов REST API: строгий разбор JSON
+// и декларативные правила в тегах validate структур запросов. Все нарушения
+// возвращаются разом как apierror.Validation с JSON Pointer на каждое поле.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"subscriptions-service/internal/apierror"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// MonthLayout - формат месяцев в API
+const MonthLayout = "01-2006"
+
+// Validator - структура запроса с правилами, которые не выражаются тегами,
+// например сравнение двух полей. Validate вызывается после проверки тегов.
+type Validator interface {
+	Validate() []apierror.FieldError
+}
+
+var validate = newValidate()
+
+func newValidate() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// в ошибках поля называются так же, как в JSON
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	v.RegisterValidation("month", func(fl validator.FieldLevel) bool {
+		_, err := time.Parse(MonthLayout, fl.Field().String())
+		return err == nil
+	})
+	// встроенный uuid принимает только нижний регистр, а uuid.Parse - любые формы UUID
+	v.RegisterValidation("uuid", func(fl validator.FieldLevel) bool {
+		_, err := uuid.Parse(fl.Field().String())
+		return err == nil
+	})
+	return v
+}
+
+// DecodeJSON разбирает тело запроса в dst. Неизвестные поля, данные после объекта
+// и значения не того типа отклоняются ошибкой validation_failed.
+func DecodeJSON(r io.Reader, dst any) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil && dec.More() {
+		err = errors.New("unexpected data after JSON object")
+	}
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.Is(err, io.EOF):
+		return apierror.Validation("request body is required")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return apierror.Validation("invalid request body", Field(
+			typeErr.Field, "must be "+jsonType(typeErr.Type)+", got "+typeErr.Value))
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return apierror.Validation("request body is not valid JSON")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		name := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return apierror.Validation("invalid request body", Field(name, "unknown field"))
+	default:
+		return apierror.Validation("invalid request body: " + err.Error())
+	}
+}
+
+// Struct проверяет правила в тегах validate и Validator и возвращает
+// все нарушения одной ошибкой validation_failed
+func Struct(v any) error {
+	var fields []apierror.FieldError
+
+	err := validate.Struct(v)
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		for _, fe := range verrs {
+			// Namespace начинается с имени типа: SubscriptionRequest.end_date
+			_, path, _ := strings.Cut(fe.Namespace(), ".")
+			fields = append(fields, Field(path, message(fe)))
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if cv, ok := v.(Validator); ok {
+		fields = append(fields, cv.Validate()...)
+	}
+	if len(fields) > 0 {
+		return apierror.Validation("request validation failed", fields...)
+	}
+	return nil
+}
+
+// Field - нарушение в поле тела запроса по его пути в JSON (items[0].name)
+func Field(path, msg string) apierror.FieldError {
+	return apierror.FieldError{Field: path, Pointer: pointer(path), Message: msg}
+}
+
+// pointer переводит путь вида items[0].name в JSON Pointer /items/0/name
+func pointer(path string) string {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	var b strings.Builder
+	for _, part := range strings.Split(path, ".") {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(part))
+	}
+	return b.String()
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "month":
+		return "must be in MM-YYYY format"
+	case "uuid":
+		return "must be a UUID"
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "min":
+		if fe.Kind() == reflect.String {
+			return "must be at least " + fe.Param() + " characters long"
+		}
+		return "must contain at least " + fe.Param() + " items"
+	case "max":
+		if fe.Kind() == reflect.String {
+			return "must be at most " + fe.Param() + " characters long"
+		}
+		return "must contain at most " + fe.Param() + " items"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	default:
+		return fmt.Sprintf("failed %s validation", fe.Tag())
+	}
+}
+
+// jsonType называет ожидаемый тип значения в терминах JSON
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
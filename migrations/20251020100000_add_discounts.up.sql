@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS subscription_discounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('percent', 'fixed')),
+    value INT NOT NULL CHECK (value > 0),
+    start_date DATE NOT NULL,
+    end_date DATE,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CHECK (type <> 'percent' OR value <= 100),
+    CHECK (end_date IS NULL OR end_date >= start_date)
+);
+
+CREATE INDEX idx_subscription_discounts_subscription_id ON subscription_discounts(subscription_id);
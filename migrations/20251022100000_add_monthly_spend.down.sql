@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS discount_spend_stale ON subscription_discounts;
+DROP TRIGGER IF EXISTS subscription_spend_stale ON subscriptions;
+DROP FUNCTION IF EXISTS trigger_discount_spend_stale;
+DROP FUNCTION IF EXISTS trigger_subscription_spend_stale;
+DROP FUNCTION IF EXISTS mark_monthly_spend_stale;
+DROP TABLE IF EXISTS monthly_spend_refresh;
+DROP TABLE IF EXISTS monthly_user_service_spend;
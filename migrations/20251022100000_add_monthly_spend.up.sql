@@ -0,0 +1,74 @@
+CREATE TABLE IF NOT EXISTS monthly_user_service_spend (
+    user_id UUID NOT NULL,
+    service_name TEXT NOT NULL,
+    overlap TEXT NOT NULL,
+    month DATE NOT NULL,
+    gross BIGINT NOT NULL,
+    discount BIGINT NOT NULL,
+    net BIGINT NOT NULL,
+    PRIMARY KEY (user_id, overlap, service_name, month)
+);
+
+-- Состояние агрегатов по паре пользователь/сервис.
+-- refreshed_month IS NULL - пара изменилась и агрегаты нужно пересчитать.
+CREATE TABLE IF NOT EXISTS monthly_spend_refresh (
+    user_id UUID NOT NULL,
+    service_name TEXT NOT NULL,
+    refreshed_month DATE,
+    PRIMARY KEY (user_id, service_name)
+);
+
+CREATE OR REPLACE FUNCTION mark_monthly_spend_stale(p_user_id UUID, p_service_name TEXT)
+    RETURNS VOID AS $$
+    BEGIN
+        INSERT INTO monthly_spend_refresh (user_id, service_name, refreshed_month)
+        VALUES (p_user_id, p_service_name, NULL)
+        ON CONFLICT (user_id, service_name) DO UPDATE SET refreshed_month = NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION trigger_subscription_spend_stale()
+    RETURNS TRIGGER AS $$
+    BEGIN
+        IF TG_OP IN ('UPDATE', 'DELETE') THEN
+            PERFORM mark_monthly_spend_stale(OLD.user_id, OLD.service_name);
+        END IF;
+        IF TG_OP IN ('INSERT', 'UPDATE') THEN
+            PERFORM mark_monthly_spend_stale(NEW.user_id, NEW.service_name);
+        END IF;
+    RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION trigger_discount_spend_stale()
+    RETURNS TRIGGER AS $$
+    BEGIN
+        IF TG_OP IN ('UPDATE', 'DELETE') THEN
+            PERFORM mark_monthly_spend_stale(s.user_id, s.service_name)
+            FROM subscriptions AS s WHERE s.id = OLD.subscription_id;
+        END IF;
+        IF TG_OP IN ('INSERT', 'UPDATE') THEN
+            PERFORM mark_monthly_spend_stale(s.user_id, s.service_name)
+            FROM subscriptions AS s WHERE s.id = NEW.subscription_id;
+        END IF;
+    RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS subscription_spend_stale ON subscriptions;
+
+CREATE TRIGGER subscription_spend_stale
+    AFTER INSERT OR UPDATE OR DELETE ON subscriptions
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_subscription_spend_stale();
+
+DROP TRIGGER IF EXISTS discount_spend_stale ON subscription_discounts;
+
+CREATE TRIGGER discount_spend_stale
+    AFTER INSERT OR UPDATE OR DELETE ON subscription_discounts
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_discount_spend_stale();
+
+INSERT INTO monthly_spend_refresh (user_id, service_name, refreshed_month)
+SELECT DISTINCT user_id, service_name, NULL::date FROM subscriptions
+ON CONFLICT DO NOTHING;
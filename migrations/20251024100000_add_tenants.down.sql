@@ -0,0 +1,70 @@
+DROP POLICY IF EXISTS tenant_isolation ON monthly_spend_refresh;
+ALTER TABLE monthly_spend_refresh NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE monthly_spend_refresh DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON monthly_user_service_spend;
+ALTER TABLE monthly_user_service_spend NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE monthly_user_service_spend DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON subscription_discounts;
+ALTER TABLE subscription_discounts NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_discounts DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON subscriptions;
+ALTER TABLE subscriptions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions DISABLE ROW LEVEL SECURITY;
+DROP FUNCTION IF EXISTS tenant_visible;
+
+CREATE OR REPLACE FUNCTION trigger_subscription_spend_stale()
+    RETURNS TRIGGER AS $$
+    BEGIN
+        IF TG_OP IN ('UPDATE', 'DELETE') THEN
+            PERFORM mark_monthly_spend_stale(OLD.user_id, OLD.service_name);
+        END IF;
+        IF TG_OP IN ('INSERT', 'UPDATE') THEN
+            PERFORM mark_monthly_spend_stale(NEW.user_id, NEW.service_name);
+        END IF;
+    RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION trigger_discount_spend_stale()
+    RETURNS TRIGGER AS $$
+    BEGIN
+        IF TG_OP IN ('UPDATE', 'DELETE') THEN
+            PERFORM mark_monthly_spend_stale(s.user_id, s.service_name)
+            FROM subscriptions AS s WHERE s.id = OLD.subscription_id;
+        END IF;
+        IF TG_OP IN ('INSERT', 'UPDATE') THEN
+            PERFORM mark_monthly_spend_stale(s.user_id, s.service_name)
+            FROM subscriptions AS s WHERE s.id = NEW.subscription_id;
+        END IF;
+    RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS mark_monthly_spend_stale(TEXT, UUID, TEXT);
+
+CREATE OR REPLACE FUNCTION mark_monthly_spend_stale(p_user_id UUID, p_service_name TEXT)
+    RETURNS VOID AS $$
+    BEGIN
+        INSERT INTO monthly_spend_refresh (user_id, service_name, refreshed_month)
+        VALUES (p_user_id, p_service_name, NULL)
+        ON CONFLICT (user_id, service_name) DO UPDATE SET refreshed_month = NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+-- агрегаты разных арендаторов не сливаются, их нужно пересобрать
+DELETE FROM monthly_user_service_spend;
+DELETE FROM monthly_spend_refresh;
+ALTER TABLE monthly_spend_refresh DROP CONSTRAINT IF EXISTS monthly_spend_refresh_pkey;
+ALTER TABLE monthly_spend_refresh DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE monthly_spend_refresh ADD PRIMARY KEY (user_id, service_name);
+ALTER TABLE monthly_user_service_spend DROP CONSTRAINT IF EXISTS monthly_user_service_spend_pkey;
+ALTER TABLE monthly_user_service_spend DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE monthly_user_service_spend ADD PRIMARY KEY (user_id, overlap, service_name, month);
+
+INSERT INTO monthly_spend_refresh (user_id, service_name, refreshed_month)
+SELECT DISTINCT user_id, service_name, NULL::date FROM subscriptions
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+DROP INDEX IF EXISTS idx_subscriptions_tenant_id;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,89 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS idx_subscriptions_tenant_id ON subscriptions(tenant_id);
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE monthly_user_service_spend ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE monthly_user_service_spend DROP CONSTRAINT IF EXISTS monthly_user_service_spend_pkey;
+ALTER TABLE monthly_user_service_spend ADD PRIMARY KEY (tenant_id, user_id, overlap, service_name, month);
+
+ALTER TABLE monthly_spend_refresh ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE monthly_spend_refresh DROP CONSTRAINT IF EXISTS monthly_spend_refresh_pkey;
+ALTER TABLE monthly_spend_refresh ADD PRIMARY KEY (tenant_id, user_id, service_name);
+
+DROP FUNCTION IF EXISTS mark_monthly_spend_stale(UUID, TEXT);
+
+CREATE OR REPLACE FUNCTION mark_monthly_spend_stale(p_tenant_id TEXT, p_user_id UUID, p_service_name TEXT)
+    RETURNS VOID AS $$
+    BEGIN
+        INSERT INTO monthly_spend_refresh (tenant_id, user_id, service_name, refreshed_month)
+        VALUES (p_tenant_id, p_user_id, p_service_name, NULL)
+        ON CONFLICT (tenant_id, user_id, service_name) DO UPDATE SET refreshed_month = NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION trigger_subscription_spend_stale()
+    RETURNS TRIGGER AS $$
+    BEGIN
+        IF TG_OP IN ('UPDATE', 'DELETE') THEN
+            PERFORM mark_monthly_spend_stale(OLD.tenant_id, OLD.user_id, OLD.service_name);
+        END IF;
+        IF TG_OP IN ('INSERT', 'UPDATE') THEN
+            PERFORM mark_monthly_spend_stale(NEW.tenant_id, NEW.user_id, NEW.service_name);
+        END IF;
+    RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION trigger_discount_spend_stale()
+    RETURNS TRIGGER AS $$
+    BEGIN
+        IF TG_OP IN ('UPDATE', 'DELETE') THEN
+            PERFORM mark_monthly_spend_stale(s.tenant_id, s.user_id, s.service_name)
+            FROM subscriptions AS s WHERE s.id = OLD.subscription_id;
+        END IF;
+        IF TG_OP IN ('INSERT', 'UPDATE') THEN
+            PERFORM mark_monthly_spend_stale(s.tenant_id, s.user_id, s.service_name)
+            FROM subscriptions AS s WHERE s.id = NEW.subscription_id;
+        END IF;
+    RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+-- Строки видны только арендатору из app.tenant_id (SET LOCAL в транзакции запроса).
+-- Значение '*' используют фоновые задания, которым нужны данные всех арендаторов.
+-- Суперпользователь и роли с BYPASSRLS политики игнорируют, поэтому сервис должен
+-- подключаться к БД отдельной ролью.
+CREATE OR REPLACE FUNCTION tenant_visible(p_tenant_id TEXT)
+    RETURNS BOOLEAN AS $$
+        SELECT p_tenant_id = current_setting('app.tenant_id', true)
+            OR current_setting('app.tenant_id', true) = '*';
+    $$ LANGUAGE sql STABLE;
+
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON subscriptions;
+CREATE POLICY tenant_isolation ON subscriptions
+    USING (tenant_visible(tenant_id))
+    WITH CHECK (tenant_visible(tenant_id));
+
+ALTER TABLE subscription_discounts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscription_discounts FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON subscription_discounts;
+CREATE POLICY tenant_isolation ON subscription_discounts
+    USING (EXISTS (SELECT 1 FROM subscriptions AS s WHERE s.id = subscription_id))
+    WITH CHECK (EXISTS (SELECT 1 FROM subscriptions AS s WHERE s.id = subscription_id));
+
+ALTER TABLE monthly_user_service_spend ENABLE ROW LEVEL SECURITY;
+ALTER TABLE monthly_user_service_spend FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON monthly_user_service_spend;
+CREATE POLICY tenant_isolation ON monthly_user_service_spend
+    USING (tenant_visible(tenant_id))
+    WITH CHECK (tenant_visible(tenant_id));
+
+ALTER TABLE monthly_spend_refresh ENABLE ROW LEVEL SECURITY;
+ALTER TABLE monthly_spend_refresh FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON monthly_spend_refresh;
+CREATE POLICY tenant_isolation ON monthly_spend_refresh
+    USING (tenant_visible(tenant_id))
+    WITH CHECK (tenant_visible(tenant_id));
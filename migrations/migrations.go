@@ -0,0 +1,27 @@
+// Package migrations встраивает SQL-миграции в бинарник
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// FS - файлы миграций Postgres в формате golang-migrate: <версия>_<имя>.up.sql и .down.sql
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFiles embed.FS
+
+// SQLite - миграции для драйвера sqlite в том же формате. Версии у них свои,
+// схема соответствует последней миграции Postgres.
+var SQLite = mustSub(sqliteFiles, "sqlite")
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS subscription_discounts;
+DROP TABLE IF EXISTS subscriptions;
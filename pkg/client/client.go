@@ -0,0 +1,89 @@
+// Package client - Go-клиент REST API v1 сервиса подписок. Типы и методы в client.gen.go
+// сгенерированы oapi-codegen из api/openapi/openapi.json (make openapi), здесь -
+// аутентификация, повторы запросов и постраничный обход.
+//
+//	c, err := client.New("http://subscriptions:8080", client.WithBearerToken(token))
+//	resp, err := c.GetSubscriptionWithResponse(ctx, id)
+//	if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+//		// err - *client.Error с problem+json из ответа
+//	}
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// BasePath - префикс версии API, который New добавляет к адресу сервиса
+const BasePath = "/v1"
+
+// New создает клиент REST API v1. server - адрес сервиса без префикса версии,
+// например http://subscriptions:8080. Если среди opts нет WithRetry, запросы
+// повторяются по DefaultRetry.
+func New(server string, opts ...ClientOption) (*ClientWithResponses, error) {
+	opts = append(opts, func(c *Client) error {
+		if _, ok := c.Client.(*retryDoer); ok {
+			return nil
+		}
+		return WithRetry(DefaultRetry)(c)
+	})
+	return NewClientWithResponses(strings.TrimSuffix(server, "/")+BasePath, opts...)
+}
+
+// WithBearerToken передает JWT в заголовке Authorization
+func WithBearerToken(token string) ClientOption {
+	return withHeader("Authorization", "Bearer "+token)
+}
+
+// WithAPIKey передает API-ключ в заголовке Authorization
+func WithAPIKey(key string) ClientOption {
+	return withHeader("Authorization", "ApiKey "+key)
+}
+
+// WithTenant передает арендатора в заголовке header (TENANT_HEADER сервиса, по умолчанию X-Tenant-ID)
+func WithTenant(header, tenant string) ClientOption {
+	return withHeader(header, tenant)
+}
+
+func withHeader(name, value string) ClientOption {
+	return WithRequestEditorFn(func(_ context.Context, req *http.Request) error {
+		req.Header.Set(name, value)
+		return nil
+	})
+}
+
+// Error - ответ API с ошибкой. Problem пуст, если тело не в формате problem+json,
+// например ответ прокси.
+type Error struct {
+	StatusCode int
+	Problem    *Problem
+}
+
+func (e *Error) Error() string {
+	if e.Problem == nil {
+		return fmt.Sprintf("subscriptions api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	msg := fmt.Sprintf("subscriptions api: %d %s", e.StatusCode, e.Problem.Code)
+	if e.Problem.Detail != nil {
+		msg += ": " + *e.Problem.Detail
+	}
+	return msg
+}
+
+// Check возвращает *Error, если статус ответа не 2xx. body - уже прочитанное тело,
+// поле Body ответа *WithResponse.
+func Check(resp *http.Response, body []byte) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	var problem Problem
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/problem+json") &&
+		json.Unmarshal(body, &problem) == nil {
+		apiErr.Problem = &problem
+	}
+	return apiErr
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// MaxPageSize - наибольший limit, который принимает GET /subscriptions/list
+const MaxPageSize = 100
+
+// EachSubscription обходит все страницы GET /subscriptions/list и вызывает fn для каждой
+// подписки. Limit из params задает размер страницы от 1 до MaxPageSize (сервис молча
+// заменяет другие значения на 10, и обход закончился бы после первой страницы), Offset -
+// с какой записи начать. Ошибка fn прекращает обход и возвращается как есть.
+func (c *ClientWithResponses) EachSubscription(ctx context.Context, params ListSubscriptionsParams, fn func(Subscription) error) error {
+	limit := MaxPageSize
+	if params.Limit != nil && *params.Limit >= 1 && *params.Limit <= MaxPageSize {
+		limit = *params.Limit
+	}
+	offset := 0
+	if params.Offset != nil {
+		offset = *params.Offset
+	}
+
+	for {
+		page := params
+		page.Limit, page.Offset = &limit, &offset
+		resp, err := c.ListSubscriptionsWithResponse(ctx, &page)
+		if err != nil {
+			return err
+		}
+		if err := Check(resp.HTTPResponse, resp.Body); err != nil {
+			return err
+		}
+		if resp.JSON200 == nil {
+			return fmt.Errorf("subscriptions api: unexpected response %s", resp.HTTPResponse.Header.Get("Content-Type"))
+		}
+
+		for _, sub := range *resp.JSON200 {
+			if err := fn(sub); err != nil {
+				return err
+			}
+		}
+		if len(*resp.JSON200) < limit {
+			return nil
+		}
+		offset += limit
+	}
+}
+
+// ListAllSubscriptions собирает все страницы GET /subscriptions/list в один список
+func (c *ClientWithResponses) ListAllSubscriptions(ctx context.Context, params ListSubscriptionsParams) ([]Subscription, error) {
+	var all []Subscription
+	err := c.EachSubscription(ctx, params, func(sub Subscription) error {
+		all = append(all, sub)
+		return nil
+	})
+	return all, err
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retry - политика повторов. Повторяются ответы 429 с учетом Retry-After, а для
+// идемпотентных методов (GET, PUT, DELETE) еще ошибки сети и ответы 502, 503, 504.
+// POST при сбое не повторяется: подписка или скидка могла быть уже создана.
+type Retry struct {
+	// MaxAttempts - число попыток вместе с первой, 1 отключает повторы
+	MaxAttempts int
+	// MinBackoff удваивается с каждой попыткой до MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+var DefaultRetry = Retry{MaxAttempts: 3, MinBackoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second}
+
+// WithRetry задает политику повторов. Оборачивает HTTP-клиент, переданный
+// в WithHTTPClient раньше этой опции.
+func WithRetry(r Retry) ClientOption {
+	return func(c *Client) error {
+		next := c.Client
+		if next == nil {
+			next = &http.Client{}
+		}
+		c.Client = &retryDoer{next: next, retry: r}
+		return nil
+	}
+}
+
+type retryDoer struct {
+	next  HttpRequestDoer
+	retry Retry
+}
+
+func (d *retryDoer) Do(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := d.next.Do(req)
+		if attempt >= d.retry.MaxAttempts || !retryable(req, resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+
+		wait := d.backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				wait = after
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	idempotent := req.Method != http.MethodPost && req.Method != http.MethodPatch
+	if err != nil {
+		return idempotent && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// backoff - экспоненциальная задержка со случайной добавкой до половины, чтобы
+// клиенты после общего сбоя не повторяли запросы одновременно
+func (d *retryDoer) backoff(attempt int) time.Duration {
+	wait := d.retry.MinBackoff << (attempt - 1)
+	if wait <= 0 || wait > d.retry.MaxBackoff {
+		wait = d.retry.MaxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + rand.N(wait/2+1)
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
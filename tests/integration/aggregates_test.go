@@ -0,0 +1,113 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func getSum(t *testing.T, userID uuid.UUID, query string) int {
+	req, _ := http.NewRequest("GET", "/subscriptions/sum?user_id="+userID.String()+query, nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var result apiv1.SumResponse
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+	return result.Sum
+}
+
+func TestSpendAggregatesFollowWrites(t *testing.T) {
+	clearDB(db)
+
+	userID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	start := models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	end := models.MonthYearDate(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	sub := models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      userID,
+		StartDate:   start,
+		EndDate:     &end,
+	}
+	assert.NoError(t, db.Create(&sub).Error)
+	assert.Equal(t, 500*6, getSum(t, userID, "&start_date=01-2025&end_date=12-2025"))
+
+	// обновление цены через API пересчитывает агрегат
+	body := `{"service_name":"Netflix","price":300,"user_id":"` + userID.String() + `","start_date":"01-2025","end_date":"06-2025"}`
+	req, _ := http.NewRequest("PUT", "/subscriptions/"+sub.ID.String(), bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 300*6, getSum(t, userID, "&start_date=01-2025&end_date=12-2025"))
+
+	// скидка тоже помечает пару устаревшей
+	assert.NoError(t, db.Create(&models.Discount{
+		SubscriptionID: sub.ID,
+		Type:           models.DiscountFixed,
+		Value:          100,
+		StartDate:      start,
+	}).Error)
+	assert.Equal(t, 200*6, getSum(t, userID, "&start_date=01-2025&end_date=12-2025"))
+
+	req, _ = http.NewRequest("DELETE", "/subscriptions/"+sub.ID.String(), nil)
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 0, getSum(t, userID, "&start_date=01-2025&end_date=12-2025"))
+}
+
+func TestSpendAggregatesRebuildAndCheck(t *testing.T) {
+	clearDB(db)
+
+	ctx := context.Background()
+	aggregates := repository.NewSpendAggregates(db)
+
+	userID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	start := models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	end := models.MonthYearDate(time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, db.Create(&models.Subscription{ServiceName: "Spotify", Price: 200, UserID: userID, StartDate: start, EndDate: &end}).Error)
+	assert.NoError(t, db.Create(&models.Subscription{ServiceName: "Spotify", Price: 100, UserID: userID, StartDate: start}).Error)
+	assert.NoError(t, db.Create(&models.Subscription{ServiceName: "Netflix", Price: 500, UserID: uuid.New(), StartDate: start, EndDate: &end}).Error)
+
+	pairs, err := aggregates.RebuildSpend(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, pairs)
+
+	mismatches, err := aggregates.CheckSpend(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	pairs, err = aggregates.RefreshStaleSpend(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, pairs)
+
+	// порча агрегата обнаруживается проверкой
+	assert.NoError(t, db.Exec("UPDATE monthly_user_service_spend SET net = net + 1 WHERE user_id = ? AND overlap = 'sum' AND month = '2025-03-01'", userID).Error)
+
+	mismatches, err = aggregates.CheckSpend(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, mismatches, 1) {
+		m := mismatches[0]
+		assert.Equal(t, userID, m.UserID)
+		assert.Equal(t, "Spotify", m.ServiceName)
+		assert.Equal(t, "sum", m.Overlap)
+		assert.Equal(t, 301, *m.StoredNet)
+		assert.Equal(t, 300, *m.LiveNet)
+	}
+}
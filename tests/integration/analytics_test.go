@@ -0,0 +1,168 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/handlers"
+	"subscriptions-service/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func adminGet(path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func seedAnalytics(t *testing.T) {
+	clearDB(db)
+
+	jan := models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	mar := models.MonthYearDate(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	jun := models.MonthYearDate(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	firstUser := uuid.New()
+	secondUser := uuid.New()
+
+	subs := []models.Subscription{
+		{ServiceName: "Netflix", Price: 500, UserID: firstUser, StartDate: jan, EndDate: &jun},
+		{ServiceName: "Netflix", Price: 700, UserID: secondUser, StartDate: jan, EndDate: &mar},
+		{ServiceName: "Spotify", Price: 200, UserID: firstUser, StartDate: jan, EndDate: &jun},
+	}
+	for i := range subs {
+		assert.NoError(t, db.Create(&subs[i]).Error)
+	}
+}
+
+func TestAnalyticsRequiresAdmin(t *testing.T) {
+	router := newAuthRouter(t)
+
+	req, _ := http.NewRequest("GET", "/admin/analytics/churn", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, jwt.MapClaims{"sub": uuid.NewString()}))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	req, _ = http.NewRequest("GET", "/admin/analytics/churn", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, jwt.MapClaims{"roles": []string{"admin"}}))
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestAnalyticsPopularServices(t *testing.T) {
+	seedAnalytics(t)
+
+	resp := adminGet("/admin/analytics/popular-services?start_date=01-2025&end_date=06-2025")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var services []models.ServicePopularity
+	err := json.Unmarshal(resp.Body.Bytes(), &services)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ServicePopularity{
+		{ServiceName: "Netflix", Subscriptions: 2, Users: 2},
+		{ServiceName: "Spotify", Subscriptions: 1, Users: 1},
+	}, services)
+
+	// после марта Netflix остался только у одного пользователя
+	resp = adminGet("/admin/analytics/popular-services?start_date=04-2025&end_date=06-2025&limit=1")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	err = json.Unmarshal(resp.Body.Bytes(), &services)
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+}
+
+func TestAnalyticsAverageSpend(t *testing.T) {
+	seedAnalytics(t)
+
+	resp := adminGet("/admin/analytics/average-spend?start_date=01-2025&end_date=06-2025")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var result handlers.AverageSpendResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	total := (500+200)*6 + 700*3
+	assert.Equal(t, 2, result.Users)
+	assert.Equal(t, total, result.Total)
+	assert.InDelta(t, float64(total)/2, result.Average, 0.001)
+	if assert.Len(t, result.Months, 6) {
+		assert.Equal(t, 2, result.Months[0].Users)
+		assert.Equal(t, 1400, result.Months[0].Total)
+		assert.Equal(t, 1, result.Months[5].Users)
+		assert.Equal(t, 700, result.Months[5].Total)
+	}
+
+	resp = adminGet("/admin/analytics/average-spend?start_date=13-2025")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestAnalyticsChurn(t *testing.T) {
+	seedAnalytics(t)
+
+	resp := adminGet("/admin/analytics/churn?start_date=01-2025&end_date=06-2025")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var months []models.MonthlyChurn
+	err := json.Unmarshal(resp.Body.Bytes(), &months)
+	assert.NoError(t, err)
+
+	// месяцы без начал и окончаний тоже попадают в отчет
+	if assert.Len(t, months, 6) {
+		for i, month := range months {
+			assert.Equal(t, time.Date(2025, time.Month(i+1), 1, 0, 0, 0, 0, time.UTC), time.Time(month.Month))
+		}
+
+		assert.Equal(t, 3, months[0].Started)
+		assert.Equal(t, 0, months[0].Ended)
+		assert.Equal(t, 3, months[0].Active)
+
+		assert.Equal(t, 0, months[1].Started)
+		assert.Equal(t, 0, months[1].Ended)
+		assert.Equal(t, 3, months[1].Active)
+		assert.Zero(t, months[1].ChurnRate)
+
+		assert.Equal(t, 1, months[2].Ended)
+		assert.Equal(t, 3, months[2].Active)
+		assert.InDelta(t, 1.0/3, months[2].ChurnRate, 0.001)
+
+		assert.Equal(t, 0, months[3].Ended)
+		assert.Equal(t, 2, months[3].Active)
+		assert.Equal(t, 0, months[4].Ended)
+		assert.Equal(t, 2, months[4].Active)
+
+		assert.Equal(t, 2, months[5].Ended)
+		assert.Equal(t, 2, months[5].Active)
+	}
+}
+
+func TestAnalyticsPriceDistribution(t *testing.T) {
+	seedAnalytics(t)
+
+	resp := adminGet("/admin/analytics/price-distribution?service_name=Netflix")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var services []models.PriceDistribution
+	err := json.Unmarshal(resp.Body.Bytes(), &services)
+	assert.NoError(t, err)
+
+	if assert.Len(t, services, 1) {
+		netflix := services[0]
+		assert.Equal(t, 2, netflix.Subscriptions)
+		assert.Equal(t, 500, netflix.Min)
+		assert.Equal(t, 700, netflix.Max)
+		assert.InDelta(t, 600, netflix.Average, 0.001)
+		assert.InDelta(t, 600, netflix.Median, 0.001)
+	}
+}
@@ -0,0 +1,280 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func createAPIKey(t *testing.T, req apiv1.CreateAPIKeyRequest) apiv1.CreateAPIKeyResponse {
+	body, _ := json.Marshal(req)
+	httpReq, _ := http.NewRequest("POST", "/admin/api-keys", bytes.NewBuffer(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httpReq)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	var created apiv1.CreateAPIKeyResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	return created
+}
+
+func doWithAPIKey(router *gin.Engine, method, path, key string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "ApiKey "+key)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestCreateAPIKeyStoresOnlyHash(t *testing.T) {
+	clearDB(db)
+
+	created := createAPIKey(t, apiv1.CreateAPIKeyRequest{
+		Name:   "billing-sync",
+		Scopes: []string{auth.ScopeSubscriptionsRead},
+	})
+	assert.NotEmpty(t, created.Key)
+	assert.Equal(t, []string{auth.ScopeSubscriptionsRead}, created.Scopes)
+
+	var stored models.APIKey
+	require.NoError(t, db.First(&stored, "id = ?", created.ID).Error)
+	assert.NotEqual(t, created.Key, stored.KeyHash)
+	assert.NotContains(t, stored.KeyHash, created.Key)
+	assert.Equal(t, auth.HashAPIKey(created.Key), stored.KeyHash)
+
+	resp := adminGet("/admin/api-keys")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NotContains(t, resp.Body.String(), created.Key)
+	assert.NotContains(t, resp.Body.String(), stored.KeyHash)
+}
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	clearDB(db)
+
+	past := time.Now().Add(-time.Hour)
+	for _, req := range []apiv1.CreateAPIKeyRequest{
+		{Scopes: []string{auth.ScopeSubscriptionsRead}},
+		{Name: "no-scopes"},
+		{Name: "unknown-scope", Scopes: []string{"subscriptions:admin"}},
+		{Name: "expired", Scopes: []string{auth.ScopeSubscriptionsRead}, ExpiresAt: &past},
+	} {
+		body, _ := json.Marshal(req)
+		httpReq, _ := http.NewRequest("POST", "/admin/api-keys", bytes.NewBuffer(body))
+		httpReq.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, httpReq)
+		assert.Equal(t, http.StatusBadRequest, resp.Code, req.Name)
+	}
+}
+
+func TestAPIKeyScopesAndAllowedUsers(t *testing.T) {
+	clearDB(db)
+	router := newAuthRouter(t)
+
+	allowed := uuid.New()
+	other := uuid.New()
+	sub := models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      allowed,
+		StartDate:   models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+	require.NoError(t, db.Create(&sub).Error)
+
+	readOnly := createAPIKey(t, apiv1.CreateAPIKeyRequest{
+		Name:           "reporting",
+		Scopes:         []string{auth.ScopeSubscriptionsRead},
+		AllowedUserIDs: []uuid.UUID{allowed},
+	})
+
+	assert.Equal(t, http.StatusOK, doWithAPIKey(router, "GET", "/subscriptions/sum?user_id="+allowed.String(), readOnly.Key).Code)
+	assert.Equal(t, http.StatusOK, doWithAPIKey(router, "GET", "/subscriptions/"+sub.ID.String(), readOnly.Key).Code)
+	assert.Equal(t, http.StatusForbidden, doWithAPIKey(router, "GET", "/subscriptions/list?user_id="+other.String(), readOnly.Key).Code)
+	assert.Equal(t, http.StatusForbidden, doWithAPIKey(router, "DELETE", "/subscriptions/"+sub.ID.String(), readOnly.Key).Code)
+	assert.Equal(t, http.StatusForbidden, doWithAPIKey(router, "GET", "/admin/analytics/churn", readOnly.Key).Code)
+	assert.Equal(t, http.StatusForbidden, doWithAPIKey(router, "GET", "/admin/api-keys", readOnly.Key).Code)
+
+	writer := createAPIKey(t, apiv1.CreateAPIKeyRequest{
+		Name:   "backoffice",
+		Scopes: []string{auth.ScopeSubscriptionsRead, auth.ScopeSubscriptionsWrite, auth.ScopeAnalyticsRead},
+	})
+	assert.Equal(t, http.StatusOK, doWithAPIKey(router, "GET", "/subscriptions/list?user_id="+other.String(), writer.Key).Code)
+	assert.Equal(t, http.StatusOK, doWithAPIKey(router, "GET", "/admin/analytics/churn", writer.Key).Code)
+	assert.Equal(t, http.StatusOK, doWithAPIKey(router, "DELETE", "/subscriptions/"+sub.ID.String(), writer.Key).Code)
+}
+
+func TestAPIKeyRejectsInvalidExpiredAndRevoked(t *testing.T) {
+	clearDB(db)
+	router := newAuthRouter(t)
+	path := "/subscriptions/list?user_id=" + uuid.New().String()
+
+	created := createAPIKey(t, apiv1.CreateAPIKeyRequest{
+		Name:   "integration",
+		Scopes: []string{auth.ScopeSubscriptionsRead},
+	})
+	assert.Equal(t, http.StatusOK, doWithAPIKey(router, "GET", path, created.Key).Code)
+
+	assert.Equal(t, http.StatusUnauthorized, doWithAPIKey(router, "GET", path, "garbage").Code)
+	assert.Equal(t, http.StatusUnauthorized, doWithAPIKey(router, "GET", path, created.Key+"x").Code)
+
+	require.NoError(t, db.Model(&models.APIKey{}).Where("id = ?", created.ID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	assert.Equal(t, http.StatusUnauthorized, doWithAPIKey(router, "GET", path, created.Key).Code)
+
+	revoked := createAPIKey(t, apiv1.CreateAPIKeyRequest{
+		Name:   "revoked",
+		Scopes: []string{auth.ScopeSubscriptionsRead},
+	})
+	req, _ := http.NewRequest("DELETE", "/admin/api-keys/"+revoked.ID.String(), nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, http.StatusUnauthorized, doWithAPIKey(router, "GET", path, revoked.Key).Code)
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestAPIKeyRecordsUsage(t *testing.T) {
+	clearDB(db)
+	router := newAuthRouter(t)
+	path := "/subscriptions/list?user_id=" + uuid.New().String()
+
+	created := createAPIKey(t, apiv1.CreateAPIKeyRequest{
+		Name:   "usage",
+		Scopes: []string{auth.ScopeSubscriptionsRead},
+	})
+	assert.Nil(t, created.LastUsedAt)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doWithAPIKey(router, "GET", path, created.Key).Code)
+	}
+
+	var stored models.APIKey
+	require.NoError(t, db.First(&stored, "id = ?", created.ID).Error)
+	assert.Equal(t, int64(3), stored.UsageCount)
+	require.NotNil(t, stored.LastUsedAt)
+	assert.WithinDuration(t, time.Now(), *stored.LastUsedAt, time.Minute)
+}
+
+func TestAPIKeyManagementRequiresAdmin(t *testing.T) {
+	router := newAuthRouter(t)
+	userToken := signHS256(t, jwt.MapClaims{"sub": uuid.New().String()})
+	adminToken := signHS256(t, jwt.MapClaims{"sub": uuid.New().String(), "roles": "admin"})
+
+	assert.Equal(t, http.StatusUnauthorized, doAuthorized(router, "GET", "/admin/api-keys", "").Code)
+	assert.Equal(t, http.StatusForbidden, doAuthorized(router, "GET", "/admin/api-keys", userToken).Code)
+	assert.Equal(t, http.StatusOK, doAuthorized(router, "GET", "/admin/api-keys", adminToken).Code)
+}
+
+// stubAPIKeys - хранилище с одним ключом и заданной ошибкой поиска, считает записи учета
+type stubAPIKeys struct {
+	key    *models.APIKey
+	err    error
+	writes []int
+}
+
+func (s *stubAPIKeys) FindAPIKeyByPrefix(context.Context, string) (*models.APIKey, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.key == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return s.key, nil
+}
+
+func (s *stubAPIKeys) RecordAPIKeyUsage(_ context.Context, _ uuid.UUID, _ time.Time, count int) error {
+	s.writes = append(s.writes, count)
+	return nil
+}
+
+func newStubKey(t *testing.T) (string, *models.APIKey) {
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+	return key, &models.APIKey{
+		ID:       uuid.New(),
+		TenantID: tenant.Default,
+		Prefix:   prefix,
+		KeyHash:  hash,
+		Scopes:   []string{auth.ScopeSubscriptionsRead},
+	}
+}
+
+func TestAPIKeyStoreErrors(t *testing.T) {
+	verifier, err := auth.NewVerifier(auth.VerifierConfig{HS256Secret: testJWTSecret})
+	require.NoError(t, err)
+	key, stored := newStubKey(t)
+	past := time.Now().Add(-time.Hour)
+	path := "/subscriptions/list?user_id=" + uuid.NewString()
+
+	for _, tc := range []struct {
+		name   string
+		store  *stubAPIKeys
+		key    string
+		status int
+		code   apierror.Code
+	}{
+		{"valid", &stubAPIKeys{key: stored}, key, http.StatusOK, ""},
+		{"not found", &stubAPIKeys{}, key, http.StatusUnauthorized, apierror.CodeInvalidAPIKey},
+		{"wrong secret", &stubAPIKeys{key: stored}, key + "x", http.StatusUnauthorized, apierror.CodeInvalidAPIKey},
+		{"expired", &stubAPIKeys{key: &models.APIKey{ID: stored.ID, KeyHash: stored.KeyHash, ExpiresAt: &past}}, key, http.StatusUnauthorized, apierror.CodeInvalidAPIKey},
+		{"revoked", &stubAPIKeys{key: &models.APIKey{ID: stored.ID, KeyHash: stored.KeyHash, RevokedAt: &past}}, key, http.StatusUnauthorized, apierror.CodeInvalidAPIKey},
+		{"store down", &stubAPIKeys{err: errors.New("dial tcp 10.0.0.5:5432: connection refused")}, key, http.StatusInternalServerError, apierror.CodeInternal},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			router := newProblemRouter(repository.NewMemorySubscriptionRepository(), auth.Middleware(verifier, tc.store))
+			resp := doWithAPIKey(router, "GET", path, tc.key)
+			require.Equal(t, tc.status, resp.Code, resp.Body.String())
+			if tc.code != "" {
+				var problem apierror.Problem
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &problem))
+				assert.Equal(t, tc.code, problem.Code)
+				assert.NotContains(t, resp.Body.String(), "10.0.0.5")
+			}
+		})
+	}
+}
+
+func TestAPIKeyUsageIsBatched(t *testing.T) {
+	verifier, err := auth.NewVerifier(auth.VerifierConfig{HS256Secret: testJWTSecret})
+	require.NoError(t, err)
+	key, stored := newStubKey(t)
+	store := &stubAPIKeys{key: stored}
+	usage := auth.NewUsageBatcher(store, time.Minute)
+	router := newProblemRouter(repository.NewMemorySubscriptionRepository(), auth.Middleware(verifier, usage))
+
+	path := "/subscriptions/list?user_id=" + uuid.NewString()
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusOK, doWithAPIKey(router, "GET", path, key).Code)
+	}
+	assert.Equal(t, []int{1}, store.writes, "первый запрос пишется сразу, остальные копятся")
+
+	require.NoError(t, usage.Flush(context.Background()))
+	assert.Equal(t, []int{1, 4}, store.writes)
+
+	require.NoError(t, usage.Flush(context.Background()))
+	assert.Equal(t, []int{1, 4}, store.writes, "пустой учет не пишется")
+}
@@ -2,6 +2,7 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,12 +10,17 @@ import (
 	"testing"
 	"time"
 
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/auth"
 	"subscriptions-service/internal/config"
 	"subscriptions-service/internal/database"
 	"subscriptions-service/internal/handlers"
 	_ "subscriptions-service/internal/logger"
+	"subscriptions-service/internal/migrate"
 	"subscriptions-service/internal/models"
 	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+	"subscriptions-service/migrations"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -23,14 +29,18 @@ import (
 )
 
 var (
-	r  *gin.Engine
-	db *gorm.DB
+	r        *gin.Engine
+	db       *gorm.DB
+	migrator *migrate.Runner
 )
 
 func TestMain(m *testing.M) {
 	var err error
 
-	cfg := config.LoadConfig()
+	cfg, _, err := config.Load(nil)
+	if err != nil {
+		panic("Ошибка конфигурации: " + err.Error())
+	}
 	db, err = database.Connect(cfg)
 	if err != nil {
 		panic("Ошибка подключения к БД" + err.Error())
@@ -38,34 +48,57 @@ func TestMain(m *testing.M) {
 
 	_ = db.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public;")
 
-	err = db.AutoMigrate(&models.Subscription{})
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic("Ошибка подключения к БД" + err.Error())
+	}
+	migrator, err = migrate.New(sqlDB, migrations.FS)
 	if err != nil {
+		panic("не удалось прочитать миграции: " + err.Error())
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
 		panic("не удалось выполнить миграцию: " + err.Error())
 	}
 
 	repo := repository.NewSubscriptionRepository(db)
 	h := handlers.NewHandler(repo)
 	r = gin.Default()
+	r.Use(auth.Disabled(), tenant.Middleware(testTenantHeader, true))
 	h.RegisterRoutes(r)
 
+	analytics := handlers.NewAnalyticsHandler(repository.NewAnalyticsRepository(db))
+	analytics.RegisterRoutes(r.Group("/admin", auth.RequireScope(auth.ScopeAnalyticsRead)))
+	handlers.NewAPIKeyHandler(repository.NewAPIKeyRepository(db)).RegisterRoutes(r.Group("/admin", auth.RequireAdmin()))
+
 	code := m.Run()
 
 	os.Exit(code)
 }
 
 func clearDB(db *gorm.DB) error {
-	return db.Exec("DELETE FROM subscriptions").Error
+	if err := db.Exec("DELETE FROM api_keys").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM subscription_discounts").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM subscriptions").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM monthly_user_service_spend").Error; err != nil {
+		return err
+	}
+	return db.Exec("DELETE FROM monthly_spend_refresh").Error
 }
 
 func TestCreateSubscription(t *testing.T) {
 	clearDB(db)
 
-	body := models.Subscription{
+	body := apiv1.CreateSubscriptionRequest{
 		ServiceName: "Netflix",
-		Price:       499,
-		UserID:      uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
-		StartDate:   models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
-		EndDate:     nil,
+		Price:       intPtr(499),
+		UserID:      "123e4567-e89b-12d3-a456-426614174000",
+		StartDate:   "01-2025",
 	}
 
 	jsonBody, _ := json.Marshal(body)
@@ -81,8 +114,8 @@ func TestCreateSubscription(t *testing.T) {
 	err := json.Unmarshal(resp.Body.Bytes(), &created)
 	assert.NoError(t, err)
 	assert.Equal(t, body.ServiceName, created.ServiceName)
-	assert.Equal(t, body.Price, created.Price)
-	assert.Equal(t, body.UserID, created.UserID)
+	assert.Equal(t, *body.Price, created.Price)
+	assert.Equal(t, body.UserID, created.UserID.String())
 }
 
 func TestGetSubscription(t *testing.T) {
@@ -122,12 +155,7 @@ func TestUpdateSubscription(t *testing.T) {
 	err := db.Create(&sub).Error
 	assert.NoError(t, err)
 
-	updated := models.Subscription{
-		ServiceName: "YouTube Premium",
-		Price:       249,
-		UserID:      sub.UserID,
-		StartDate:   models.MonthYearDate(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)),
-	}
+	updated := apiv1.UpdateSubscriptionRequest{Price: intPtr(249)}
 
 	jsonBody, _ := json.Marshal(updated)
 	req, _ := http.NewRequest("PUT", "/subscriptions/"+sub.ID.String(), bytes.NewBuffer(jsonBody))
@@ -273,10 +301,10 @@ func TestGetSubscriptionSum(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, resp.Code)
 
-	var result map[string]int
+	var result apiv1.SumResponse
 	err := json.Unmarshal(resp.Body.Bytes(), &result)
 	assert.NoError(t, err)
-	assert.Equal(t, (500+200)*6, result["sum"])
+	assert.Equal(t, (500+200)*6, result.Sum)
 }
 
 func TestGetSubscriptionSumWithSameService(t *testing.T) {
@@ -313,10 +341,10 @@ func TestGetSubscriptionSumWithSameService(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, resp.Code)
 
-	var result map[string]int
+	var result apiv1.SumResponse
 	err := json.Unmarshal(resp.Body.Bytes(), &result)
 	assert.NoError(t, err)
-	assert.Equal(t, (200)*6, result["sum"])
+	assert.Equal(t, (200)*6, result.Sum)
 }
 
 func TestGetSubscriptionSumWithEmptyEnd(t *testing.T) {
@@ -341,8 +369,8 @@ func TestGetSubscriptionSumWithEmptyEnd(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, resp.Code)
 
-	var result map[string]int
+	var result apiv1.SumResponse
 	err := json.Unmarshal(resp.Body.Bytes(), &result)
 	assert.NoError(t, err)
-	assert.Equal(t, (200)*9, result["sum"])
+	assert.Equal(t, (200)*9, result.Sum)
 }
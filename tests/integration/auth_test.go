@@ -0,0 +1,198 @@
+package integration
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/handlers"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJWTSecret = "test-jwt-secret"
+
+// signHS256 подписывает claims тестовым секретом. Без exp токен живет час, без tenant_id
+// принадлежит tenant.Default; "tenant_id": nil выпускает токен без арендатора.
+func signHS256(t *testing.T, claims jwt.MapClaims) string {
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+	if _, ok := claims["tenant_id"]; !ok {
+		claims["tenant_id"] = tenant.Default
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	require.NoError(t, err)
+	return token
+}
+
+func newRouterWithAuth(t *testing.T, cfg auth.VerifierConfig) *gin.Engine {
+	cfg.RolesClaim = "roles"
+	cfg.AdminRole = "admin"
+	cfg.TenantClaim = "tenant_id"
+	verifier, err := auth.NewVerifier(cfg)
+	require.NoError(t, err)
+
+	apiKeys := repository.NewAPIKeyRepository(db)
+	router := gin.New()
+	api := router.Group("/", auth.Middleware(verifier, apiKeys), tenant.Middleware(testTenantHeader, false))
+	handlers.NewHandler(repository.NewSubscriptionRepository(db)).RegisterRoutes(api)
+	handlers.NewAnalyticsHandler(repository.NewAnalyticsRepository(db)).RegisterRoutes(api.Group("/admin", auth.RequireScope(auth.ScopeAnalyticsRead)))
+	handlers.NewAPIKeyHandler(apiKeys).RegisterRoutes(api.Group("/admin", auth.RequireAdmin()))
+	return router
+}
+
+func newAuthRouter(t *testing.T) *gin.Engine {
+	return newRouterWithAuth(t, auth.VerifierConfig{HS256Secret: testJWTSecret})
+}
+
+func doAuthorized(router *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestAuthRequiresValidToken(t *testing.T) {
+	router := newAuthRouter(t)
+	userID := uuid.New()
+	path := "/subscriptions/list?user_id=" + userID.String()
+
+	assert.Equal(t, http.StatusUnauthorized, doAuthorized(router, "GET", path, "").Code)
+
+	expired := signHS256(t, jwt.MapClaims{"sub": userID.String(), "exp": time.Now().Add(-time.Minute).Unix()})
+	assert.Equal(t, http.StatusUnauthorized, doAuthorized(router, "GET", path, expired).Code)
+
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": userID.String(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("other-secret"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, doAuthorized(router, "GET", path, forged).Code)
+
+	valid := signHS256(t, jwt.MapClaims{"sub": userID.String()})
+	assert.Equal(t, http.StatusOK, doAuthorized(router, "GET", path, valid).Code)
+}
+
+func TestAuthEnforcesOwnership(t *testing.T) {
+	clearDB(db)
+	router := newAuthRouter(t)
+
+	owner := uuid.New()
+	stranger := uuid.New()
+	sub := models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      owner,
+		StartDate:   models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+	require.NoError(t, db.Create(&sub).Error)
+
+	ownerToken := signHS256(t, jwt.MapClaims{"sub": owner.String()})
+	strangerToken := signHS256(t, jwt.MapClaims{"sub": stranger.String()})
+	adminToken := signHS256(t, jwt.MapClaims{"sub": stranger.String(), "roles": []string{"admin"}})
+
+	for _, path := range []string{
+		"/subscriptions/" + sub.ID.String(),
+		"/subscriptions/" + sub.ID.String() + "/discounts",
+		"/subscriptions/list?user_id=" + owner.String(),
+		"/subscriptions/sum?user_id=" + owner.String(),
+		"/users/" + owner.String() + "/subscriptions/conflicts",
+	} {
+		assert.Equal(t, http.StatusOK, doAuthorized(router, "GET", path, ownerToken).Code, path)
+		assert.Equal(t, http.StatusForbidden, doAuthorized(router, "GET", path, strangerToken).Code, path)
+		assert.Equal(t, http.StatusOK, doAuthorized(router, "GET", path, adminToken).Code, path)
+	}
+
+	assert.Equal(t, http.StatusForbidden, doAuthorized(router, "DELETE", "/subscriptions/"+sub.ID.String(), strangerToken).Code)
+	assert.Equal(t, http.StatusOK, doAuthorized(router, "DELETE", "/subscriptions/"+sub.ID.String(), ownerToken).Code)
+}
+
+func TestAuthCreateUsesCallerUserID(t *testing.T) {
+	clearDB(db)
+	router := newAuthRouter(t)
+
+	userID := uuid.New()
+	token := signHS256(t, jwt.MapClaims{"sub": userID.String()})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/subscriptions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := post(`{"service_name":"Spotify","price":200,"start_date":"01-2025"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	var created models.Subscription
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	assert.Equal(t, userID, created.UserID)
+
+	resp = post(`{"service_name":"Spotify","price":200,"user_id":"` + uuid.NewString() + `","start_date":"01-2025"}`)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestAuthRS256WithJWKSFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+	keys, err := auth.LoadJWKSFile(path)
+	require.NoError(t, err)
+	router := newRouterWithAuth(t, auth.VerifierConfig{JWKS: keys, UserClaim: "uid"})
+
+	userID := uuid.New()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"uid":       userID.String(),
+		"tenant_id": tenant.Default,
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	resp := doAuthorized(router, "GET", "/subscriptions/list?user_id="+userID.String(), signed)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// HS256 не принимается, если настроен только JWKS
+	resp = doAuthorized(router, "GET", "/subscriptions/list?user_id="+userID.String(), signHS256(t, jwt.MapClaims{"uid": userID.String()}))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
@@ -0,0 +1,302 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/handlers"
+	"subscriptions-service/internal/openapi"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+	"subscriptions-service/pkg/client"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastRetry повторяет запросы без заметных пауз
+var fastRetry = client.Retry{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+// newClientServer поднимает REST API с проверкой по спецификации: запрос клиента,
+// не совпадающий со спецификацией, вернется как 400, а не пройдет незамеченным
+func newClientServer(t *testing.T) *httptest.Server {
+	router, _ := newSpecRouter(t)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestClient(t *testing.T, server string, opts ...client.ClientOption) *client.ClientWithResponses {
+	c, err := client.New(server, opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func problemCode(t *testing.T, err error) apierror.Code {
+	t.Helper()
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	require.NotNil(t, apiErr.Problem, "ответ с ошибкой в формате problem+json")
+	return apierror.Code(apiErr.Problem.Code)
+}
+
+func TestClientSubscriptionLifecycle(t *testing.T) {
+	c := newTestClient(t, newClientServer(t).URL)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	created, err := c.CreateSubscriptionWithResponse(ctx, client.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      &userID,
+		StartDate:   "01-2025",
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Check(created.HTTPResponse, created.Body))
+	require.NotNil(t, created.JSON201)
+	sub := *created.JSON201
+	assert.Equal(t, userID, sub.UserID)
+	assert.Equal(t, "01-2025", sub.StartDate)
+	assert.Nil(t, sub.EndDate)
+
+	end := "06-2025"
+	updated, err := c.UpdateSubscriptionWithResponse(ctx, sub.ID, client.UpdateSubscriptionRequest{EndDate: &end})
+	require.NoError(t, err)
+	require.NoError(t, client.Check(updated.HTTPResponse, updated.Body))
+	assert.Equal(t, &end, updated.JSON200.EndDate)
+
+	discount, err := c.CreateDiscountWithResponse(ctx, sub.ID, client.CreateDiscountRequest{
+		Type: client.CreateDiscountRequestTypePercent, Value: 10, StartDate: "02-2025",
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Check(discount.HTTPResponse, discount.Body))
+
+	overlap := client.GetSubscriptionMonthlySumParamsOverlapSum
+	start, finish := "01-2025", "03-2025"
+	monthly, err := c.GetSubscriptionMonthlySumWithResponse(ctx, &client.GetSubscriptionMonthlySumParams{
+		UserID: userID, StartDate: &start, EndDate: &finish, Overlap: &overlap,
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Check(monthly.HTTPResponse, monthly.Body))
+	assert.Equal(t, 1400, monthly.JSON200.Sum)
+	assert.Equal(t, []client.MonthlySpend{
+		{Month: "01-2025", Gross: 500, Net: 500},
+		{Month: "02-2025", Gross: 500, Discount: 50, Net: 450},
+		{Month: "03-2025", Gross: 500, Discount: 50, Net: 450},
+	}, monthly.JSON200.Months)
+
+	deleted, err := c.DeleteSubscriptionWithResponse(ctx, sub.ID)
+	require.NoError(t, err)
+	require.NoError(t, client.Check(deleted.HTTPResponse, deleted.Body))
+
+	missing, err := c.GetSubscriptionWithResponse(ctx, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, missing.StatusCode())
+	require.NotNil(t, missing.ApplicationProblemJSON404)
+	assert.Equal(t, apierror.CodeSubscriptionNotFound, problemCode(t, client.Check(missing.HTTPResponse, missing.Body)))
+}
+
+func TestClientValidationErrors(t *testing.T) {
+	c := newTestClient(t, newClientServer(t).URL)
+	userID := uuid.New()
+
+	resp, err := c.CreateSubscriptionWithResponse(context.Background(), client.CreateSubscriptionRequest{
+		ServiceName: "Netflix", Price: 500, UserID: &userID, StartDate: "2025-01",
+	})
+	require.NoError(t, err)
+
+	err = client.Check(resp.HTTPResponse, resp.Body)
+	assert.Equal(t, apierror.CodeValidationFailed, problemCode(t, err))
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	require.NotNil(t, apiErr.Problem.Errors)
+	assert.Equal(t, "start_date", (*apiErr.Problem.Errors)[0].Field)
+}
+
+func TestClientPagination(t *testing.T) {
+	var lists atomic.Int32
+	router, _ := newSpecRouter(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/subscriptions/list" {
+			lists.Add(1)
+		}
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := newTestClient(t, srv.URL)
+	ctx := context.Background()
+	userID := uuid.New()
+	for i := 0; i < 25; i++ {
+		resp, err := c.CreateSubscriptionWithResponse(ctx, client.CreateSubscriptionRequest{
+			ServiceName: "Netflix", Price: 100 + i, UserID: &userID, StartDate: "01-2025",
+		})
+		require.NoError(t, err)
+		require.NoError(t, client.Check(resp.HTTPResponse, resp.Body))
+	}
+
+	limit := 10
+	all, err := c.ListAllSubscriptions(ctx, client.ListSubscriptionsParams{UserID: userID, Limit: &limit})
+	require.NoError(t, err)
+	assert.Len(t, all, 25)
+	assert.EqualValues(t, 3, lists.Load(), "25 подписок по 10 на страницу")
+
+	seen := make(map[uuid.UUID]bool)
+	for _, sub := range all {
+		seen[sub.ID] = true
+	}
+	assert.Len(t, seen, 25, "страницы не пересекаются")
+
+	// ошибка fn прекращает обход
+	stop := errors.New("stop")
+	visited := 0
+	err = c.EachSubscription(ctx, client.ListSubscriptionsParams{UserID: userID, Limit: &limit}, func(client.Subscription) error {
+		visited++
+		if visited == 12 {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 12, visited)
+
+	// limit больше допустимого сервис заменил бы на 10, клиент берет MaxPageSize
+	lists.Store(0)
+	limit = 1000
+	all, err = c.ListAllSubscriptions(ctx, client.ListSubscriptionsParams{UserID: userID, Limit: &limit})
+	require.NoError(t, err)
+	assert.Len(t, all, 25)
+	assert.EqualValues(t, 1, lists.Load())
+
+	// ошибка API на странице возвращается как *client.Error
+	badMonth := "2025-01"
+	err = c.EachSubscription(ctx, client.ListSubscriptionsParams{UserID: userID, StartDate: &badMonth}, func(client.Subscription) error {
+		return nil
+	})
+	assert.Equal(t, apierror.CodeValidationFailed, problemCode(t, err))
+}
+
+// flakyServer отвечает status на первые failures запросов, остальные передает router
+func flakyServer(t *testing.T, router http.Handler, status, failures int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(requests.Add(1)) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestClientRetries(t *testing.T) {
+	router, _ := newSpecRouter(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	body := client.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 500, UserID: &userID, StartDate: "01-2025"}
+
+	t.Run("GET повторяется после 503", func(t *testing.T) {
+		srv, requests := flakyServer(t, router, http.StatusServiceUnavailable, 2, nil)
+		c := newTestClient(t, srv.URL, client.WithRetry(fastRetry))
+
+		resp, err := c.GetSubscriptionWithResponse(ctx, uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode())
+		assert.EqualValues(t, 3, requests.Load())
+	})
+
+	t.Run("попытки ограничены MaxAttempts", func(t *testing.T) {
+		srv, requests := flakyServer(t, router, http.StatusServiceUnavailable, 5, nil)
+		c := newTestClient(t, srv.URL, client.WithRetry(fastRetry))
+
+		resp, err := c.GetSubscriptionWithResponse(ctx, uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+		assert.EqualValues(t, 3, requests.Load())
+	})
+
+	t.Run("POST не повторяется после 503", func(t *testing.T) {
+		srv, requests := flakyServer(t, router, http.StatusServiceUnavailable, 1, nil)
+		c := newTestClient(t, srv.URL, client.WithRetry(fastRetry))
+
+		resp, err := c.CreateSubscriptionWithResponse(ctx, body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+		assert.EqualValues(t, 1, requests.Load())
+	})
+
+	t.Run("POST повторяется после 429 с тем же телом", func(t *testing.T) {
+		srv, requests := flakyServer(t, router, http.StatusTooManyRequests, 1, http.Header{"Retry-After": {"0"}})
+		c := newTestClient(t, srv.URL, client.WithRetry(fastRetry))
+
+		resp, err := c.CreateSubscriptionWithResponse(ctx, body)
+		require.NoError(t, err)
+		require.NoError(t, client.Check(resp.HTTPResponse, resp.Body))
+		assert.Equal(t, "Netflix", resp.JSON201.ServiceName)
+		assert.EqualValues(t, 2, requests.Load())
+	})
+
+	t.Run("отмена контекста прерывает ожидание", func(t *testing.T) {
+		srv, requests := flakyServer(t, router, http.StatusTooManyRequests, 5, http.Header{"Retry-After": {"60"}})
+		c := newTestClient(t, srv.URL, client.WithRetry(fastRetry))
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err := c.GetSubscriptionWithResponse(ctx, uuid.New())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.EqualValues(t, 1, requests.Load())
+	})
+}
+
+func TestClientAuthentication(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+	verifier, err := auth.NewVerifier(auth.VerifierConfig{HS256Secret: testJWTSecret})
+	require.NoError(t, err)
+
+	router := gin.New()
+	api := router.Group(apiv1.Prefix, auth.Middleware(verifier, nil), tenant.Middleware(testTenantHeader, false),
+		openapi.Middleware(spec, apiv1.Prefix, openapi.Options{Requests: true, Responses: true}))
+	handlers.NewHandler(repository.NewMemorySubscriptionRepository()).RegisterRoutes(api)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	params := &client.ListSubscriptionsParams{UserID: userID}
+
+	anonymous := newTestClient(t, srv.URL)
+	resp, err := anonymous.ListSubscriptionsWithResponse(ctx, params)
+	require.NoError(t, err)
+	assert.Equal(t, apierror.CodeUnauthenticated, problemCode(t, client.Check(resp.HTTPResponse, resp.Body)))
+
+	token := signHS256(t, jwt.MapClaims{"sub": userID.String()})
+	c := newTestClient(t, srv.URL, client.WithBearerToken(token), client.WithTenant(testTenantHeader, tenant.Default))
+	created, err := c.CreateSubscriptionWithResponse(ctx, client.CreateSubscriptionRequest{
+		ServiceName: "Netflix", Price: 500, StartDate: "01-2025",
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Check(created.HTTPResponse, created.Body))
+	assert.Equal(t, userID, created.JSON201.UserID, "user_id по умолчанию берется из токена")
+
+	resp, err = c.ListSubscriptionsWithResponse(ctx, params)
+	require.NoError(t, err)
+	require.NoError(t, client.Check(resp.HTTPResponse, resp.Body))
+	assert.Len(t, *resp.JSON200, 1)
+}
@@ -0,0 +1,93 @@
+package integration
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestConfigLayers(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  address: ":9000"
+  write_timeout: 45s
+db:
+  max_open_conns: 30
+rate_limit:
+  capacity: 5
+  store: postgres
+`)
+	t.Setenv("SERVER_ADDRESS", "")
+	t.Setenv("RATE_LIMIT_STORE", "")
+	t.Setenv("RATE_LIMIT_CAPACITY", "6")
+	t.Setenv("DB_MAX_OPEN_CONNS", "40")
+
+	cfg, args, err := config.Load([]string{"-config", path, "-rate_limit.capacity=8", "-features.migrate_on_start", "migrate", "up"})
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9000", cfg.Server.Address, "yaml overrides defaults")
+	assert.Equal(t, 45*time.Second, cfg.Server.WriteTimeout)
+	assert.Equal(t, 15*time.Second, cfg.Server.ReadTimeout, "default kept")
+	assert.Equal(t, "postgres", cfg.RateLimit.Store)
+	assert.Equal(t, 40, cfg.DB.MaxOpenConns, "env overrides yaml")
+	assert.Equal(t, 8, cfg.RateLimit.Capacity, "flag overrides env")
+	assert.True(t, cfg.Features.MigrateOnStart)
+	assert.Equal(t, []string{"migrate", "up"}, args)
+}
+
+func TestConfigReportsAllErrors(t *testing.T) {
+	path := writeConfigFile(t, `
+db:
+  max_idle: 3
+`)
+	t.Setenv("DB_PORT", "abc")
+	t.Setenv("LOG_LEVEL", "verbose")
+	t.Setenv("RATE_LIMIT_STORE", "redis")
+	t.Setenv("TRACING_SAMPLE_RATIO", "2")
+	t.Setenv("JWT_JWKS_FILE", "/etc/subscriptions/jwks.json")
+	t.Setenv("JWT_JWKS_URL", "https://auth.example.com/.well-known/jwks.json")
+
+	_, _, err := config.Load([]string{"-config", path, "-server.shutdown_timeout=soon"})
+	require.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "field max_idle not found")
+	assert.Contains(t, msg, `env DB_PORT: invalid integer "abc"`)
+	assert.Contains(t, msg, `flag -server.shutdown_timeout: invalid duration "soon"`)
+	assert.Contains(t, msg, `log.level must be one of [debug info warn error], got "verbose"`)
+	assert.Contains(t, msg, `rate_limit.store must be one of [memory postgres], got "redis"`)
+	assert.Contains(t, msg, "tracing.sample_ratio must be between 0 and 1")
+	assert.Contains(t, msg, "auth.jwks_file and auth.jwks_url are mutually exclusive")
+}
+
+func TestConfigRedactsSecrets(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "db-password-value")
+	t.Setenv("JWT_HS256_SECRET", "jwt-secret-value")
+
+	cfg, _, err := config.Load(nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("config", "config", cfg)
+
+	out := buf.String()
+	assert.NotContains(t, out, "db-password-value")
+	assert.NotContains(t, out, "jwt-secret-value")
+	assert.Contains(t, out, `"db.password":"[REDACTED]"`)
+	assert.Contains(t, out, `"auth.hs256_secret":"[REDACTED]"`)
+	assert.Contains(t, out, `"db.name":"`+cfg.DB.Name+`"`)
+}
@@ -0,0 +1,213 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSubscriptionConflicts(t *testing.T) {
+	clearDB(db)
+
+	userID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	jan := models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	mar := models.MonthYearDate(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	jun := models.MonthYearDate(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	dec := models.MonthYearDate(time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC))
+
+	// пересечение с разной ценой - предлагается обрезать первую запись
+	first := models.Subscription{ID: uuid.New(), ServiceName: "Netflix", Price: 500, UserID: userID, StartDate: jan, EndDate: &jun}
+	second := models.Subscription{ID: uuid.New(), ServiceName: "Netflix", Price: 700, UserID: userID, StartDate: mar, EndDate: &dec}
+	// похожее название без пересечения
+	similar := models.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 300, UserID: userID, StartDate: jan, EndDate: &mar}
+	similarOther := models.Subscription{ID: uuid.New(), ServiceName: "yandex-plus", Price: 300, UserID: userID, StartDate: jun, EndDate: &dec}
+	// перепутанные даты
+	invalid := models.Subscription{ID: uuid.New(), ServiceName: "Spotify", Price: 200, UserID: userID, StartDate: dec, EndDate: &jan}
+	// подписка другого пользователя не участвует
+	other := models.Subscription{ID: uuid.New(), ServiceName: "Netflix", Price: 500, UserID: uuid.New(), StartDate: jan}
+
+	for _, s := range []*models.Subscription{&first, &second, &similar, &similarOther, &invalid, &other} {
+		assert.NoError(t, db.Create(s).Error)
+	}
+
+	req, _ := http.NewRequest("GET", "/users/"+userID.String()+"/subscriptions/conflicts", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var conflicts []models.Conflict
+	err := json.Unmarshal(resp.Body.Bytes(), &conflicts)
+	assert.NoError(t, err)
+
+	byKind := map[models.ConflictKind][]models.Conflict{}
+	for _, c := range conflicts {
+		byKind[c.Kind] = append(byKind[c.Kind], c)
+	}
+
+	if assert.Len(t, byKind[models.ConflictInvalidPeriod], 1) {
+		c := byKind[models.ConflictInvalidPeriod][0]
+		assert.Equal(t, []uuid.UUID{invalid.ID}, c.SubscriptionIDs)
+		assert.Equal(t, models.FixSwapDates, c.Fix.Action)
+		assert.Equal(t, jan, c.Fix.Update[0].StartDate)
+		assert.Equal(t, dec, *c.Fix.Update[0].EndDate)
+	}
+
+	if assert.Len(t, byKind[models.ConflictOverlap], 1) {
+		c := byKind[models.ConflictOverlap][0]
+		assert.Equal(t, []uuid.UUID{first.ID, second.ID}, c.SubscriptionIDs)
+		assert.Equal(t, models.FixTrim, c.Fix.Action)
+		assert.Equal(t, first.ID, c.Fix.Update[0].ID)
+		assert.Equal(t, models.MonthYearDate(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)), *c.Fix.Update[0].EndDate)
+	}
+
+	if assert.Len(t, byKind[models.ConflictSimilarName], 1) {
+		c := byKind[models.ConflictSimilarName][0]
+		assert.ElementsMatch(t, []uuid.UUID{similar.ID, similarOther.ID}, c.SubscriptionIDs)
+		assert.Equal(t, models.FixRename, c.Fix.Action)
+		assert.Len(t, c.Fix.Update, 1)
+	}
+}
+
+func TestGetSubscriptionConflictsMergeDuplicates(t *testing.T) {
+	clearDB(db)
+
+	userID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	jan := models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	mar := models.MonthYearDate(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	jun := models.MonthYearDate(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	first := models.Subscription{ID: uuid.New(), ServiceName: "Spotify", Price: 200, UserID: userID, StartDate: jan, EndDate: &mar}
+	second := models.Subscription{ID: uuid.New(), ServiceName: "Spotify", Price: 200, UserID: userID, StartDate: mar, EndDate: &jun}
+	assert.NoError(t, db.Create(&first).Error)
+	assert.NoError(t, db.Create(&second).Error)
+
+	req, _ := http.NewRequest("GET", "/users/"+userID.String()+"/subscriptions/conflicts", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var conflicts []models.Conflict
+	err := json.Unmarshal(resp.Body.Bytes(), &conflicts)
+	assert.NoError(t, err)
+
+	if assert.Len(t, conflicts, 1) {
+		fix := conflicts[0].Fix
+		assert.Equal(t, models.FixMerge, fix.Action)
+		assert.Equal(t, []uuid.UUID{second.ID}, fix.Delete)
+		assert.Equal(t, first.ID, fix.Update[0].ID)
+		assert.Equal(t, jan, fix.Update[0].StartDate)
+		assert.Equal(t, jun, *fix.Update[0].EndDate)
+	}
+
+	req, _ = http.NewRequest("GET", "/users/not-a-uuid/subscriptions/conflicts", nil)
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func getConflicts(t *testing.T, userID uuid.UUID) []models.Conflict {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/users/"+userID.String()+"/subscriptions/conflicts", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var conflicts []models.Conflict
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &conflicts))
+	return conflicts
+}
+
+func TestGetSubscriptionConflictsKeepsExpensiveRecord(t *testing.T) {
+	clearDB(db)
+
+	userID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	month := func(m time.Month) models.MonthYearDate {
+		return models.MonthYearDate(time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC))
+	}
+	jan, mar, apr, dec := month(time.January), month(time.March), month(time.April), month(time.December)
+
+	// дорогая запись вложена в дешевую с той же датой начала - дешевая обрезается после нее
+	cheap := models.Subscription{ID: uuid.New(), ServiceName: "Netflix", Price: 100, UserID: userID, StartDate: jan, EndDate: &dec}
+	expensive := models.Subscription{ID: uuid.New(), ServiceName: "Netflix", Price: 500, UserID: userID, StartDate: jan, EndDate: &mar}
+	assert.NoError(t, db.Create(&cheap).Error)
+	assert.NoError(t, db.Create(&expensive).Error)
+
+	conflicts := getConflicts(t, userID)
+	if assert.Len(t, conflicts, 1) {
+		fix := conflicts[0].Fix
+		assert.Equal(t, models.FixTrim, fix.Action)
+		assert.Empty(t, fix.Delete)
+		if assert.Len(t, fix.Update, 1) {
+			assert.Equal(t, cheap.ID, fix.Update[0].ID)
+			assert.Equal(t, apr, fix.Update[0].StartDate)
+			assert.Equal(t, dec, *fix.Update[0].EndDate)
+		}
+	}
+
+	// дорогая запись внутри периода дешевой - дешевая делится на две части
+	clearDB(db)
+	expensive.StartDate = mar
+	expensive.EndDate = &apr
+	assert.NoError(t, db.Create(&cheap).Error)
+	assert.NoError(t, db.Create(&expensive).Error)
+
+	conflicts = getConflicts(t, userID)
+	if assert.Len(t, conflicts, 1) {
+		fix := conflicts[0].Fix
+		assert.Equal(t, models.FixSplit, fix.Action)
+		assert.Empty(t, fix.Delete)
+		if assert.Len(t, fix.Update, 1) && assert.Len(t, fix.Create, 1) {
+			assert.Equal(t, cheap.ID, fix.Update[0].ID)
+			assert.Equal(t, jan, fix.Update[0].StartDate)
+			assert.Equal(t, month(time.February), *fix.Update[0].EndDate)
+			assert.Equal(t, month(time.May), fix.Create[0].StartDate)
+			assert.Equal(t, dec, *fix.Create[0].EndDate)
+			assert.Equal(t, 100, fix.Create[0].Price)
+		}
+	}
+
+	// дешевая запись вложена в дорогую - удаляется дешевая
+	clearDB(db)
+	cheap.StartDate, cheap.EndDate = mar, &apr
+	expensive.StartDate, expensive.EndDate = jan, &dec
+	assert.NoError(t, db.Create(&cheap).Error)
+	assert.NoError(t, db.Create(&expensive).Error)
+
+	conflicts = getConflicts(t, userID)
+	if assert.Len(t, conflicts, 1) {
+		fix := conflicts[0].Fix
+		assert.Equal(t, models.FixRemove, fix.Action)
+		assert.Equal(t, []uuid.UUID{cheap.ID}, fix.Delete)
+	}
+}
+
+func TestGetSubscriptionConflictsSameNormalizedName(t *testing.T) {
+	clearDB(db)
+
+	userID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	jan := models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	jun := models.MonthYearDate(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	first := models.Subscription{ID: uuid.New(), ServiceName: "Netflix", Price: 500, UserID: userID, StartDate: jan, EndDate: &jun}
+	second := models.Subscription{ID: uuid.New(), ServiceName: "netflix ", Price: 500, UserID: userID, StartDate: jan, EndDate: &jun}
+	assert.NoError(t, db.Create(&first).Error)
+	assert.NoError(t, db.Create(&second).Error)
+
+	// пересечение уже исправляется объединением, отдельный similar_name не нужен
+	conflicts := getConflicts(t, userID)
+	if assert.Len(t, conflicts, 1) {
+		assert.Equal(t, models.ConflictOverlap, conflicts[0].Kind)
+		assert.Equal(t, models.FixMerge, conflicts[0].Fix.Action)
+	}
+}
@@ -0,0 +1,72 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/config"
+	"subscriptions-service/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig(t *testing.T) config.Config {
+	cfg, _, err := config.Load(nil)
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestConnectRetriesUntilDeadline(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.DB.Port = 1
+	cfg.DB.ConnectTimeout = time.Second
+	cfg.DB.StartupTimeout = 1500 * time.Millisecond
+
+	start := time.Now()
+	_, err := database.Connect(cfg)
+	elapsed := time.Since(start)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connect to database after")
+	assert.GreaterOrEqual(t, elapsed, cfg.DB.StartupTimeout)
+	assert.Less(t, elapsed, 5*time.Second)
+}
+
+func TestStatementTimeout(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.DB.StatementTimeout = 100 * time.Millisecond
+
+	conn, err := database.Connect(cfg)
+	require.NoError(t, err)
+	sqlDB, err := conn.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	err = conn.Exec("SELECT pg_sleep(1)").Error
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "statement timeout")
+}
+
+func TestRequestTimeout(t *testing.T) {
+	router := gin.New()
+	router.Use(database.RequestTimeout(100 * time.Millisecond))
+	router.GET("/slow", func(c *gin.Context) {
+		if err := db.WithContext(c.Request.Context()).Exec("SELECT pg_sleep(1)").Error; err != nil {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	start := time.Now()
+	req, _ := http.NewRequest("GET", "/slow", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, resp.Code)
+	assert.Less(t, time.Since(start), 900*time.Millisecond)
+}
@@ -0,0 +1,173 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateDiscount(t *testing.T) {
+	clearDB(db)
+
+	sub := models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+		StartDate:   models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+	assert.NoError(t, db.Create(&sub).Error)
+
+	body := `{"type":"percent","value":50,"start_date":"01-2025","end_date":"03-2025"}`
+	req, _ := http.NewRequest("POST", "/subscriptions/"+sub.ID.String()+"/discounts", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	var created models.Discount
+	err := json.Unmarshal(resp.Body.Bytes(), &created)
+	assert.NoError(t, err)
+	assert.Equal(t, sub.ID, created.SubscriptionID)
+	assert.Equal(t, models.DiscountPercent, created.Type)
+
+	// невалидный процент
+	body = `{"type":"percent","value":150,"start_date":"01-2025"}`
+	req, _ = http.NewRequest("POST", "/subscriptions/"+sub.ID.String()+"/discounts", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// несуществующая подписка
+	body = `{"type":"fixed","value":100,"start_date":"01-2025"}`
+	req, _ = http.NewRequest("POST", "/subscriptions/"+uuid.NewString()+"/discounts", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestGetSubscriptionSumWithDiscounts(t *testing.T) {
+	clearDB(db)
+
+	userID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	start := models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	end := models.MonthYearDate(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	promoEnd := models.MonthYearDate(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	netflix := models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      userID,
+		StartDate:   start,
+		EndDate:     &end,
+	}
+	spotify := models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: "Spotify",
+		Price:       200,
+		UserID:      userID,
+		StartDate:   start,
+		EndDate:     &end,
+	}
+	assert.NoError(t, db.Create(&netflix).Error)
+	assert.NoError(t, db.Create(&spotify).Error)
+
+	// промо-цена 250 первые три месяца
+	assert.NoError(t, db.Create(&models.Discount{
+		SubscriptionID: netflix.ID,
+		Type:           models.DiscountPercent,
+		Value:          50,
+		StartDate:      start,
+		EndDate:        &promoEnd,
+	}).Error)
+	// фиксированная скидка больше цены не уводит сумму в минус
+	assert.NoError(t, db.Create(&models.Discount{
+		SubscriptionID: spotify.ID,
+		Type:           models.DiscountFixed,
+		Value:          300,
+		StartDate:      end,
+	}).Error)
+
+	url := "/subscriptions/sum?user_id=" + userID.String() +
+		"&start_date=01-2025&end_date=06-2025"
+
+	req, _ := http.NewRequest("GET", url, nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var result apiv1.SumResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &result)
+	assert.NoError(t, err)
+	assert.Equal(t, 250*3+500*3+200*5, result.Sum)
+
+	url = "/subscriptions/sum/monthly?user_id=" + userID.String() +
+		"&start_date=01-2025&end_date=06-2025"
+
+	req, _ = http.NewRequest("GET", url, nil)
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var monthly apiv1.MonthlySumResponse
+	err = json.Unmarshal(resp.Body.Bytes(), &monthly)
+	assert.NoError(t, err)
+	assert.Equal(t, 700*6, monthly.Gross)
+	assert.Equal(t, 250*3+200, monthly.Discount)
+	assert.Equal(t, result.Sum, monthly.Sum)
+	if assert.Len(t, monthly.Months, 6) {
+		assert.Equal(t, apiv1.MonthlySpend{Month: "01-2025", Gross: 700, Discount: 250, Net: 450}, monthly.Months[0])
+		assert.Equal(t, apiv1.MonthlySpend{Month: "06-2025", Gross: 700, Discount: 200, Net: 500}, monthly.Months[5])
+	}
+}
+
+func TestDeleteDiscount(t *testing.T) {
+	clearDB(db)
+
+	sub := models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: "Spotify",
+		Price:       300,
+		UserID:      uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+		StartDate:   models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+	assert.NoError(t, db.Create(&sub).Error)
+
+	discount := models.Discount{
+		ID:             uuid.New(),
+		SubscriptionID: sub.ID,
+		Type:           models.DiscountFixed,
+		Value:          100,
+		StartDate:      sub.StartDate,
+	}
+	assert.NoError(t, db.Create(&discount).Error)
+
+	req, _ := http.NewRequest("DELETE", "/subscriptions/"+sub.ID.String()+"/discounts/"+discount.ID.String(), nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	req, _ = http.NewRequest("DELETE", "/subscriptions/"+sub.ID.String()+"/discounts/"+discount.ID.String(), nil)
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
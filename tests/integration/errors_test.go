@@ -0,0 +1,104 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/handlers"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+	"subscriptions-service/internal/trace"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingRepository отвечает на Get заданной ошибкой, как сломанная или медленная база
+type failingRepository struct {
+	repository.SubscriptionRepository
+	err error
+}
+
+func (r *failingRepository) Get(context.Context, uuid.UUID) (*models.Subscription, error) {
+	return nil, r.err
+}
+
+func newProblemRouter(repo repository.SubscriptionRepository, mw ...gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(trace.Middleware())
+	api := router.Group("/", append(mw, tenant.Middleware(testTenantHeader, true))...)
+	handlers.NewHandler(repo).RegisterRoutes(api)
+	return router
+}
+
+func getProblem(t *testing.T, router *gin.Engine, path string) (int, apierror.Problem) {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, apierror.ContentType, resp.Header().Get("Content-Type"))
+	var problem apierror.Problem
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &problem), resp.Body.String())
+	assert.Equal(t, resp.Code, problem.Status)
+	assert.Equal(t, resp.Header().Get("X-Trace-ID"), problem.TraceID)
+	return resp.Code, problem
+}
+
+func TestProblemDetails(t *testing.T) {
+	router := newProblemRouter(repository.NewMemorySubscriptionRepository(), auth.Disabled())
+
+	id := uuid.New()
+	code, problem := getProblem(t, router, "/subscriptions/"+id.String())
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, apierror.CodeSubscriptionNotFound, problem.Code)
+	assert.Equal(t, "/subscriptions/"+id.String(), problem.Instance)
+	assert.NotEmpty(t, problem.TraceID)
+
+	code, problem = getProblem(t, router, "/subscriptions/sum?user_id="+uuid.NewString()+"&start_date=2025-01")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, apierror.CodeValidationFailed, problem.Code)
+	require.Len(t, problem.Errors, 1)
+	assert.Equal(t, "start_date", problem.Errors[0].Field)
+
+	code, problem = getProblem(t, router, "/subscriptions/not-a-uuid")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "id", problem.Errors[0].Field)
+}
+
+func TestProblemDetailsHideRepositoryErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		status int
+		code   apierror.Code
+	}{
+		{errors.New(`ERROR: relation "subscriptions_secret" does not exist (SQLSTATE 42P01)`), http.StatusInternalServerError, apierror.CodeInternal},
+		{fmt.Errorf("select subscription: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, apierror.CodeTimeout},
+	} {
+		router := newProblemRouter(&failingRepository{err: tc.err}, auth.Disabled())
+		code, problem := getProblem(t, router, "/subscriptions/"+uuid.NewString())
+		assert.Equal(t, tc.status, code)
+		assert.Equal(t, tc.code, problem.Code)
+		assert.NotContains(t, problem.Detail, "subscriptions_secret")
+		assert.NotContains(t, problem.Detail, "select subscription")
+	}
+}
+
+func TestProblemDetailsFromMiddleware(t *testing.T) {
+	verifier, err := auth.NewVerifier(auth.VerifierConfig{HS256Secret: testJWTSecret})
+	require.NoError(t, err)
+	router := newProblemRouter(repository.NewMemorySubscriptionRepository(), auth.Middleware(verifier, nil))
+
+	code, problem := getProblem(t, router, "/subscriptions/"+uuid.NewString())
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, apierror.CodeUnauthenticated, problem.Code)
+}
@@ -0,0 +1,189 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/graph"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRepository считает пакетные чтения, чтобы проверить отсутствие N+1
+type countingRepository struct {
+	repository.SubscriptionRepository
+	listByUsers, serviceSpend atomic.Int32
+}
+
+func (r *countingRepository) ListByUsers(ctx context.Context, userIDs []uuid.UUID, serviceName *string, start, end *time.Time) ([]models.Subscription, error) {
+	r.listByUsers.Add(1)
+	return r.SubscriptionRepository.ListByUsers(ctx, userIDs, serviceName, start, end)
+}
+
+func (r *countingRepository) ServiceMonthlySpend(ctx context.Context, userIDs []uuid.UUID, serviceName *string, start, end *time.Time, overlap repository.OverlapPolicy) ([]models.ServiceMonthSpend, error) {
+	r.serviceSpend.Add(1)
+	return r.SubscriptionRepository.ServiceMonthlySpend(ctx, userIDs, serviceName, start, end, overlap)
+}
+
+func newGraphQLRouter(t *testing.T, repo repository.SubscriptionRepository) *gin.Engine {
+	verifier, err := auth.NewVerifier(auth.VerifierConfig{
+		HS256Secret: testJWTSecret,
+		RolesClaim:  "roles",
+		AdminRole:   "admin",
+		TenantClaim: "tenant_id",
+	})
+	require.NoError(t, err)
+
+	router := gin.New()
+	api := router.Group("/", auth.Middleware(verifier, nil), tenant.Middleware(testTenantHeader, false))
+	graph.NewHandler(repo, graph.Limits{MaxDepth: 6, MaxComplexity: 1000}).RegisterRoutes(api)
+	return router
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func postGraphQL(t *testing.T, router *gin.Engine, token, query string) graphQLResponse {
+	body, err := json.Marshal(map[string]string{"query": query})
+	require.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var out graphQLResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out), resp.Body.String())
+	return out
+}
+
+func seedGraphQL(t *testing.T, repo repository.SubscriptionRepository, userID uuid.UUID, service string, price int, start, end string) models.Subscription {
+	startDate, err := time.Parse("01-2006", start)
+	require.NoError(t, err)
+	endDate, err := time.Parse("01-2006", end)
+	require.NoError(t, err)
+	endMonth := models.MonthYearDate(endDate)
+
+	sub := models.Subscription{
+		ServiceName: service,
+		Price:       price,
+		UserID:      userID,
+		StartDate:   models.MonthYearDate(startDate),
+		EndDate:     &endMonth,
+	}
+	ctx := tenant.WithID(context.Background(), tenant.Default)
+	require.NoError(t, repo.Create(ctx, &sub))
+	return sub
+}
+
+func TestGraphQLUserPageInOneBatch(t *testing.T) {
+	repo := &countingRepository{SubscriptionRepository: repository.NewMemorySubscriptionRepository()}
+	router := newGraphQLRouter(t, repo)
+	alice, bob := uuid.New(), uuid.New()
+	seedGraphQL(t, repo, alice, "Netflix", 500, "01-2025", "03-2025")
+	seedGraphQL(t, repo, alice, "Spotify", 200, "02-2025", "02-2025")
+	seedGraphQL(t, repo, bob, "Netflix", 300, "01-2025", "01-2025")
+
+	resp := postGraphQL(t, router, signHS256(t, jwt.MapClaims{"sub": uuid.NewString(), "roles": []string{"admin"}}), `{
+		users(ids: ["`+alice.String()+`", "`+bob.String()+`"]) {
+			id
+			subscriptions { serviceName startDate endDate }
+			monthlySpend(period: {start: "01-2025", end: "12-2025"}) { month net }
+			spendByService(period: {start: "01-2025", end: "12-2025"}) { serviceName net }
+			totalSpend(period: {start: "01-2025", end: "12-2025"})
+		}
+	}`)
+	require.Empty(t, resp.Errors)
+
+	var data struct {
+		Users []struct {
+			ID            string
+			Subscriptions []struct{ ServiceName, StartDate, EndDate string }
+			MonthlySpend  []struct {
+				Month string
+				Net   int
+			}
+			SpendByService []struct {
+				ServiceName string
+				Net         int
+			}
+			TotalSpend int
+		}
+	}
+	require.NoError(t, json.Unmarshal(resp.Data, &data))
+	require.Len(t, data.Users, 2)
+
+	a, b := data.Users[0], data.Users[1]
+	assert.Equal(t, alice.String(), a.ID)
+	require.Len(t, a.Subscriptions, 2)
+	assert.Equal(t, "Netflix", a.Subscriptions[0].ServiceName)
+	assert.Equal(t, "01-2025", a.Subscriptions[0].StartDate)
+	assert.Equal(t, "03-2025", a.Subscriptions[0].EndDate)
+	require.Len(t, a.MonthlySpend, 3)
+	assert.Equal(t, "02-2025", a.MonthlySpend[1].Month)
+	assert.Equal(t, 700, a.MonthlySpend[1].Net)
+	require.Len(t, a.SpendByService, 2)
+	assert.Equal(t, "Netflix", a.SpendByService[0].ServiceName)
+	assert.Equal(t, 1500, a.SpendByService[0].Net)
+	assert.Equal(t, 200, a.SpendByService[1].Net)
+	assert.Equal(t, 1700, a.TotalSpend)
+	assert.Equal(t, 300, b.TotalSpend)
+
+	assert.Equal(t, int32(1), repo.listByUsers.Load(), "подписки всех пользователей читаются одним вызовом")
+	assert.Equal(t, int32(1), repo.serviceSpend.Load(), "расходы с одинаковым фильтром читаются одним вызовом")
+}
+
+func TestGraphQLAccessAndLimits(t *testing.T) {
+	repo := repository.NewMemorySubscriptionRepository()
+	router := newGraphQLRouter(t, repo)
+	owner, other := uuid.New(), uuid.New()
+	seedGraphQL(t, repo, owner, "Netflix", 500, "01-2025", "03-2025")
+	token := signHS256(t, jwt.MapClaims{"sub": owner.String()})
+
+	resp := postGraphQL(t, router, token, `{ user(id: "`+owner.String()+`") { totalSpend(period: {start: "01-2025", end: "01-2025"}) } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"user": {"totalSpend": 500}}`, string(resp.Data))
+
+	resp = postGraphQL(t, router, token, `{ user(id: "`+other.String()+`") { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "FORBIDDEN", resp.Errors[0].Extensions["code"])
+
+	// чужая подписка выглядит так же, как несуществующая
+	foreign := seedGraphQL(t, repo, other, "Spotify", 200, "01-2025", "03-2025")
+	for _, id := range []uuid.UUID{foreign.ID, uuid.New()} {
+		resp = postGraphQL(t, router, token, `{ subscription(id: "`+id.String()+`") { id } }`)
+		require.Empty(t, resp.Errors)
+		assert.JSONEq(t, `{"subscription": null}`, string(resp.Data))
+	}
+
+	resp = postGraphQL(t, router, token, `{ user(id: "`+owner.String()+`") { subscriptions { user { subscriptions { user { subscriptions { id } } } } } } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "DEPTH_LIMIT_EXCEEDED", resp.Errors[0].Extensions["code"])
+
+	resp = postGraphQL(t, router, token, `{ user(id: "`+owner.String()+`") { subscriptions(limit: 100) { user { subscriptions(limit: 100) { id } } } } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "COMPLEXITY_LIMIT_EXCEEDED", resp.Errors[0].Extensions["code"])
+
+	// интроспекция не упирается в ограничение глубины
+	resp = postGraphQL(t, router, token, `{ __schema { types { fields { type { ofType { ofType { name } } } } } } }`)
+	assert.Empty(t, resp.Errors)
+}
@@ -0,0 +1,353 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	subscriptionsv1 "subscriptions-service/api/subscriptions/v1"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/grpcserver"
+	"subscriptions-service/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newGRPCClient поднимает gRPC-сервер с репозиторием в памяти и HS256-токенами
+func newGRPCClient(t *testing.T) (subscriptionsv1.SubscriptionServiceClient, *grpc.ClientConn) {
+	return newGRPCClientWithRepo(t, repository.NewMemorySubscriptionRepository())
+}
+
+func newGRPCClientWithRepo(t *testing.T, repo repository.SubscriptionRepository) (subscriptionsv1.SubscriptionServiceClient, *grpc.ClientConn) {
+	verifier, err := auth.NewVerifier(auth.VerifierConfig{
+		HS256Secret: testJWTSecret,
+		RolesClaim:  "roles",
+		AdminRole:   "admin",
+		TenantClaim: "tenant_id",
+	})
+	require.NoError(t, err)
+
+	srv, _ := grpcserver.New(grpcserver.Config{
+		Authenticate: grpcserver.Authenticate(verifier, nil),
+		TenantHeader: testTenantHeader,
+	}, repo)
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return subscriptionsv1.NewSubscriptionServiceClient(conn), conn
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestGRPCSubscriptionLifecycle(t *testing.T) {
+	client, _ := newGRPCClient(t)
+	userID := uuid.New()
+	ctx := withToken(signHS256(t, jwt.MapClaims{"sub": userID.String()}))
+
+	var header metadata.MD
+	created, err := client.CreateSubscription(ctx, &subscriptionsv1.CreateSubscriptionRequest{
+		Subscription: &subscriptionsv1.Subscription{
+			ServiceName: "Yandex Plus",
+			Price:       400,
+			StartDate:   &subscriptionsv1.YearMonth{Year: 2025, Month: 1},
+			EndDate:     &subscriptionsv1.YearMonth{Year: 2025, Month: 3},
+		},
+	}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.GetId())
+	assert.Equal(t, userID.String(), created.GetUserId(), "без user_id подписка создается для клиента")
+	assert.Len(t, header.Get("x-trace-id"), 1)
+
+	got, err := client.GetSubscription(ctx, &subscriptionsv1.GetSubscriptionRequest{Id: created.GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, "Yandex Plus", got.GetServiceName())
+	assert.Equal(t, int32(3), got.GetEndDate().GetMonth())
+
+	updated, err := client.UpdateSubscription(ctx, &subscriptionsv1.UpdateSubscriptionRequest{
+		Id:           created.GetId(),
+		Subscription: &subscriptionsv1.Subscription{Price: 500},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), updated.GetPrice())
+	assert.Equal(t, "Yandex Plus", updated.GetServiceName(), "пустые поля не меняются")
+
+	_, err = client.CreateSubscription(ctx, &subscriptionsv1.CreateSubscriptionRequest{
+		Subscription: &subscriptionsv1.Subscription{
+			ServiceName: "Kinopoisk",
+			Price:       300,
+			StartDate:   &subscriptionsv1.YearMonth{Year: 2025, Month: 2},
+			EndDate:     &subscriptionsv1.YearMonth{Year: 2025, Month: 2},
+		},
+	})
+	require.NoError(t, err)
+
+	stream, err := client.ListSubscriptions(ctx, &subscriptionsv1.ListSubscriptionsRequest{UserId: userID.String()})
+	require.NoError(t, err)
+	var names []string
+	for {
+		sub, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, sub.GetServiceName())
+	}
+	assert.Equal(t, []string{"Yandex Plus", "Kinopoisk"}, names)
+
+	sum, err := client.SumSubscriptions(ctx, &subscriptionsv1.SumSubscriptionsRequest{
+		UserId:    userID.String(),
+		StartDate: &subscriptionsv1.YearMonth{Year: 2025, Month: 1},
+		EndDate:   &subscriptionsv1.YearMonth{Year: 2025, Month: 12},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3*500+300), sum.GetSum())
+	assert.Equal(t, "max", sum.GetOverlap())
+
+	_, err = client.DeleteSubscription(ctx, &subscriptionsv1.DeleteSubscriptionRequest{Id: created.GetId()})
+	require.NoError(t, err)
+	_, err = client.GetSubscription(ctx, &subscriptionsv1.GetSubscriptionRequest{Id: created.GetId()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// TestGRPCListSubscriptionsPages проверяет, что поток из нескольких страниц репозитория
+// отдает каждую подписку ровно один раз и в порядке ListByUser
+func TestGRPCListSubscriptionsPages(t *testing.T) {
+	const total = 250 // больше двух страниц listPageSize
+	services := []string{"Netflix", "Spotify", "Yandex Plus"}
+
+	for _, impl := range repositoryImpls {
+		t.Run(impl.name, func(t *testing.T) {
+			repo := impl.new(t)
+			client, _ := newGRPCClientWithRepo(t, repo)
+			userID := uuid.New()
+
+			// даты и сервисы повторяются, порядок внутри страниц задает id
+			want := map[string]bool{}
+			for i := 0; i < total; i++ {
+				sub := createSub(t, repo, conformCtx, userID, services[i%len(services)], 100, month(2025, time.Month(i%4+1)), nil)
+				want[sub.ID.String()] = true
+			}
+
+			stream, err := client.ListSubscriptions(withToken(signHS256(t, jwt.MapClaims{"sub": userID.String()})),
+				&subscriptionsv1.ListSubscriptionsRequest{UserId: userID.String()})
+			require.NoError(t, err)
+
+			got := map[string]bool{}
+			var prev *subscriptionsv1.Subscription
+			for {
+				sub, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				assert.False(t, got[sub.GetId()], "подписка %s отдана дважды", sub.GetId())
+				got[sub.GetId()] = true
+				if prev != nil {
+					assert.True(t, listOrderLess(prev, sub), "%v перед %v", prev, sub)
+				}
+				prev = sub
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func listOrderLess(a, b *subscriptionsv1.Subscription) bool {
+	am := a.GetStartDate().GetYear()*12 + a.GetStartDate().GetMonth()
+	bm := b.GetStartDate().GetYear()*12 + b.GetStartDate().GetMonth()
+	if am != bm {
+		return am < bm
+	}
+	if a.GetServiceName() != b.GetServiceName() {
+		return a.GetServiceName() < b.GetServiceName()
+	}
+	return a.GetId() < b.GetId()
+}
+
+func TestGRPCAuthAndValidation(t *testing.T) {
+	client, _ := newGRPCClient(t)
+	owner, other := uuid.New(), uuid.New()
+	ownerCtx := withToken(signHS256(t, jwt.MapClaims{"sub": owner.String()}))
+	otherCtx := withToken(signHS256(t, jwt.MapClaims{"sub": other.String()}))
+
+	_, err := client.SumSubscriptions(context.Background(), &subscriptionsv1.SumSubscriptionsRequest{UserId: owner.String()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.SumSubscriptions(withToken("garbage"), &subscriptionsv1.SumSubscriptionsRequest{UserId: owner.String()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	created, err := client.CreateSubscription(ownerCtx, &subscriptionsv1.CreateSubscriptionRequest{
+		Subscription: &subscriptionsv1.Subscription{
+			ServiceName: "Spotify",
+			Price:       200,
+			StartDate:   &subscriptionsv1.YearMonth{Year: 2025, Month: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = client.GetSubscription(otherCtx, &subscriptionsv1.GetSubscriptionRequest{Id: created.GetId()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.SumSubscriptions(otherCtx, &subscriptionsv1.SumSubscriptionsRequest{UserId: owner.String()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// подписки другого арендатора не видны
+	acmeCtx := withToken(signHS256(t, jwt.MapClaims{"sub": owner.String(), "tenant_id": "acme"}))
+	_, err = client.GetSubscription(acmeCtx, &subscriptionsv1.GetSubscriptionRequest{Id: created.GetId()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// токен без арендатора не выбирает его метаданными
+	noTenantCtx := withToken(signHS256(t, jwt.MapClaims{"sub": owner.String(), "tenant_id": nil}))
+	_, err = client.GetSubscription(metadata.AppendToOutgoingContext(noTenantCtx, testTenantHeader, "acme"),
+		&subscriptionsv1.GetSubscriptionRequest{Id: created.GetId()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetSubscription(ownerCtx, &subscriptionsv1.GetSubscriptionRequest{Id: "not-a-uuid"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.SumSubscriptions(ownerCtx, &subscriptionsv1.SumSubscriptionsRequest{UserId: owner.String(), Overlap: "avg"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.SumSubscriptions(ownerCtx, &subscriptionsv1.SumSubscriptionsRequest{
+		UserId:    owner.String(),
+		StartDate: &subscriptionsv1.YearMonth{Year: 2025, Month: 13},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// fieldViolations возвращает поля из деталей BadRequest ошибки InvalidArgument
+func fieldViolations(t *testing.T, err error) []string {
+	t.Helper()
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code(), st.Message())
+	var fields []string
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+			}
+		}
+	}
+	return fields
+}
+
+func TestGRPCSubscriptionValidation(t *testing.T) {
+	client, _ := newGRPCClient(t)
+	userID := uuid.New()
+	ctx := withToken(signHS256(t, jwt.MapClaims{"sub": userID.String()}))
+	adminCtx := withToken(signHS256(t, jwt.MapClaims{"sub": uuid.NewString(), "roles": []string{"admin"}}))
+
+	valid := func() *subscriptionsv1.Subscription {
+		return &subscriptionsv1.Subscription{
+			ServiceName: "Netflix",
+			Price:       500,
+			StartDate:   &subscriptionsv1.YearMonth{Year: 2025, Month: 3},
+			EndDate:     &subscriptionsv1.YearMonth{Year: 2025, Month: 6},
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		ctx    context.Context
+		change func(*subscriptionsv1.Subscription)
+		fields []string
+	}{
+		{"zero price", ctx, func(s *subscriptionsv1.Subscription) { s.Price = 0 }, []string{"price"}},
+		{"negative price", ctx, func(s *subscriptionsv1.Subscription) { s.Price = -1 }, []string{"price"}},
+		{"empty service", ctx, func(s *subscriptionsv1.Subscription) { s.ServiceName = "" }, []string{"service_name"}},
+		{"admin without user", adminCtx, func(s *subscriptionsv1.Subscription) {}, []string{"user_id"}},
+		{"nil user", adminCtx, func(s *subscriptionsv1.Subscription) { s.UserId = uuid.Nil.String() }, []string{"user_id"}},
+		{"end before start", ctx, func(s *subscriptionsv1.Subscription) { s.EndDate = &subscriptionsv1.YearMonth{Year: 2025, Month: 2} }, []string{"end_date"}},
+		{"no start", ctx, func(s *subscriptionsv1.Subscription) { s.StartDate = nil }, []string{"start_date"}},
+		{"several", ctx, func(s *subscriptionsv1.Subscription) { s.Price, s.ServiceName = 0, "" }, []string{"service_name", "price"}},
+	} {
+		t.Run("create "+tc.name, func(t *testing.T) {
+			sub := valid()
+			tc.change(sub)
+			_, err := client.CreateSubscription(tc.ctx, &subscriptionsv1.CreateSubscriptionRequest{Subscription: sub})
+			assert.ElementsMatch(t, tc.fields, fieldViolations(t, err))
+		})
+	}
+
+	created, err := client.CreateSubscription(ctx, &subscriptionsv1.CreateSubscriptionRequest{Subscription: valid()})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		sub    *subscriptionsv1.Subscription
+		fields []string
+	}{
+		{"negative price", &subscriptionsv1.Subscription{Price: -100}, []string{"price"}},
+		{"nil user", &subscriptionsv1.Subscription{UserId: uuid.Nil.String()}, []string{"user_id"}},
+		{"end before stored start", &subscriptionsv1.Subscription{EndDate: &subscriptionsv1.YearMonth{Year: 2025, Month: 1}}, []string{"end_date"}},
+		{"start after stored end", &subscriptionsv1.Subscription{StartDate: &subscriptionsv1.YearMonth{Year: 2025, Month: 9}}, []string{"start_date"}},
+		{"invalid month", &subscriptionsv1.Subscription{StartDate: &subscriptionsv1.YearMonth{Year: 2025, Month: 13}}, []string{"start_date"}},
+	} {
+		t.Run("update "+tc.name, func(t *testing.T) {
+			_, err := client.UpdateSubscription(ctx, &subscriptionsv1.UpdateSubscriptionRequest{Id: created.GetId(), Subscription: tc.sub})
+			assert.ElementsMatch(t, tc.fields, fieldViolations(t, err))
+		})
+	}
+
+	got, err := client.GetSubscription(ctx, &subscriptionsv1.GetSubscriptionRequest{Id: created.GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), got.GetPrice(), "отклоненные изменения не сохраняются")
+	assert.Equal(t, int32(3), got.GetStartDate().GetMonth())
+}
+
+func TestGRPCHidesRepositoryErrors(t *testing.T) {
+	ctx := withToken(signHS256(t, jwt.MapClaims{"sub": uuid.NewString(), "roles": []string{"admin"}}))
+	for _, tc := range []struct {
+		err  error
+		code codes.Code
+	}{
+		{errors.New(`ERROR: relation "subscriptions_secret" does not exist (SQLSTATE 42P01)`), codes.Internal},
+		{fmt.Errorf("select subscription: %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+		{fmt.Errorf("select subscription: %w", context.Canceled), codes.Canceled},
+	} {
+		client, _ := newGRPCClientWithRepo(t, &failingRepository{err: tc.err})
+		_, err := client.GetSubscription(ctx, &subscriptionsv1.GetSubscriptionRequest{Id: uuid.NewString()})
+		st := status.Convert(err)
+		assert.Equal(t, tc.code, st.Code())
+		assert.NotContains(t, st.Message(), "subscriptions_secret")
+		assert.NotContains(t, st.Message(), "select subscription")
+	}
+}
+
+func TestGRPCHealthWithoutAuth(t *testing.T) {
+	_, conn := newGRPCClient(t)
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: subscriptionsv1.SubscriptionService_ServiceDesc.ServiceName,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
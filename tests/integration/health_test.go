@@ -0,0 +1,138 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/health"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHealthRouter(t *testing.T) (*gin.Engine, *health.Checker) {
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+
+	checker := health.NewChecker(time.Second)
+	checker.Add("database", true, health.DBPing(sqlDB))
+	checker.Add("migrations", true, health.SchemaVersion(sqlDB, migrator.Latest()))
+
+	router := gin.New()
+	router.GET("/livez", health.LiveHandler())
+	router.GET("/readyz", checker.ReadyHandler())
+	return router, checker
+}
+
+// setSchemaVersion подменяет версию схемы, не выполняя миграций, и возвращает ее после теста
+func setSchemaVersion(t *testing.T, version uint, dirty bool) {
+	require.NoError(t, db.Exec(`UPDATE schema_migrations SET version = ?, dirty = ?`, version, dirty).Error)
+	t.Cleanup(func() {
+		db.Exec(`UPDATE schema_migrations SET version = ?, dirty = false`, migrator.Latest())
+	})
+}
+
+func getReadiness(t *testing.T, router *gin.Engine) (int, health.Report) {
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var report health.Report
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+	return resp.Code, report
+}
+
+func TestReadyz(t *testing.T) {
+	router, checker := newHealthRouter(t)
+
+	worker := health.NewWorker("spend_aggregates_refresh")
+	checker.AddWorker(worker)
+	worker.Started()
+	worker.Report(nil)
+
+	code, report := getReadiness(t, router)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusOK, report.Status)
+	assert.Equal(t, health.StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, health.StatusOK, report.Checks["migrations"].Status)
+	assert.Equal(t, health.StatusOK, report.Checks["worker:spend_aggregates_refresh"].Status)
+	assert.Equal(t, true, report.Checks["worker:spend_aggregates_refresh"].Details["running"])
+
+	t.Run("failed worker does not make the service unready", func(t *testing.T) {
+		worker.Report(errors.New("refresh failed"))
+
+		code, report := getReadiness(t, router)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, health.StatusFail, report.Checks["worker:spend_aggregates_refresh"].Status)
+		assert.Equal(t, "refresh failed", report.Checks["worker:spend_aggregates_refresh"].Error)
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		checker.SetShuttingDown()
+
+		code, report := getReadiness(t, router)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.StatusFail, report.Status)
+		assert.Equal(t, health.StatusFail, report.Checks["shutdown"].Status)
+
+		req, _ := http.NewRequest("GET", "/livez", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+}
+
+func TestReadyzSchemaVersion(t *testing.T) {
+	router, _ := newHealthRouter(t)
+
+	status, err := migrator.Status(context.Background())
+	require.NoError(t, err)
+	require.Greater(t, len(status.Migrations), 1)
+	latest := migrator.Latest()
+	previous := status.Migrations[len(status.Migrations)-2].Version
+
+	tests := []struct {
+		name    string
+		version uint
+		dirty   bool
+		errText string
+	}{
+		{"older schema", previous, false, fmt.Sprintf("schema version %d, expected %d", previous, latest)},
+		{"dirty migration", latest, true, fmt.Sprintf("migration %d is dirty", latest)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setSchemaVersion(t, tt.version, tt.dirty)
+
+			code, report := getReadiness(t, router)
+			assert.Equal(t, http.StatusServiceUnavailable, code)
+			assert.Equal(t, health.StatusOK, report.Checks["database"].Status)
+			assert.Equal(t, health.StatusFail, report.Checks["migrations"].Status)
+			assert.Equal(t, tt.errText, report.Checks["migrations"].Error)
+		})
+	}
+}
+
+func TestReadyzDatabaseDown(t *testing.T) {
+	checker := health.NewChecker(100 * time.Millisecond)
+	checker.Add("database", true, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	router := gin.New()
+	router.GET("/readyz", checker.ReadyHandler())
+
+	code, report := getReadiness(t, router)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusFail, report.Checks["database"].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["database"].Error)
+}
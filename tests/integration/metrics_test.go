@@ -0,0 +1,68 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/handlers"
+	"subscriptions-service/internal/metrics"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+	"subscriptions-service/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrapeMetrics(t *testing.T, router *gin.Engine) string {
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	clearDB(db)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, metrics.RegisterDB(sqlDB))
+	require.NoError(t, metrics.RegisterActivity(repository.NewStatsRepository(db)))
+
+	router := gin.New()
+	router.Use(metrics.Middleware())
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	api := router.Group("/", auth.Disabled(), tenant.Middleware(testTenantHeader, true))
+	handlers.NewHandler(repository.NewSubscriptionRepository(db)).RegisterRoutes(api)
+
+	userID := uuid.New()
+	now := time.Now()
+	resp := doInTenant(router, "POST", "/subscriptions", "acme", apiv1.CreateSubscriptionRequest{
+		ServiceName: "Metrics Music",
+		Price:       intPtr(300),
+		UserID:      userID.String(),
+		StartDate:   now.Format(validation.MonthLayout),
+	})
+	require.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, http.StatusNotFound, doInTenant(router, "GET", "/subscriptions/"+uuid.New().String(), "acme", nil).Code)
+
+	body := scrapeMetrics(t, router)
+	assert.Contains(t, body, `subscriptions_http_request_duration_seconds_count{method="POST",route="/subscriptions",status="201"} 1`)
+	assert.Contains(t, body, `subscriptions_http_request_duration_seconds_count{method="GET",route="/subscriptions/:id",status="404"} 1`)
+	assert.Contains(t, body, `subscriptions_db_query_duration_seconds_count{operation="insert"}`)
+	assert.Contains(t, body, `subscriptions_created_total{service_name="Metrics Music"} 1`)
+	assert.Contains(t, body, `go_sql_open_connections{db_name="subscriptions"}`)
+	assert.Contains(t, body, `subscriptions_active{tenant="acme"} 1`)
+	assert.Contains(t, body, `subscriptions_active_users{tenant="acme"} 1`)
+}
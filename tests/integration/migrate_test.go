@@ -0,0 +1,107 @@
+package integration
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"subscriptions-service/internal/migrate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tableExists(t *testing.T, name string) bool {
+	var exists bool
+	require.NoError(t, db.Raw(`SELECT to_regclass(?) IS NOT NULL`, name).Scan(&exists).Error)
+	return exists
+}
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	clearDB(db)
+
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), status.Version)
+	assert.False(t, status.Dirty)
+	for _, m := range status.Migrations {
+		assert.True(t, m.Applied, m.Name)
+	}
+
+	n, err := migrator.Down(ctx, len(status.Migrations))
+	require.NoError(t, err)
+	assert.Equal(t, len(status.Migrations), n)
+	assert.False(t, tableExists(t, "subscriptions"))
+	assert.False(t, tableExists(t, "api_keys"))
+
+	down, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), down.Version)
+
+	// реплики стартуют одновременно: advisory lock пропускает миграции только одной из них
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		applied int
+	)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := migrator.Up(ctx)
+			assert.NoError(t, err)
+			mu.Lock()
+			applied += n
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, len(status.Migrations), applied)
+	assert.True(t, tableExists(t, "subscriptions"))
+	assert.True(t, tableExists(t, "rate_limit_buckets"))
+
+	n, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestMigrateGoto(t *testing.T) {
+	ctx := context.Background()
+	clearDB(db)
+
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	latest := migrator.Latest()
+	previous := status.Migrations[len(status.Migrations)-2].Version
+
+	n, err := migrator.Goto(ctx, previous)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.False(t, tableExists(t, "rate_limit_buckets"))
+
+	n, err = migrator.Goto(ctx, latest)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.True(t, tableExists(t, "rate_limit_buckets"))
+
+	_, err = migrator.Goto(ctx, 42)
+	assert.ErrorIs(t, err, migrate.ErrUnknownVersion)
+}
+
+func TestMigrateDirty(t *testing.T) {
+	ctx := context.Background()
+	latest := migrator.Latest()
+
+	require.NoError(t, db.Exec(`UPDATE schema_migrations SET dirty = true`).Error)
+	t.Cleanup(func() { db.Exec(`UPDATE schema_migrations SET version = ?, dirty = false`, latest) })
+
+	_, err := migrator.Up(ctx)
+	assert.ErrorIs(t, err, migrate.ErrDirty)
+
+	require.NoError(t, migrator.Force(ctx, latest))
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.Dirty)
+	assert.Equal(t, latest, status.Version)
+}
@@ -0,0 +1,276 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/handlers"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/openapi"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+	"subscriptions-service/internal/trace"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// specAnalytics отдает фиксированную аналитику: в тестах спецификации важна форма ответа
+type specAnalytics struct{}
+
+func (specAnalytics) PopularServices(context.Context, *time.Time, *time.Time, int) ([]models.ServicePopularity, error) {
+	return []models.ServicePopularity{{ServiceName: "Netflix", Subscriptions: 2, Users: 1}}, nil
+}
+
+func (specAnalytics) AverageSpend(context.Context, *time.Time, *time.Time) (*models.SpendAverage, []models.MonthlySpendAverage, error) {
+	avg := models.SpendAverage{Users: 1, Total: 500, Average: 500}
+	return &avg, []models.MonthlySpendAverage{{Month: month(2025, time.January), SpendAverage: avg}}, nil
+}
+
+func (specAnalytics) ChurnByMonth(context.Context, *time.Time, *time.Time) ([]models.MonthlyChurn, error) {
+	return []models.MonthlyChurn{{Month: month(2025, time.January), Started: 1, Active: 1}}, nil
+}
+
+func (specAnalytics) PriceDistribution(context.Context, *string, *time.Time, *time.Time) ([]models.PriceDistribution, error) {
+	return []models.PriceDistribution{{ServiceName: "Netflix", Subscriptions: 1, Min: 500, Max: 500, Average: 500, P25: 500, Median: 500, P75: 500}}, nil
+}
+
+// specAPIKeys хранит API-ключи в памяти
+type specAPIKeys struct {
+	mu   sync.Mutex
+	keys []models.APIKey
+}
+
+func (r *specAPIKeys) CreateAPIKey(_ context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key.ID = uuid.New()
+	key.TenantID = tenant.Default
+	key.CreatedAt = time.Now()
+	r.keys = append(r.keys, *key)
+	return nil
+}
+
+func (r *specAPIKeys) ListAPIKeys(context.Context) ([]models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.APIKey(nil), r.keys...), nil
+}
+
+func (r *specAPIKeys) RevokeAPIKey(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.keys {
+		if r.keys[i].ID == id && r.keys[i].RevokedAt == nil {
+			now := time.Now()
+			r.keys[i].RevokedAt = &now
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *specAPIKeys) FindAPIKeyByPrefix(context.Context, string) (*models.APIKey, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *specAPIKeys) RecordAPIKeyUsage(context.Context, uuid.UUID, time.Time, int) error {
+	return nil
+}
+
+// newSpecRouter монтирует REST API под /v1 так же, как main, и проверяет по спецификации
+// и запросы, и ответы, как тестовый стенд с OPENAPI_VALIDATE_RESPONSES=true
+func newSpecRouter(t *testing.T) (*gin.Engine, *openapi3.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(trace.Middleware())
+	api := router.Group(apiv1.Prefix, auth.Disabled(), tenant.Middleware(testTenantHeader, true),
+		openapi.Middleware(spec, apiv1.Prefix, openapi.Options{Requests: true, Responses: true}))
+
+	handlers.NewHandler(repository.NewMemorySubscriptionRepository()).RegisterRoutes(api)
+	admin := api.Group("/admin")
+	handlers.NewAnalyticsHandler(specAnalytics{}).RegisterRoutes(admin)
+	handlers.NewAPIKeyHandler(&specAPIKeys{}).RegisterRoutes(admin)
+	return router, spec
+}
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	router, spec := newSpecRouter(t)
+
+	var routes []string
+	for _, rt := range router.Routes() {
+		routes = append(routes, rt.Method+" "+strings.TrimPrefix(rt.Path, apiv1.Prefix))
+	}
+	var operations []string
+	for path, item := range spec.Paths.Map() {
+		for method := range item.Operations() {
+			operations = append(operations, method+" "+strings.NewReplacer("{", ":", "}", "").Replace(path))
+		}
+	}
+	sort.Strings(routes)
+	sort.Strings(operations)
+	assert.Equal(t, operations, routes, "каждый маршрут REST API описан в swagger.json и наоборот")
+}
+
+func TestOpenAPIResponsesMatchSpec(t *testing.T) {
+	router, _ := newSpecRouter(t)
+	userID := "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+
+	// каждый ответ проверяется по спецификации; расхождение превращается в 500 с описанием
+	expect := func(status int, method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		resp := sendJSON(t, router, method, apiv1.Prefix+path, body)
+		require.Equal(t, status, resp.Code, "%s %s: %s", method, path, resp.Body.String())
+		return resp
+	}
+
+	created := expect(http.StatusCreated, "POST", "/subscriptions", `{
+		"service_name": "Netflix", "price": 500, "user_id": "`+userID+`", "start_date": "01-2025"}`)
+	var sub apiv1.Subscription
+	decodeJSON(t, created, &sub)
+	byID := "/subscriptions/" + sub.ID.String()
+	expect(http.StatusCreated, "POST", "/subscriptions", `{
+		"service_name": "Netflix", "price": 300, "user_id": "`+userID+`", "start_date": "03-2025", "end_date": "04-2025"}`)
+
+	expect(http.StatusOK, "GET", byID, "")
+	expect(http.StatusNotFound, "GET", "/subscriptions/"+uuid.NewString(), "")
+	expect(http.StatusBadRequest, "GET", "/subscriptions/not-a-uuid", "")
+	expect(http.StatusOK, "PUT", byID, `{"price": 550, "end_date": null}`)
+	expect(http.StatusNotFound, "PUT", "/subscriptions/"+uuid.NewString(), `{"price": 550}`)
+	expect(http.StatusBadRequest, "PUT", byID, `{"price": 550, "color": "red"}`)
+
+	expect(http.StatusOK, "GET", "/subscriptions/list?user_id="+userID, "")
+	expect(http.StatusOK, "GET", "/subscriptions/list?user_id="+userID+"&limit=1&offset=1", "")
+	expect(http.StatusOK, "GET", "/subscriptions/sum?user_id="+userID+"&start_date=01-2025&end_date=06-2025", "")
+	expect(http.StatusOK, "GET", "/subscriptions/sum/monthly?user_id="+userID+"&start_date=01-2025&end_date=06-2025&overlap=sum", "")
+	expect(http.StatusOK, "GET", "/users/"+userID+"/subscriptions/conflicts", "")
+
+	discount := expect(http.StatusCreated, "POST", byID+"/discounts", `{"type": "percent", "value": 10, "start_date": "02-2025"}`)
+	var d apiv1.Discount
+	decodeJSON(t, discount, &d)
+	expect(http.StatusOK, "GET", byID+"/discounts", "")
+	expect(http.StatusOK, "DELETE", byID+"/discounts/"+d.ID.String(), "")
+	expect(http.StatusNotFound, "DELETE", byID+"/discounts/"+d.ID.String(), "")
+
+	for _, path := range []string{"popular-services", "average-spend", "churn", "price-distribution?service_name=Netflix"} {
+		expect(http.StatusOK, "GET", "/admin/analytics/"+path, "")
+	}
+
+	key := expect(http.StatusCreated, "POST", "/admin/api-keys", `{"name": "billing", "scopes": ["subscriptions:read"]}`)
+	var k apiv1.CreateAPIKeyResponse
+	decodeJSON(t, key, &k)
+	expect(http.StatusOK, "GET", "/admin/api-keys", "")
+	expect(http.StatusOK, "DELETE", "/admin/api-keys/"+k.ID.String(), "")
+	expect(http.StatusNotFound, "DELETE", "/admin/api-keys/"+k.ID.String(), "")
+
+	expect(http.StatusOK, "DELETE", byID, "")
+	expect(http.StatusNotFound, "DELETE", byID, "")
+}
+
+func TestOpenAPIRejectsRequestsOutsideSpec(t *testing.T) {
+	router, _ := newSpecRouter(t)
+
+	resp := sendJSON(t, router, "GET", "/v1/subscriptions/list?user_id="+uuid.NewString()+"&limit=ten", "")
+	assert.Contains(t, violationsByField(t, resp), "limit")
+
+	resp = sendJSON(t, router, "GET", "/v1/subscriptions/sum", "")
+	assert.Equal(t, "is required", violationsByField(t, resp)["user_id"])
+
+	resp = sendJSON(t, router, "GET", "/v1/subscriptions/sum?user_id="+uuid.NewString()+"&overlap=min", "")
+	assert.Contains(t, violationsByField(t, resp), "overlap")
+
+	resp = sendJSON(t, router, "POST", "/v1/subscriptions", `{"service_name": "Netflix", "price": "500", "start_date": "01-2025"}`)
+	assert.Contains(t, violations(t, resp), "/price")
+}
+
+func TestOpenAPIReportsResponseDrift(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	router := gin.New()
+	api := router.Group(apiv1.Prefix, openapi.Middleware(spec, apiv1.Prefix, openapi.Options{Responses: true}))
+	// обработчики, которые разошлись со спецификацией: цена строкой и незадокументированный статус
+	api.GET("/subscriptions/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id"), "price": "500"})
+	})
+	api.DELETE("/subscriptions/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, method := range []string{"GET", "DELETE"} {
+		resp := sendJSON(t, router, method, "/v1/subscriptions/"+uuid.NewString(), "")
+		assert.Equal(t, http.StatusInternalServerError, resp.Code, method)
+		assert.Equal(t, apierror.ContentType, resp.Header().Get("Content-Type"), method)
+
+		var problem apierror.Problem
+		decodeJSON(t, resp, &problem)
+		assert.Equal(t, apierror.CodeInternal, problem.Code, method)
+		assert.Contains(t, problem.Detail, "does not match the API specification", method)
+	}
+}
+
+func TestOpenAPI31Document(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+	generated, err := openapi.V31(spec)
+	require.NoError(t, err)
+
+	committed, err := os.ReadFile("../../api/openapi/openapi.json")
+	require.NoError(t, err)
+	assert.JSONEq(t, string(generated), string(committed), "api/openapi/openapi.json устарел: make openapi")
+
+	var doc struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas         map[string]map[string]any `json:"schemas"`
+			SecuritySchemes map[string]map[string]any `json:"securitySchemes"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(generated, &doc))
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+	assert.Equal(t, "bearer", doc.Components.SecuritySchemes["BearerAuth"]["scheme"])
+	assert.Equal(t, "apiKey", doc.Components.SecuritySchemes["ApiKeyAuth"]["type"])
+	assert.Contains(t, doc.Components.Schemas, "Problem")
+
+	props := doc.Components.Schemas["Subscription"]["properties"].(map[string]any)
+	end := props["end_date"].(map[string]any)
+	assert.Equal(t, []any{"string", "null"}, end["type"])
+	assert.Equal(t, openapi.MonthPattern, end["pattern"])
+	assert.NotContains(t, end, "nullable")
+}
+
+func decodeJSON(t *testing.T, resp *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), v), resp.Body.String())
+}
+
+// violationsByField возвращает нарушения из ответа 400 как field -> message
+func violationsByField(t *testing.T, resp *httptest.ResponseRecorder) map[string]string {
+	require.Equal(t, http.StatusBadRequest, resp.Code, resp.Body.String())
+	var problem apierror.Problem
+	decodeJSON(t, resp, &problem)
+	assert.Equal(t, apierror.CodeValidationFailed, problem.Code)
+
+	out := make(map[string]string, len(problem.Errors))
+	for _, e := range problem.Errors {
+		out[e.Field] = e.Message
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSubscriptionSumOverlapPolicies(t *testing.T) {
+	clearDB(db)
+
+	userID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	start := models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	end := models.MonthYearDate(time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC))
+	startIn := models.MonthYearDate(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	endIn := models.MonthYearDate(time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC))
+
+	outer := models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: "Spotify",
+		Price:       200,
+		UserID:      userID,
+		StartDate:   start,
+		EndDate:     &end,
+	}
+	inner := models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: "Spotify",
+		Price:       100,
+		UserID:      userID,
+		StartDate:   startIn,
+		EndDate:     &endIn,
+	}
+	assert.NoError(t, db.Create(&outer).Error)
+	assert.NoError(t, db.Create(&inner).Error)
+
+	cases := []struct {
+		overlap   string
+		sum       int
+		collapsed []apiv1.CollapsedOverlap
+	}{
+		{
+			overlap: "max",
+			sum:     200 * 6,
+			collapsed: []apiv1.CollapsedOverlap{{
+				ServiceName: "Spotify",
+				KeptID:      outer.ID,
+				CollapsedID: inner.ID,
+				StartMonth:  "03-2025",
+				EndMonth:    "07-2025",
+				Months:      5,
+			}},
+		},
+		{
+			overlap:   "sum",
+			sum:       200*6 + 100*5,
+			collapsed: []apiv1.CollapsedOverlap{},
+		},
+		{
+			overlap: "latest",
+			sum:     200 + 100*5,
+			collapsed: []apiv1.CollapsedOverlap{{
+				ServiceName: "Spotify",
+				KeptID:      inner.ID,
+				CollapsedID: outer.ID,
+				StartMonth:  "03-2025",
+				EndMonth:    "07-2025",
+				Months:      5,
+			}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.overlap, func(t *testing.T) {
+			url := "/subscriptions/sum?user_id=" + userID.String() +
+				"&start_date=02-2025&end_date=07-2025&overlap=" + tc.overlap
+
+			req, _ := http.NewRequest("GET", url, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+
+			var result apiv1.SumResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &result)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.sum, result.Sum)
+			assert.Equal(t, tc.overlap, result.Overlap)
+			assert.Equal(t, tc.collapsed, result.Collapsed)
+		})
+	}
+
+	url := "/subscriptions/sum?user_id=" + userID.String() + "&overlap=min"
+
+	req, _ := http.NewRequest("GET", url, nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
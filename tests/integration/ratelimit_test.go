@@ -0,0 +1,152 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/handlers"
+	"subscriptions-service/internal/ratelimit"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// почти не пополняющееся ведро, чтобы тест не зависел от времени выполнения
+var testRateLimits = ratelimit.Config{Capacity: 3, RefillPerSecond: 0.01}
+
+func newRateLimitedRouter(t *testing.T, store ratelimit.Store) *gin.Engine {
+	verifier, err := auth.NewVerifier(auth.VerifierConfig{HS256Secret: testJWTSecret})
+	require.NoError(t, err)
+
+	router := gin.New()
+	api := router.Group("/",
+		auth.Middleware(verifier, nil),
+		tenant.Middleware(testTenantHeader, false),
+		ratelimit.Middleware(store, testRateLimits, ratelimit.Costs{"GET /subscriptions/sum": 2}),
+	)
+	handlers.NewHandler(repository.NewSubscriptionRepository(db)).RegisterRoutes(api)
+	return router
+}
+
+func TestRateLimitPerClient(t *testing.T) {
+	clearDB(db)
+	router := newRateLimitedRouter(t, ratelimit.NewMemoryStore(testRateLimits))
+
+	first := uuid.New()
+	firstToken := signHS256(t, jwt.MapClaims{"sub": first.String()})
+	path := "/subscriptions/list?user_id=" + first.String()
+
+	for i := 0; i < 3; i++ {
+		resp := doAuthorized(router, "GET", path, firstToken)
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "3", resp.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(2-i), resp.Header().Get("RateLimit-Remaining"))
+		assert.NotEmpty(t, resp.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "3;w=300", resp.Header().Get("RateLimit-Policy"))
+	}
+
+	resp := doAuthorized(router, "GET", path, firstToken)
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "0", resp.Header().Get("RateLimit-Remaining"))
+	retryAfter, err := strconv.Atoi(resp.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.Greater(t, retryAfter, 0)
+
+	// у другого пользователя свое ведро
+	second := uuid.New()
+	secondToken := signHS256(t, jwt.MapClaims{"sub": second.String()})
+	assert.Equal(t, http.StatusOK, doAuthorized(router, "GET", "/subscriptions/list?user_id="+second.String(), secondToken).Code)
+}
+
+func TestRateLimitRouteCost(t *testing.T) {
+	clearDB(db)
+	router := newRateLimitedRouter(t, ratelimit.NewMemoryStore(testRateLimits))
+
+	userID := uuid.New()
+	token := signHS256(t, jwt.MapClaims{"sub": userID.String()})
+	sum := "/subscriptions/sum?user_id=" + userID.String()
+
+	resp := doAuthorized(router, "GET", sum, token)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "1", resp.Header().Get("RateLimit-Remaining"))
+
+	assert.Equal(t, http.StatusTooManyRequests, doAuthorized(router, "GET", sum, token).Code)
+	assert.Equal(t, http.StatusOK, doAuthorized(router, "GET", "/subscriptions/list?user_id="+userID.String(), token).Code)
+}
+
+func TestRateLimitPostgresStoreSharedAcrossReplicas(t *testing.T) {
+	require.NoError(t, db.Exec("DELETE FROM rate_limit_buckets").Error)
+
+	replicaA := newRateLimitedRouter(t, ratelimit.NewPostgresStore(db, testRateLimits))
+	replicaB := newRateLimitedRouter(t, ratelimit.NewPostgresStore(db, testRateLimits))
+
+	userID := uuid.New()
+	token := signHS256(t, jwt.MapClaims{"sub": userID.String()})
+	path := "/subscriptions/list?user_id=" + userID.String()
+
+	assert.Equal(t, http.StatusOK, doAuthorized(replicaA, "GET", path, token).Code)
+	assert.Equal(t, http.StatusOK, doAuthorized(replicaB, "GET", path, token).Code)
+	assert.Equal(t, http.StatusOK, doAuthorized(replicaA, "GET", path, token).Code)
+
+	resp := doAuthorized(replicaB, "GET", path, token)
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+
+	// ведро с окном в 1 мс успевает наполниться и удаляется как неиспользуемое
+	time.Sleep(10 * time.Millisecond)
+	deleted, err := ratelimit.NewPostgresStore(db, ratelimit.Config{Capacity: 1, RefillPerSecond: 1000}).DeleteIdle(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestRateLimitByIPWithoutUser(t *testing.T) {
+	router := gin.New()
+	store := ratelimit.NewMemoryStore(ratelimit.Config{Capacity: 1, RefillPerSecond: 0.01})
+	router.GET("/ping", ratelimit.Middleware(store, ratelimit.Config{Capacity: 1, RefillPerSecond: 0.01}, nil), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	do := func(ip string) int {
+		req, _ := http.NewRequest("GET", "/ping", nil)
+		req.RemoteAddr = ip + ":1234"
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+	assert.Equal(t, http.StatusNoContent, do("10.0.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, do("10.0.0.1"))
+	assert.Equal(t, http.StatusNoContent, do("10.0.0.2"))
+}
+
+func TestRateLimitPerTenant(t *testing.T) {
+	verifier, err := auth.NewVerifier(auth.VerifierConfig{HS256Secret: testJWTSecret, TenantClaim: "tenant_id"})
+	require.NoError(t, err)
+	limits := ratelimit.Config{Capacity: 1, RefillPerSecond: 0.01}
+
+	router := gin.New()
+	router.GET("/ping",
+		auth.Middleware(verifier, nil),
+		tenant.Middleware(testTenantHeader, false),
+		ratelimit.Middleware(ratelimit.NewMemoryStore(limits), limits, nil),
+		func(c *gin.Context) { c.Status(http.StatusNoContent) },
+	)
+
+	// один и тот же пользователь в двух арендаторах
+	userID := uuid.NewString()
+	acme := signHS256(t, jwt.MapClaims{"sub": userID, "tenant_id": "acme"})
+	globex := signHS256(t, jwt.MapClaims{"sub": userID, "tenant_id": "globex"})
+
+	assert.Equal(t, http.StatusNoContent, doAuthorized(router, "GET", "/ping", acme).Code)
+	assert.Equal(t, http.StatusTooManyRequests, doAuthorized(router, "GET", "/ping", acme).Code)
+	assert.Equal(t, http.StatusNoContent, doAuthorized(router, "GET", "/ping", globex).Code)
+}
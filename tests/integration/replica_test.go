@@ -0,0 +1,130 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/database"
+	"subscriptions-service/internal/handlers"
+	"subscriptions-service/internal/migrate"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/replica"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+	"subscriptions-service/migrations"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReplicaRouter подключает в качестве реплики отдельную пустую базу со схемой,
+// поэтому по ответам видно, с какой базы прочитаны данные
+func newReplicaRouter(t *testing.T, window time.Duration) *gin.Engine {
+	cfg := testConfig(t)
+	replicaCfg := cfg
+	replicaCfg.DB.Name = cfg.DB.Name + "_replica"
+
+	dropReplica := func() {
+		db.Exec(fmt.Sprintf(`DROP DATABASE IF EXISTS %q WITH (FORCE)`, replicaCfg.DB.Name))
+	}
+	dropReplica()
+	require.NoError(t, db.Exec(fmt.Sprintf(`CREATE DATABASE %q`, replicaCfg.DB.Name)).Error)
+	t.Cleanup(dropReplica)
+
+	replicaDB, err := database.Connect(replicaCfg)
+	require.NoError(t, err)
+	replicaSQL, err := replicaDB.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { replicaSQL.Close() })
+	runner, err := migrate.New(replicaSQL, migrations.FS)
+	require.NoError(t, err)
+	_, err = runner.Up(context.Background())
+	require.NoError(t, err)
+
+	primary, err := database.Connect(cfg)
+	require.NoError(t, err)
+	primarySQL, err := primary.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { primarySQL.Close() })
+	require.NoError(t, replica.Register(primary, []string{database.DSN(replicaCfg.DB)}, replica.Pool{MaxOpenConns: 2}))
+
+	router := gin.New()
+	api := router.Group("/", auth.Disabled(), tenant.Middleware(testTenantHeader, true), replica.ReadYourWrites(window))
+	handlers.NewHandler(repository.NewSubscriptionRepository(primary)).RegisterRoutes(api)
+	return router
+}
+
+func doAsClient(router *gin.Engine, client, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = client + ":40000"
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestReplicaRouting(t *testing.T) {
+	clearDB(db)
+	window := time.Second
+	router := newReplicaRouter(t, window)
+
+	const writer, reader = "10.0.0.1", "10.0.0.2"
+	userID := uuid.New()
+	resp := doAsClient(router, writer, "POST", "/subscriptions", apiv1.CreateSubscriptionRequest{
+		ServiceName: "Replica Music",
+		Price:       intPtr(250),
+		UserID:      userID.String(),
+		StartDate:   "01-2025",
+		EndDate:     ptr("02-2025"),
+	})
+	require.Equal(t, http.StatusCreated, resp.Code)
+	var created models.Subscription
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+
+	getPath := "/subscriptions/" + created.ID.String()
+	listPath := "/subscriptions/list?user_id=" + userID.String()
+	sumPath := "/subscriptions/sum?user_id=" + userID.String() + "&start_date=01-2025&end_date=12-2025"
+
+	t.Run("writer reads its own writes from the primary", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, doAsClient(router, writer, "GET", getPath, nil).Code)
+
+		var subs []models.Subscription
+		require.NoError(t, json.Unmarshal(doAsClient(router, writer, "GET", listPath, nil).Body.Bytes(), &subs))
+		assert.Len(t, subs, 1)
+
+		var sum apiv1.SumResponse
+		require.NoError(t, json.Unmarshal(doAsClient(router, writer, "GET", sumPath, nil).Body.Bytes(), &sum))
+		assert.Equal(t, 500, sum.Sum)
+	})
+
+	t.Run("other clients read from the replica", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, doAsClient(router, reader, "GET", getPath, nil).Code)
+
+		var subs []models.Subscription
+		require.NoError(t, json.Unmarshal(doAsClient(router, reader, "GET", listPath, nil).Body.Bytes(), &subs))
+		assert.Empty(t, subs)
+
+		var sum apiv1.SumResponse
+		require.NoError(t, json.Unmarshal(doAsClient(router, reader, "GET", sumPath, nil).Body.Bytes(), &sum))
+		assert.Equal(t, 0, sum.Sum)
+	})
+
+	t.Run("pin expires after the window", func(t *testing.T) {
+		time.Sleep(window)
+		assert.Equal(t, http.StatusNotFound, doAsClient(router, writer, "GET", getPath, nil).Code)
+	})
+}
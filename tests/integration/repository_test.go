@@ -0,0 +1,463 @@
+package integration
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/config"
+	"subscriptions-service/internal/database"
+	"subscriptions-service/internal/migrate"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+	"subscriptions-service/migrations"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// repositoryImpls - реализации SubscriptionRepository, которые проходят один и тот же
+// набор проверок, чтобы их поведение не расходилось. Каждый вызов возвращает пустое хранилище.
+var repositoryImpls = []struct {
+	name string
+	new  func(t *testing.T) repository.SubscriptionRepository
+}{
+	{"gorm", func(t *testing.T) repository.SubscriptionRepository {
+		require.NoError(t, clearDB(db))
+		return repository.NewSubscriptionRepository(db)
+	}},
+	{"memory", func(t *testing.T) repository.SubscriptionRepository {
+		return repository.NewMemorySubscriptionRepository()
+	}},
+	{"sqlite", func(t *testing.T) repository.SubscriptionRepository {
+		return repository.NewSQLiteSubscriptionRepository(openSQLite(t))
+	}},
+}
+
+// openSQLite создает мигрированную базу SQLite во временном каталоге теста
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := config.Default()
+	cfg.DB.Driver = "sqlite"
+	cfg.DB.Path = filepath.Join(t.TempDir(), "subscriptions.db")
+
+	sqliteDB, err := database.Connect(cfg)
+	require.NoError(t, err)
+	sqlDB, err := sqliteDB.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	runner, err := migrate.NewSQLite(sqlDB, migrations.SQLite)
+	require.NoError(t, err)
+	_, err = runner.Up(context.Background())
+	require.NoError(t, err)
+	return sqliteDB
+}
+
+func TestSubscriptionRepositoryConformance(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, repo repository.SubscriptionRepository)
+	}{
+		{"CRUD", conformCRUD},
+		{"TenantIsolation", conformTenantIsolation},
+		{"ListByUser", conformListByUser},
+		{"BatchedReads", conformBatchedReads},
+		{"Sum", conformSum},
+		{"CollapsedOverlaps", conformCollapsedOverlaps},
+		{"Discounts", conformDiscounts},
+		{"Conflicts", conformConflicts},
+	}
+	for _, impl := range repositoryImpls {
+		t.Run(impl.name, func(t *testing.T) {
+			for _, c := range cases {
+				t.Run(c.name, func(t *testing.T) {
+					c.run(t, impl.new(t))
+				})
+			}
+		})
+	}
+}
+
+var conformCtx = tenant.WithID(context.Background(), tenant.Default)
+
+func month(year int, m time.Month) models.MonthYearDate {
+	return models.MonthYearDate(time.Date(year, m, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func monthPtr(year int, m time.Month) *models.MonthYearDate {
+	d := month(year, m)
+	return &d
+}
+
+func timePtr(year int, m time.Month) *time.Time {
+	d := time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+	return &d
+}
+
+func createSub(t *testing.T, repo repository.SubscriptionRepository, ctx context.Context, userID uuid.UUID, service string, price int, start models.MonthYearDate, end *models.MonthYearDate) models.Subscription {
+	t.Helper()
+	sub := models.Subscription{ServiceName: service, Price: price, UserID: userID, StartDate: start, EndDate: end}
+	require.NoError(t, repo.Create(ctx, &sub))
+	require.NotEqual(t, uuid.Nil, sub.ID)
+	return sub
+}
+
+func conformCRUD(t *testing.T, repo repository.SubscriptionRepository) {
+	userID := uuid.New()
+	sub := createSub(t, repo, conformCtx, userID, "Netflix", 499, month(2025, 1), nil)
+
+	got, err := repo.Get(conformCtx, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Netflix", got.ServiceName)
+	assert.Equal(t, 499, got.Price)
+	assert.Equal(t, userID, got.UserID)
+	assert.True(t, time.Time(got.StartDate).Equal(time.Time(month(2025, 1))))
+	assert.Nil(t, got.EndDate)
+
+	// пустые поля не меняются, как в Updates GORM
+	require.NoError(t, repo.Update(conformCtx, sub.ID, &models.Subscription{Price: 599, EndDate: monthPtr(2025, 6)}))
+	got, err = repo.Get(conformCtx, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Netflix", got.ServiceName)
+	assert.Equal(t, 599, got.Price)
+	require.NotNil(t, got.EndDate)
+	assert.True(t, time.Time(*got.EndDate).Equal(time.Time(month(2025, 6))))
+
+	assert.ErrorIs(t, repo.Update(conformCtx, uuid.New(), &models.Subscription{Price: 1}), gorm.ErrRecordNotFound)
+
+	require.NoError(t, repo.Delete(conformCtx, sub.ID))
+	_, err = repo.Get(conformCtx, sub.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.NoError(t, repo.Delete(conformCtx, sub.ID))
+
+	assert.ErrorIs(t, repo.Create(context.Background(), &models.Subscription{}), tenant.ErrNoTenant)
+	_, err = repo.Get(context.Background(), sub.ID)
+	assert.ErrorIs(t, err, tenant.ErrNoTenant)
+}
+
+func conformTenantIsolation(t *testing.T, repo repository.SubscriptionRepository) {
+	otherCtx := tenant.WithID(context.Background(), "conformance-other")
+	userID := uuid.New()
+	sub := createSub(t, repo, conformCtx, userID, "Netflix", 499, month(2025, 1), monthPtr(2025, 3))
+
+	_, err := repo.Get(otherCtx, sub.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.Update(otherCtx, sub.ID, &models.Subscription{Price: 1}), gorm.ErrRecordNotFound)
+
+	subs, err := repo.ListByUser(otherCtx, userID, nil, nil, nil, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, subs)
+
+	sum, err := repo.SumByUserAndService(otherCtx, userID, nil, nil, nil, repository.OverlapMax)
+	require.NoError(t, err)
+	assert.Zero(t, sum)
+
+	require.NoError(t, repo.Delete(otherCtx, sub.ID))
+	got, err := repo.Get(conformCtx, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 499, got.Price)
+}
+
+func conformListByUser(t *testing.T, repo repository.SubscriptionRepository) {
+	userID := uuid.New()
+	netflix := createSub(t, repo, conformCtx, userID, "Netflix", 499, month(2025, 1), monthPtr(2025, 3))
+	spotify := createSub(t, repo, conformCtx, userID, "Spotify", 199, month(2025, 2), monthPtr(2025, 8))
+	open := createSub(t, repo, conformCtx, userID, "Netflix", 599, month(2025, 6), nil)
+	createSub(t, repo, conformCtx, uuid.New(), "Netflix", 499, month(2025, 1), nil)
+
+	ids := func(subs []models.Subscription) []uuid.UUID {
+		out := make([]uuid.UUID, 0, len(subs))
+		for _, s := range subs {
+			out = append(out, s.ID)
+		}
+		return out
+	}
+
+	subs, err := repo.ListByUser(conformCtx, userID, nil, nil, nil, 10, 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{netflix.ID, spotify.ID, open.ID}, ids(subs))
+
+	service := "Netflix"
+	subs, err = repo.ListByUser(conformCtx, userID, &service, nil, nil, 10, 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{netflix.ID, open.ID}, ids(subs))
+
+	// период апрель-май задевает только Spotify
+	subs, err = repo.ListByUser(conformCtx, userID, nil, timePtr(2025, 4), timePtr(2025, 5), 10, 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{spotify.ID}, ids(subs))
+
+	subs, err = repo.ListByUser(conformCtx, userID, nil, timePtr(2025, 9), nil, 10, 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{open.ID}, ids(subs))
+
+	first, err := repo.ListByUser(conformCtx, userID, nil, nil, nil, 2, 0)
+	require.NoError(t, err)
+	rest, err := repo.ListByUser(conformCtx, userID, nil, nil, nil, 2, 2)
+	require.NoError(t, err)
+	assert.Len(t, first, 2)
+	assert.Len(t, rest, 1)
+}
+
+func conformBatchedReads(t *testing.T, repo repository.SubscriptionRepository) {
+	alice, bob := uuid.New(), uuid.New()
+	aliceNetflix := createSub(t, repo, conformCtx, alice, "Netflix", 500, month(2025, 1), monthPtr(2025, 3))
+	aliceSpotify := createSub(t, repo, conformCtx, alice, "Spotify", 200, month(2025, 2), monthPtr(2025, 2))
+	bobSpotify := createSub(t, repo, conformCtx, bob, "Spotify", 300, month(2025, 1), monthPtr(2025, 2))
+	// посторонний пользователь и другой арендатор не попадают в выборку
+	createSub(t, repo, conformCtx, uuid.New(), "Netflix", 100, month(2025, 1), nil)
+	createSub(t, repo, tenant.WithID(context.Background(), "conformance-other"), alice, "Netflix", 100, month(2025, 1), nil)
+
+	users := []uuid.UUID{alice, bob}
+	subs, err := repo.ListByUsers(conformCtx, users, nil, nil, nil)
+	require.NoError(t, err)
+	byUser := make(map[uuid.UUID][]uuid.UUID)
+	for _, s := range subs {
+		byUser[s.UserID] = append(byUser[s.UserID], s.ID)
+	}
+	assert.Equal(t, []uuid.UUID{aliceNetflix.ID, aliceSpotify.ID}, byUser[alice], "ordered by start date")
+	assert.Equal(t, []uuid.UUID{bobSpotify.ID}, byUser[bob])
+	assert.Len(t, subs, 3)
+
+	subs, err = repo.ListByUsers(conformCtx, users, ptr("Spotify"), timePtr(2025, 2), timePtr(2025, 2))
+	require.NoError(t, err)
+	assert.Len(t, subs, 2)
+
+	subs, err = repo.ListByUsers(conformCtx, nil, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, subs)
+
+	spend, err := repo.ServiceMonthlySpend(conformCtx, users, nil, timePtr(2025, 1), timePtr(2025, 3), repository.OverlapMax)
+	require.NoError(t, err)
+	type row struct {
+		user    uuid.UUID
+		service string
+		month   models.MonthYearDate
+		net     int
+	}
+	got := make(map[row]bool)
+	for _, s := range spend {
+		ts := time.Time(s.Month)
+		assert.Equal(t, s.Gross-s.Discount, s.Net)
+		got[row{s.UserID, s.ServiceName, month(ts.Year(), ts.Month()), s.Net}] = true
+	}
+	assert.Equal(t, map[row]bool{
+		{alice, "Netflix", month(2025, 1), 500}: true,
+		{alice, "Netflix", month(2025, 2), 500}: true,
+		{alice, "Netflix", month(2025, 3), 500}: true,
+		{alice, "Spotify", month(2025, 2), 200}: true,
+		{bob, "Spotify", month(2025, 1), 300}:   true,
+		{bob, "Spotify", month(2025, 2), 300}:   true,
+	}, got)
+
+	// помесячные суммы по сервисам сходятся с суммой пользователя
+	for _, userID := range users {
+		sum, err := repo.SumByUserAndService(conformCtx, userID, nil, timePtr(2025, 1), timePtr(2025, 3), repository.OverlapMax)
+		require.NoError(t, err)
+		net := 0
+		for _, s := range spend {
+			if s.UserID == userID {
+				net += s.Net
+			}
+		}
+		assert.Equal(t, sum, net)
+	}
+
+	spend, err = repo.ServiceMonthlySpend(conformCtx, users, ptr("Netflix"), timePtr(2025, 2), timePtr(2025, 2), repository.OverlapMax)
+	require.NoError(t, err)
+	require.Len(t, spend, 1)
+	assert.Equal(t, alice, spend[0].UserID)
+	assert.Equal(t, 500, spend[0].Net)
+}
+
+func conformSum(t *testing.T, repo repository.SubscriptionRepository) {
+	userID := uuid.New()
+	// Spotify: внешняя запись на весь 2024 год и вложенная дешевая на март-июль
+	outer := createSub(t, repo, conformCtx, userID, "Spotify", 200, month(2024, 1), monthPtr(2024, 12))
+	createSub(t, repo, conformCtx, userID, "Spotify", 100, month(2024, 3), monthPtr(2024, 7))
+	// Netflix переходит через границу года
+	createSub(t, repo, conformCtx, userID, "Netflix", 500, month(2024, 11), monthPtr(2025, 2))
+	// бессрочная подписка ограничивается концом периода
+	createSub(t, repo, conformCtx, userID, "Yandex Plus", 300, month(2024, 12), nil)
+
+	require.NoError(t, repo.CreateDiscount(conformCtx, &models.Discount{
+		SubscriptionID: outer.ID, Type: models.DiscountPercent, Value: 25,
+		StartDate: month(2024, 1), EndDate: monthPtr(2024, 2),
+	}))
+
+	allOf2024 := 200*12 - 50*2 + 500*2 + 300
+	cases := []struct {
+		name    string
+		service *string
+		start   *time.Time
+		end     *time.Time
+		overlap repository.OverlapPolicy
+		sum     int
+	}{
+		{"max", nil, timePtr(2024, 1), timePtr(2024, 12), repository.OverlapMax, allOf2024},
+		{"sum", nil, timePtr(2024, 1), timePtr(2024, 12), repository.OverlapSum, allOf2024 + 100*5},
+		{"latest", nil, timePtr(2024, 1), timePtr(2024, 12), repository.OverlapLatest, allOf2024 - 100*5},
+		{"clipped", nil, timePtr(2024, 12), timePtr(2025, 3), repository.OverlapMax, 200 + 500*3 + 300*4},
+		{"service", ptr("Netflix"), nil, timePtr(2025, 6), repository.OverlapMax, 500 * 4},
+		{"empty period", nil, timePtr(2023, 1), timePtr(2023, 12), repository.OverlapMax, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sum, err := repo.SumByUserAndService(conformCtx, userID, c.service, c.start, c.end, c.overlap)
+			require.NoError(t, err)
+			assert.Equal(t, c.sum, sum)
+
+			months, err := repo.MonthlyBreakdown(conformCtx, userID, c.service, c.start, c.end, c.overlap)
+			require.NoError(t, err)
+			net := 0
+			for i, m := range months {
+				if i > 0 {
+					assert.True(t, time.Time(months[i-1].Month).Before(time.Time(m.Month)), "months must be ordered")
+				}
+				assert.Equal(t, m.Gross-m.Discount, m.Net)
+				net += m.Net
+			}
+			assert.Equal(t, c.sum, net)
+		})
+	}
+
+	months, err := repo.MonthlyBreakdown(conformCtx, userID, ptr("Spotify"), timePtr(2024, 1), timePtr(2024, 3), repository.OverlapSum)
+	require.NoError(t, err)
+	assert.Equal(t, []models.MonthlySpend{
+		{Month: month(2024, 1), Gross: 200, Discount: 50, Net: 150},
+		{Month: month(2024, 2), Gross: 200, Discount: 50, Net: 150},
+		{Month: month(2024, 3), Gross: 300, Discount: 0, Net: 300},
+	}, normalizeMonths(months))
+}
+
+// normalizeMonths приводит месяцы к UTC: Postgres возвращает даты в часовом поясе сессии
+func normalizeMonths(months []models.MonthlySpend) []models.MonthlySpend {
+	for i, m := range months {
+		ts := time.Time(m.Month)
+		months[i].Month = month(ts.Year(), ts.Month())
+	}
+	return months
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func conformCollapsedOverlaps(t *testing.T, repo repository.SubscriptionRepository) {
+	userID := uuid.New()
+	outer := createSub(t, repo, conformCtx, userID, "Spotify", 200, month(2025, 1), monthPtr(2025, 12))
+	inner := createSub(t, repo, conformCtx, userID, "Spotify", 100, month(2025, 3), monthPtr(2025, 7))
+
+	collapsed, err := repo.CollapsedOverlaps(conformCtx, userID, nil, timePtr(2025, 1), timePtr(2025, 12), repository.OverlapMax)
+	require.NoError(t, err)
+	require.Len(t, collapsed, 1)
+	assert.Equal(t, outer.ID, collapsed[0].KeptID)
+	assert.Equal(t, inner.ID, collapsed[0].CollapsedID)
+	assert.Equal(t, "Spotify", collapsed[0].ServiceName)
+	assert.Equal(t, 5, collapsed[0].Months)
+	assert.Equal(t, 2025, time.Time(collapsed[0].StartMonth).Year())
+	assert.Equal(t, time.March, time.Time(collapsed[0].StartMonth).Month())
+	assert.Equal(t, time.July, time.Time(collapsed[0].EndMonth).Month())
+
+	collapsed, err = repo.CollapsedOverlaps(conformCtx, userID, nil, timePtr(2025, 1), timePtr(2025, 12), repository.OverlapLatest)
+	require.NoError(t, err)
+	require.Len(t, collapsed, 1)
+	assert.Equal(t, inner.ID, collapsed[0].KeptID)
+	assert.Equal(t, outer.ID, collapsed[0].CollapsedID)
+
+	collapsed, err = repo.CollapsedOverlaps(conformCtx, userID, nil, timePtr(2025, 1), timePtr(2025, 12), repository.OverlapSum)
+	require.NoError(t, err)
+	assert.Empty(t, collapsed)
+}
+
+func conformDiscounts(t *testing.T, repo repository.SubscriptionRepository) {
+	userID := uuid.New()
+	sub := createSub(t, repo, conformCtx, userID, "Netflix", 400, month(2025, 1), monthPtr(2025, 4))
+
+	later := models.Discount{SubscriptionID: sub.ID, Type: models.DiscountFixed, Value: 300, StartDate: month(2025, 3)}
+	earlier := models.Discount{SubscriptionID: sub.ID, Type: models.DiscountPercent, Value: 50, StartDate: month(2025, 2), EndDate: monthPtr(2025, 3)}
+	require.NoError(t, repo.CreateDiscount(conformCtx, &later))
+	require.NoError(t, repo.CreateDiscount(conformCtx, &earlier))
+
+	assert.ErrorIs(t, repo.CreateDiscount(conformCtx, &models.Discount{
+		SubscriptionID: uuid.New(), Type: models.DiscountFixed, Value: 1, StartDate: month(2025, 1),
+	}), gorm.ErrRecordNotFound)
+
+	discounts, err := repo.ListDiscounts(conformCtx, sub.ID)
+	require.NoError(t, err)
+	require.Len(t, discounts, 2)
+	assert.Equal(t, earlier.ID, discounts[0].ID)
+	assert.Equal(t, later.ID, discounts[1].ID)
+
+	// март: 50% и 300 рублей больше цены, скидка ограничена ценой
+	months, err := repo.MonthlyBreakdown(conformCtx, userID, nil, timePtr(2025, 1), timePtr(2025, 4), repository.OverlapMax)
+	require.NoError(t, err)
+	assert.Equal(t, []models.MonthlySpend{
+		{Month: month(2025, 1), Gross: 400, Discount: 0, Net: 400},
+		{Month: month(2025, 2), Gross: 400, Discount: 200, Net: 200},
+		{Month: month(2025, 3), Gross: 400, Discount: 400, Net: 0},
+		{Month: month(2025, 4), Gross: 400, Discount: 300, Net: 100},
+	}, normalizeMonths(months))
+
+	otherCtx := tenant.WithID(context.Background(), "conformance-other")
+	discounts, err = repo.ListDiscounts(otherCtx, sub.ID)
+	require.NoError(t, err)
+	assert.Empty(t, discounts)
+	assert.ErrorIs(t, repo.DeleteDiscount(otherCtx, sub.ID, later.ID), gorm.ErrRecordNotFound)
+
+	require.NoError(t, repo.DeleteDiscount(conformCtx, sub.ID, later.ID))
+	assert.ErrorIs(t, repo.DeleteDiscount(conformCtx, sub.ID, later.ID), gorm.ErrRecordNotFound)
+
+	require.NoError(t, repo.Delete(conformCtx, sub.ID))
+	discounts, err = repo.ListDiscounts(conformCtx, sub.ID)
+	require.NoError(t, err)
+	assert.Empty(t, discounts)
+}
+
+func conformConflicts(t *testing.T, repo repository.SubscriptionRepository) {
+	userID := uuid.New()
+	invalid := createSub(t, repo, conformCtx, userID, "Kinopoisk", 300, month(2025, 5), monthPtr(2025, 2))
+	first := createSub(t, repo, conformCtx, userID, "YouTube Premium", 200, month(2025, 1), monthPtr(2025, 6))
+	second := createSub(t, repo, conformCtx, userID, "youtube-premium", 300, month(2025, 4), monthPtr(2025, 9))
+	netflix := createSub(t, repo, conformCtx, userID, "Netflix", 500, month(2024, 1), monthPtr(2024, 6))
+	netflx := createSub(t, repo, conformCtx, userID, "Netflx", 500, month(2024, 9), monthPtr(2024, 12))
+	createSub(t, repo, conformCtx, userID, "Spotify", 200, month(2025, 1), nil)
+
+	conflicts, err := repo.FindConflicts(conformCtx, userID)
+	require.NoError(t, err)
+
+	type summary struct {
+		Kind   models.ConflictKind
+		Action models.FixAction
+		IDs    []uuid.UUID
+	}
+	got := make([]summary, 0, len(conflicts))
+	for _, c := range conflicts {
+		got = append(got, summary{Kind: c.Kind, Action: c.Fix.Action, IDs: c.SubscriptionIDs})
+	}
+
+	sortedIDs := func(a, b uuid.UUID) []uuid.UUID {
+		if a.String() > b.String() {
+			a, b = b, a
+		}
+		return []uuid.UUID{a, b}
+	}
+	assert.Equal(t, []summary{
+		{models.ConflictInvalidPeriod, models.FixSwapDates, []uuid.UUID{invalid.ID}},
+		{models.ConflictOverlap, models.FixTrim, []uuid.UUID{first.ID, second.ID}},
+		{models.ConflictSimilarName, models.FixRename, sortedIDs(netflix.ID, netflx.ID)},
+	}, got)
+
+	require.Len(t, conflicts[1].Fix.Update, 1)
+	assert.Equal(t, first.ID, conflicts[1].Fix.Update[0].ID)
+	assert.Equal(t, time.March, time.Time(*conflicts[1].Fix.Update[0].EndDate).Month())
+}
@@ -0,0 +1,239 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const testTenantHeader = "X-Tenant-ID"
+
+func doInTenant(router *gin.Engine, method, path, tenantID string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if tenantID != "" {
+		req.Header.Set(testTenantHeader, tenantID)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestTenantIsolation(t *testing.T) {
+	clearDB(db)
+
+	userID := uuid.New()
+	resp := doInTenant(r, "POST", "/subscriptions", "acme", apiv1.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       intPtr(500),
+		UserID:      userID.String(),
+		StartDate:   "01-2025",
+	})
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var created models.Subscription
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+
+	var stored models.Subscription
+	require.NoError(t, db.First(&stored, "id = ?", created.ID).Error)
+	assert.Equal(t, "acme", stored.TenantID)
+
+	byID := "/subscriptions/" + created.ID.String()
+	period := "?user_id=" + userID.String() + "&start_date=01-2025&end_date=03-2025"
+
+	assert.Equal(t, http.StatusOK, doInTenant(r, "GET", byID, "acme", nil).Code)
+	sum := doInTenant(r, "GET", "/subscriptions/sum"+period, "acme", nil)
+	assert.Contains(t, sum.Body.String(), `"sum":1500`)
+
+	for _, other := range []string{"globex", ""} {
+		assert.Equal(t, http.StatusNotFound, doInTenant(r, "GET", byID, other, nil).Code, other)
+		assert.Equal(t, http.StatusNotFound, doInTenant(r, "GET", byID+"/discounts", other, nil).Code, other)
+		assert.Equal(t, http.StatusNotFound, doInTenant(r, "PUT", byID, other, apiv1.UpdateSubscriptionRequest{Price: intPtr(1)}).Code, other)
+
+		list := doInTenant(r, "GET", "/subscriptions/list"+period, other, nil)
+		assert.Equal(t, http.StatusOK, list.Code)
+		assert.JSONEq(t, "[]", list.Body.String())
+
+		sum := doInTenant(r, "GET", "/subscriptions/sum"+period, other, nil)
+		assert.Equal(t, http.StatusOK, sum.Code)
+		assert.Contains(t, sum.Body.String(), `"sum":0`)
+
+		conflicts := doInTenant(r, "GET", "/users/"+userID.String()+"/subscriptions/conflicts", other, nil)
+		assert.JSONEq(t, "[]", conflicts.Body.String())
+
+		popular := doInTenant(r, "GET", "/admin/analytics/popular-services?start_date=01-2025&end_date=03-2025", other, nil)
+		assert.JSONEq(t, "[]", popular.Body.String())
+	}
+
+	// удаление из чужого арендатора не затрагивает подписку
+	doInTenant(r, "DELETE", byID, "globex", nil)
+	assert.Equal(t, http.StatusOK, doInTenant(r, "GET", byID, "acme", nil).Code)
+	assert.Equal(t, http.StatusOK, doInTenant(r, "GET", "/admin/api-keys", "acme", nil).Code)
+}
+
+func TestTenantSameUserInTwoTenants(t *testing.T) {
+	clearDB(db)
+
+	userID := uuid.New()
+	for tenantID, price := range map[string]int{"acme": 100, "globex": 700} {
+		resp := doInTenant(r, "POST", "/subscriptions", tenantID, apiv1.CreateSubscriptionRequest{
+			ServiceName: "Spotify",
+			Price:       intPtr(price),
+			UserID:      userID.String(),
+			StartDate:   "01-2025",
+		})
+		require.Equal(t, http.StatusCreated, resp.Code)
+	}
+
+	period := "/subscriptions/sum?user_id=" + userID.String() + "&start_date=01-2025&end_date=02-2025&overlap=sum"
+	assert.Contains(t, doInTenant(r, "GET", period, "acme", nil).Body.String(), `"sum":200`)
+	assert.Contains(t, doInTenant(r, "GET", period, "globex", nil).Body.String(), `"sum":1400`)
+}
+
+func TestTenantFromToken(t *testing.T) {
+	clearDB(db)
+	router := newAuthRouter(t)
+	userID := uuid.New()
+
+	token := signHS256(t, jwt.MapClaims{"sub": userID.String(), "tenant_id": "acme"})
+	body, _ := json.Marshal(apiv1.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       intPtr(500),
+		UserID:      userID.String(),
+		StartDate:   "01-2025",
+	})
+	req, _ := http.NewRequest("POST", "/subscriptions", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusCreated, resp.Code)
+
+	var count int64
+	require.NoError(t, db.Model(&models.Subscription{}).Where("tenant_id = ? AND user_id = ?", "acme", userID).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	path := "/subscriptions/list?user_id=" + userID.String()
+	req, _ = http.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(testTenantHeader, "globex")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	for _, bad := range []string{"*", "Acme!"} {
+		badToken := signHS256(t, jwt.MapClaims{"sub": userID.String(), "tenant_id": bad})
+		assert.Equal(t, http.StatusForbidden, doAuthorized(router, "GET", path, badToken).Code, bad)
+	}
+	assert.Equal(t, http.StatusBadRequest, doInTenant(r, "GET", path, "*", nil).Code)
+}
+
+// TestTenantlessToken проверяет, что токен без арендатора не может выбрать чужого
+// арендатора заголовком, если заголовку не доверяют
+func TestTenantlessToken(t *testing.T) {
+	clearDB(db)
+
+	userID := uuid.New()
+	resp := doInTenant(r, "POST", "/subscriptions", "acme", apiv1.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       intPtr(500),
+		UserID:      userID.String(),
+		StartDate:   "01-2025",
+	})
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	router := newAuthRouter(t)
+	token := signHS256(t, jwt.MapClaims{"sub": userID.String(), "tenant_id": nil})
+	for _, header := range []string{"acme", ""} {
+		req, _ := http.NewRequest("GET", "/subscriptions/list?user_id="+userID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if header != "" {
+			req.Header.Set(testTenantHeader, header)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusForbidden, resp.Code, header)
+		var problem apierror.Problem
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &problem))
+		assert.Equal(t, apierror.CodeInvalidTenant, problem.Code)
+		assert.NotContains(t, resp.Body.String(), "Netflix")
+	}
+}
+
+// TestTenantRowLevelSecurity проверяет политики RLS без фильтров репозитория.
+// Тесты подключаются суперпользователем, для которого RLS не действует,
+// поэтому запросы выполняются от отдельной роли.
+func TestTenantRowLevelSecurity(t *testing.T) {
+	clearDB(db)
+
+	start := models.MonthYearDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, db.Create(&models.Subscription{TenantID: "acme", ServiceName: "Netflix", Price: 500, UserID: uuid.New(), StartDate: start}).Error)
+	require.NoError(t, db.Create(&models.Subscription{TenantID: "globex", ServiceName: "Netflix", Price: 700, UserID: uuid.New(), StartDate: start}).Error)
+
+	require.NoError(t, db.Exec(`
+		DO $$ BEGIN
+			IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'tenant_probe') THEN
+				CREATE ROLE tenant_probe NOLOGIN;
+			END IF;
+		END $$;
+		GRANT USAGE ON SCHEMA public TO tenant_probe;
+		GRANT ALL ON ALL TABLES IN SCHEMA public TO tenant_probe;
+	`).Error)
+
+	asTenant := func(tenantID string, fn func(tx *gorm.DB)) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			require.NoError(t, tx.Exec("SET LOCAL ROLE tenant_probe").Error)
+			if tenantID != "" {
+				require.NoError(t, tx.Exec("SELECT set_config('app.tenant_id', ?, true)", tenantID).Error)
+			}
+			fn(tx)
+			return gorm.ErrInvalidTransaction // откатываем изменения
+		})
+		require.ErrorIs(t, err, gorm.ErrInvalidTransaction)
+	}
+
+	asTenant("acme", func(tx *gorm.DB) {
+		var prices []int
+		require.NoError(t, tx.Raw("SELECT price FROM subscriptions").Scan(&prices).Error)
+		assert.Equal(t, []int{500}, prices)
+
+		err := tx.Exec(`INSERT INTO subscriptions (tenant_id, service_name, price, user_id, start_date, created_at, updated_at)
+			VALUES ('globex', 'Spotify', 100, gen_random_uuid(), '2025-01-01', now(), now())`).Error
+		assert.Error(t, err)
+	})
+
+	asTenant("globex", func(tx *gorm.DB) {
+		result := tx.Exec("UPDATE subscriptions SET price = 1 WHERE tenant_id = 'acme'")
+		require.NoError(t, result.Error)
+		assert.Equal(t, int64(0), result.RowsAffected)
+	})
+
+	asTenant("", func(tx *gorm.DB) {
+		var count int64
+		require.NoError(t, tx.Raw("SELECT COUNT(*) FROM subscriptions").Scan(&count).Error)
+		assert.Equal(t, int64(0), count)
+	})
+
+	asTenant("*", func(tx *gorm.DB) {
+		var count int64
+		require.NoError(t, tx.Raw("SELECT COUNT(*) FROM subscriptions").Scan(&count).Error)
+		assert.Equal(t, int64(2), count)
+	})
+}
@@ -0,0 +1,128 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/handlers"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+	"subscriptions-service/internal/trace"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func newTracedRouter(t *testing.T) (*gin.Engine, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	router := gin.New()
+	router.Use(trace.Middleware())
+	api := router.Group("/", auth.Disabled(), tenant.Middleware(testTenantHeader, true))
+	handlers.NewHandler(repository.NewSubscriptionRepository(db)).RegisterRoutes(api)
+	return router, exporter
+}
+
+func TestTracingContinuesIncomingTraceparent(t *testing.T) {
+	clearDB(db)
+	router, exporter := newTracedRouter(t)
+
+	const (
+		traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentSpanID = "00f067aa0ba902b7"
+	)
+	req, _ := http.NewRequest("GET", "/subscriptions/list?user_id="+uuid.New().String(), nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	assert.Equal(t, traceID, resp.Header().Get("X-Trace-ID"))
+	assert.True(t, strings.HasPrefix(resp.Header().Get("traceparent"), "00-"+traceID+"-"))
+
+	spans := exporter.GetSpans()
+	var server *tracetest.SpanStub
+	for i := range spans {
+		if spans[i].SpanKind == oteltrace.SpanKindServer {
+			server = &spans[i]
+		}
+	}
+	require.NotNil(t, server)
+	assert.Equal(t, "GET /subscriptions/list", server.Name)
+	assert.Equal(t, traceID, server.SpanContext.TraceID().String())
+	assert.Equal(t, parentSpanID, server.Parent.SpanID().String())
+
+	var sqlSpans int
+	for _, s := range spans {
+		if s.SpanKind != oteltrace.SpanKindClient {
+			continue
+		}
+		sqlSpans++
+		assert.True(t, strings.HasPrefix(s.Name, "SQL "), s.Name)
+		assert.Equal(t, traceID, s.SpanContext.TraceID().String())
+		assert.Equal(t, server.SpanContext.SpanID(), s.Parent.SpanID())
+	}
+	assert.Greater(t, sqlSpans, 0)
+}
+
+func TestTracingStartsNewTrace(t *testing.T) {
+	router, exporter := newTracedRouter(t)
+
+	req, _ := http.NewRequest("GET", "/subscriptions/"+uuid.New().String(), nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	traceID := resp.Header().Get("X-Trace-ID")
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{32}$`), traceID)
+	assert.Contains(t, resp.Header().Get("traceparent"), traceID)
+
+	spans := exporter.GetSpans()
+	require.NotEmpty(t, spans)
+	for _, s := range spans {
+		assert.Equal(t, traceID, s.SpanContext.TraceID().String())
+	}
+}
+
+func TestTracingOmitsQueryParameters(t *testing.T) {
+	clearDB(db)
+	router, exporter := newTracedRouter(t)
+
+	userID := uuid.NewString()
+	req, _ := http.NewRequest("GET", "/subscriptions/list?user_id="+userID, nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var queries []string
+	for _, s := range exporter.GetSpans() {
+		for _, attr := range s.Attributes {
+			if attr.Key == semconv.DBQueryTextKey {
+				queries = append(queries, attr.Value.AsString())
+			}
+		}
+	}
+	require.NotEmpty(t, queries)
+	// значения параметров, включая арендатора и пользователя, в спаны не попадают
+	for _, q := range queries {
+		assert.NotContains(t, q, userID)
+		assert.NotContains(t, q, "'"+tenant.Default+"'")
+	}
+}
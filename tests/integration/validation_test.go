@@ -0,0 +1,172 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/apierror"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/handlers"
+	"subscriptions-service/internal/models"
+	"subscriptions-service/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sendJSON(t *testing.T, router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+// violations возвращает нарушения из ответа 400 как pointer -> message
+func violations(t *testing.T, resp *httptest.ResponseRecorder) map[string]string {
+	require.Equal(t, http.StatusBadRequest, resp.Code, resp.Body.String())
+	var problem apierror.Problem
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &problem))
+	assert.Equal(t, apierror.CodeValidationFailed, problem.Code)
+
+	out := make(map[string]string, len(problem.Errors))
+	for _, e := range problem.Errors {
+		out[e.Pointer] = e.Message
+	}
+	return out
+}
+
+func TestCreateSubscriptionValidation(t *testing.T) {
+	router := newProblemRouter(repository.NewMemorySubscriptionRepository(), auth.Disabled())
+
+	resp := sendJSON(t, router, "POST", "/subscriptions", `{
+		"service_name": "",
+		"price": -100,
+		"user_id": "00000000-0000-0000-0000-000000000000",
+		"start_date": "13-2025",
+		"end_date": "2025-01"
+	}`)
+	assert.Equal(t, map[string]string{
+		"/service_name": "is required",
+		"/price":        "must be greater than 0",
+		"/user_id":      "must not be the nil UUID",
+		"/start_date":   "must be in MM-YYYY format",
+		"/end_date":     "must be in MM-YYYY format",
+	}, violations(t, resp), "все нарушения возвращаются разом")
+
+	resp = sendJSON(t, router, "POST", "/subscriptions", `{"service_name": "Netflix", "price": 500, "start_date": "05-2025", "end_date": "03-2025"}`)
+	assert.Equal(t, map[string]string{
+		"/user_id":  "is required",
+		"/end_date": "must not be before start_date",
+	}, violations(t, resp))
+
+	resp = sendJSON(t, router, "POST", "/subscriptions", `{"service_name": "Netflix", "price": "500", "user_id": "`+uuid.NewString()+`", "start_date": "05-2025"}`)
+	assert.Equal(t, map[string]string{"/price": "must be an integer, got string"}, violations(t, resp))
+
+	resp = sendJSON(t, router, "POST", "/subscriptions", `{"service_name": "Netflix", "price": 500, "user_id": "`+uuid.NewString()+`", "start_date": "05-2025", "discount": 10}`)
+	assert.Equal(t, map[string]string{"/discount": "unknown field"}, violations(t, resp))
+
+	resp = sendJSON(t, router, "POST", "/subscriptions", `{"service_name": "Netflix"`)
+	assert.Empty(t, violations(t, resp))
+}
+
+func TestCreateSubscriptionWithAPIKeyRequiresUserID(t *testing.T) {
+	apiKey := func(c *gin.Context) {
+		p := auth.Principal{APIKeyID: uuid.New(), Scopes: []string{auth.ScopeSubscriptionsWrite}}
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), p))
+	}
+	router := newProblemRouter(repository.NewMemorySubscriptionRepository(), apiKey)
+
+	resp := sendJSON(t, router, "POST", "/subscriptions", `{"service_name": "Netflix", "price": 500, "start_date": "05-2025"}`)
+	assert.Equal(t, map[string]string{"/user_id": "is required"}, violations(t, resp))
+}
+
+func TestRequestsRejectResponseFields(t *testing.T) {
+	router := newProblemRouter(repository.NewMemorySubscriptionRepository(), auth.Disabled())
+
+	// подписка из ответа API - не тело запроса: id задает сервис
+	body, err := json.Marshal(models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      uuid.New(),
+		StartDate:   month(2025, time.May),
+	})
+	require.NoError(t, err)
+	resp := sendJSON(t, router, "POST", "/subscriptions", string(body))
+	assert.Equal(t, map[string]string{"/id": "unknown field"}, violations(t, resp))
+
+	resp = sendJSON(t, router, "POST", "/subscriptions", `{"service_name": "Netflix", "price": 500, "user_id": "`+uuid.NewString()+`", "start_date": "05-2025"}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var created models.Subscription
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	path := "/subscriptions/" + created.ID.String()
+
+	resp = sendJSON(t, router, "PUT", path, `{"id": "`+uuid.NewString()+`", "price": 600}`)
+	assert.Equal(t, map[string]string{"/id": "unknown field"}, violations(t, resp))
+
+	for _, field := range []string{"id", "subscription_id"} {
+		resp = sendJSON(t, router, "POST", path+"/discounts", `{"`+field+`": "`+uuid.NewString()+`", "type": "percent", "value": 10, "start_date": "05-2025"}`)
+		assert.Equal(t, map[string]string{"/" + field: "unknown field"}, violations(t, resp), field)
+	}
+}
+
+func TestUpdateSubscriptionValidation(t *testing.T) {
+	router := newProblemRouter(repository.NewMemorySubscriptionRepository(), auth.Disabled())
+
+	resp := sendJSON(t, router, "POST", "/subscriptions", `{"service_name": "Netflix", "price": 500, "user_id": "`+uuid.NewString()+`", "start_date": "05-2025"}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var created models.Subscription
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	path := "/subscriptions/" + created.ID.String()
+
+	resp = sendJSON(t, router, "PUT", path, `{"service_name": "", "price": 0}`)
+	assert.Equal(t, map[string]string{
+		"/service_name": "must be at least 1 characters long",
+		"/price":        "must be greater than 0",
+	}, violations(t, resp))
+
+	// end_date сравнивается с сохраненным start_date
+	resp = sendJSON(t, router, "PUT", path, `{"end_date": "01-2025"}`)
+	assert.Equal(t, map[string]string{"/end_date": "must not be before start_date"}, violations(t, resp))
+
+	resp = sendJSON(t, router, "PUT", path, `{"price": 600, "end_date": "12-2025"}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var updated models.Subscription
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &updated))
+	assert.Equal(t, 600, updated.Price)
+	assert.Equal(t, "Netflix", updated.ServiceName, "отсутствующие поля не меняются")
+}
+
+func TestCreateAPIKeyReportsAllViolations(t *testing.T) {
+	// до хранилища невалидные запросы не доходят
+	router := gin.New()
+	handlers.NewAPIKeyHandler(nil).RegisterRoutes(router.Group("/admin"))
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	resp := sendJSON(t, router, "POST", "/admin/api-keys", `{
+		"name": "",
+		"scopes": ["subscriptions:read", "subscriptions:admin"],
+		"expires_at": "`+past+`"
+	}`)
+	assert.Equal(t, map[string]string{
+		"/name":       "is required",
+		"/scopes/1":   "must be one of subscriptions:read, subscriptions:write, analytics:read",
+		"/expires_at": "must be in the future",
+	}, violations(t, resp), "все нарушения возвращаются разом")
+
+	resp = sendJSON(t, router, "POST", "/admin/api-keys", `{"name": "billing-sync"}`)
+	assert.Equal(t, map[string]string{"/scopes": "is required"}, violations(t, resp))
+
+	resp = sendJSON(t, router, "POST", "/admin/api-keys", `{"name": "billing-sync", "scopes": []}`)
+	assert.Equal(t, map[string]string{"/scopes": "must contain at least 1 items"}, violations(t, resp))
+
+	resp = sendJSON(t, router, "POST", "/admin/api-keys", `{"name": "billing-sync", "scopes": ["subscriptions:read"], "key": "sk_test"}`)
+	assert.Equal(t, map[string]string{"/key": "unknown field"}, violations(t, resp))
+}
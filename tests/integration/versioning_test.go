@@ -0,0 +1,118 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subscriptions-service/internal/apiv1"
+	"subscriptions-service/internal/auth"
+	"subscriptions-service/internal/handlers"
+	"subscriptions-service/internal/repository"
+	"subscriptions-service/internal/tenant"
+	"subscriptions-service/internal/trace"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// newVersionedRouter монтирует API так же, как main: под /v1 и по старым путям
+func newVersionedRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(trace.Middleware())
+	api := router.Group("/", auth.Disabled(), tenant.Middleware(testTenantHeader, true))
+
+	h := handlers.NewHandler(repository.NewMemorySubscriptionRepository())
+	h.RegisterRoutes(api.Group(apiv1.Prefix))
+	h.RegisterRoutes(api.Group("", handlers.Deprecated(testDeprecatedAt, apiv1.Prefix)))
+	return router
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	router := newVersionedRouter()
+
+	resp := sendJSON(t, router, "POST", "/v1/subscriptions", `{
+		"service_name": "Yandex Plus",
+		"price": 400,
+		"user_id": "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		"start_date": "07-2025"
+	}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Empty(t, resp.Header().Get("Deprecation"))
+
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &fields))
+	assert.ElementsMatch(t,
+		[]string{"id", "service_name", "price", "user_id", "start_date", "end_date"},
+		keys(fields), "в ответ попадают только поля DTO, без служебных колонок")
+	assert.Equal(t, "07-2025", fields["start_date"])
+	assert.Nil(t, fields["end_date"])
+
+	var created apiv1.Subscription
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	path := "/subscriptions/" + created.ID.String()
+
+	req, _ := http.NewRequest("GET", path, nil)
+	legacy := httptest.NewRecorder()
+	router.ServeHTTP(legacy, req)
+	require.Equal(t, http.StatusOK, legacy.Code)
+	assert.Equal(t, fmt.Sprintf("@%d", testDeprecatedAt.Unix()), legacy.Header().Get("Deprecation"))
+	assert.Equal(t, `</v1`+path+`>; rel="successor-version"`, legacy.Header().Get("Link"))
+
+	req, _ = http.NewRequest("GET", "/v1"+path, nil)
+	current := httptest.NewRecorder()
+	router.ServeHTTP(current, req)
+	require.Equal(t, http.StatusOK, current.Code)
+	assert.Empty(t, current.Header().Get("Deprecation"))
+	assert.JSONEq(t, current.Body.String(), legacy.Body.String(), "старый путь отвечает так же, как v1")
+}
+
+func TestCreateDiscountValidation(t *testing.T) {
+	router := newVersionedRouter()
+
+	resp := sendJSON(t, router, "POST", "/v1/subscriptions", `{
+		"service_name": "Netflix",
+		"price": 500,
+		"user_id": "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		"start_date": "01-2025"
+	}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var sub apiv1.Subscription
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &sub))
+	path := "/v1/subscriptions/" + sub.ID.String() + "/discounts"
+
+	resp = sendJSON(t, router, "POST", path, `{"type":"coupon","value":0,"start_date":"2025-01"}`)
+	assert.Equal(t, map[string]string{
+		"/type":       "must be one of percent, fixed",
+		"/value":      "must be greater than 0",
+		"/start_date": "must be in MM-YYYY format",
+	}, violations(t, resp))
+
+	resp = sendJSON(t, router, "POST", path, `{"type":"percent","value":150,"start_date":"03-2025","end_date":"01-2025"}`)
+	assert.Equal(t, map[string]string{
+		"/value":    "percent discount value must be between 1 and 100",
+		"/end_date": "must not be before start_date",
+	}, violations(t, resp))
+
+	resp = sendJSON(t, router, "POST", path, `{"type":"fixed","value":100,"start_date":"02-2025"}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var discount apiv1.Discount
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &discount))
+	assert.Equal(t, sub.ID, discount.SubscriptionID)
+	assert.Equal(t, "02-2025", discount.StartDate)
+	assert.Nil(t, discount.EndDate)
+}
+
+func keys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}